@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"pocketbase-server/internal/config"
+)
+
+// newConfigCommand returns the `config` command group. It does not need a
+// database connection, so it runs before the server is constructed.
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the effective configuration",
+	}
+	config.RegisterFlags(cmd.PersistentFlags())
+
+	var format string
+	print := &cobra.Command{
+		Use:   "print",
+		Short: "Print the merged configuration with secrets masked",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			cfg, loadErr := config.Load(os.Args[1:])
+			if cfg == nil {
+				return loadErr
+			}
+			if err := cfg.Print(cmd.OutOrStdout(), format); err != nil {
+				return err
+			}
+			// Still print the config so problems can be inspected in context.
+			if loadErr != nil {
+				fmt.Fprintln(cmd.ErrOrStderr())
+				return loadErr
+			}
+			return nil
+		},
+	}
+	print.Flags().StringVar(&format, "format", "yaml", "output format: yaml, toml or json")
+	cmd.AddCommand(print)
+
+	return cmd
+}
@@ -1,11 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
-	"os/signal"
-	"syscall"
 
+	"pocketbase-server/internal/config"
 	"pocketbase-server/internal/logging"
 	"pocketbase-server/server"
 )
@@ -15,21 +15,38 @@ func init() {
 }
 
 func main() {
-	srv, err := server.New()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		cmd := newConfigCommand()
+		cmd.SetArgs(os.Args[2:])
+		if err := cmd.Execute(); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(server.ExitUsage)
+	}
+
+	logOpts := []logging.Option{logging.WithLevel(cfg.Log.Level)}
+	if cfg.Log.Console {
+		logOpts = append(logOpts, logging.WithConsole())
 	}
+	logging.SetGlobal(logOpts...)
 
-	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-		<-sigCh
-		log.Println("Shutting down PocketBase...")
-		os.Exit(0)
-	}()
+	srv, err := server.New(cfg)
+	if err != nil {
+		log.Printf("Failed to initialize server: %v", err)
+		os.Exit(server.ExitFailure)
+	}
 
-	log.Println("Starting PocketBase server...")
+	// Runs the requested subcommand (serve by default). SIGINT/SIGTERM are
+	// handled by PocketBase, which triggers the server lifecycle shutdown
+	// (drain, stop cron, sync, close) before Start returns.
 	if err := srv.Start(); err != nil {
-		log.Fatalf("PocketBase start error: %v", err)
+		fmt.Fprintln(os.Stderr, err)
 	}
+	os.Exit(srv.ExitCode())
 }
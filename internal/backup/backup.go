@@ -0,0 +1,383 @@
+// Package backup snapshots the main database, the auxiliary database and
+// uploaded files into a single archive and restores them.
+//
+// Archives are stored as <prefix><id>.tar.gz on a local directory or an
+// S3 bucket. The id is the UTC creation time to the millisecond
+// (20060102T150405.000Z), so ids
+// sort chronologically and a point in time maps to the latest backup
+// taken at or before it. Each archive ends with a manifest listing the
+// SHA-256 of every entry, which Verify checks along with SQLite's
+// integrity_check for the databases.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// Archive entry names.
+const (
+	DataDB        = "data.db"
+	AuxDB         = "auxiliary.db"
+	StorageDir    = "storage/"
+	manifestEntry = "manifest.json"
+
+	idLayout   = "20060102T150405.000Z"
+	archiveExt = ".tar.gz"
+)
+
+var (
+	ErrNotFound = errors.New("backup not found")
+	ErrCorrupt  = errors.New("backup is corrupt")
+)
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	Id      string    `json:"id"`
+	Created time.Time `json:"created"`
+	// DataDBEncrypted is set when data.db is an encrypted replica copy,
+	// which Verify can only check by hash.
+	DataDBEncrypted bool     `json:"data_db_encrypted,omitempty"`
+	Files           []Entry  `json:"files"`
+	Skipped         []string `json:"skipped,omitempty"`
+}
+
+// Entry is one archived file.
+type Entry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Info is a stored backup.
+type Info struct {
+	Id      string    `json:"id"`
+	Key     string    `json:"key"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+}
+
+// Retention decides which backups Prune deletes: everything beyond the
+// newest KeepLast, and anything older than MaxAge (when set). The newest
+// backup is always kept.
+type Retention struct {
+	KeepLast int
+	MaxAge   time.Duration
+}
+
+// Config wires a Manager to the app and the backup destination.
+type Config struct {
+	App core.App
+
+	// Store opens the destination filesystem; the caller's Close is
+	// deferred by the Manager.
+	Store func() (*filesystem.System, error)
+	// Prefix is prepended to archive keys, e.g. "backups/".
+	Prefix string
+
+	// SnapshotDataDB writes a consistent copy of data.db to dst. Nil
+	// copies it with VACUUM INTO through the app's main DB.
+	SnapshotDataDB  func(dst string) error
+	DataDBEncrypted bool
+
+	Retention Retention
+}
+
+// Manager creates, lists, verifies and prunes backups.
+type Manager struct {
+	cfg Config
+	mu  sync.Mutex // one backup at a time
+}
+
+// New returns a Manager for cfg.
+func New(cfg Config) *Manager {
+	if cfg.SnapshotDataDB == nil {
+		cfg.SnapshotDataDB = func(dst string) error {
+			return vacuumInto(cfg.App.NonconcurrentDB(), dst)
+		}
+	}
+	if cfg.Retention.KeepLast <= 0 {
+		cfg.Retention.KeepLast = 1
+	}
+	return &Manager{cfg: cfg}
+}
+
+// Create snapshots the databases and uploaded files, uploads the archive
+// and applies the retention rules.
+func (m *Manager) Create(ctx context.Context) (*Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tmp, err := os.MkdirTemp("", "pb-backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	manifest := &Manifest{Id: now.Format(idLayout), Created: now}
+
+	archivePath := filepath.Join(tmp, manifest.Id+archiveExt)
+	if err := m.writeArchive(ctx, tmp, archivePath, manifest); err != nil {
+		return nil, err
+	}
+
+	store, err := m.cfg.Store()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup store: %w", err)
+	}
+	defer store.Close()
+	store.SetContext(ctx)
+
+	file, err := filesystem.NewFileFromPath(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	key := m.cfg.Prefix + manifest.Id + archiveExt
+	if err := store.UploadFile(file, key); err != nil {
+		return nil, fmt.Errorf("failed to upload backup: %w", err)
+	}
+
+	if _, err := m.prune(ctx, store); err != nil {
+		return nil, fmt.Errorf("backup %s created but pruning failed: %w", manifest.Id, err)
+	}
+
+	return &Info{Id: manifest.Id, Key: key, Created: now, Size: file.Size}, nil
+}
+
+func (m *Manager) writeArchive(ctx context.Context, tmp, archivePath string, manifest *Manifest) error {
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	// databases
+	dataPath := filepath.Join(tmp, DataDB)
+	switch err := m.cfg.SnapshotDataDB(dataPath); {
+	case err == nil:
+		manifest.DataDBEncrypted = m.cfg.DataDBEncrypted
+		if err := addFile(tw, manifest, DataDB, dataPath); err != nil {
+			return err
+		}
+	case errors.Is(err, errSkip{}):
+		manifest.Skipped = append(manifest.Skipped, DataDB+": "+err.Error())
+	default:
+		return fmt.Errorf("failed to snapshot %s: %w", DataDB, err)
+	}
+
+	auxPath := filepath.Join(tmp, AuxDB)
+	if err := vacuumInto(m.cfg.App.AuxNonconcurrentDB(), auxPath); err != nil {
+		return fmt.Errorf("failed to snapshot %s: %w", AuxDB, err)
+	}
+	if err := addFile(tw, manifest, AuxDB, auxPath); err != nil {
+		return err
+	}
+
+	// uploaded files
+	if err := m.addStorage(ctx, tw, manifest); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: manifestEntry, Mode: 0600, Size: int64(len(data)), ModTime: manifest.Created}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func (m *Manager) addStorage(ctx context.Context, tw *tar.Writer, manifest *Manifest) error {
+	fsys, err := m.cfg.App.NewFilesystem()
+	if err != nil {
+		return fmt.Errorf("failed to open file storage: %w", err)
+	}
+	defer fsys.Close()
+	fsys.SetContext(ctx)
+
+	objects, err := fsys.List("")
+	if err != nil {
+		return fmt.Errorf("failed to list uploaded files: %w", err)
+	}
+
+	for _, obj := range objects {
+		// backups may share the bucket with the uploaded files
+		if m.cfg.Prefix != "" && strings.HasPrefix(obj.Key, m.cfg.Prefix) {
+			continue
+		}
+
+		r, err := fsys.GetReader(obj.Key)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", obj.Key, err)
+		}
+		err = addReader(tw, manifest, StorageDir+obj.Key, r.Size(), r.ModTime(), r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errSkip marks a part of the backup that doesn't apply, e.g. data.db in
+// remote mode.
+type errSkip struct{ reason string }
+
+func (e errSkip) Error() string        { return e.reason }
+func (e errSkip) Is(target error) bool { _, ok := target.(errSkip); return ok }
+
+// Skip returns an error SnapshotDataDB can return to leave data.db out of
+// the backup, recording reason in the manifest.
+func Skip(reason string) error {
+	return errSkip{reason: reason}
+}
+
+func addFile(tw *tar.Writer, manifest *Manifest, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return addReader(tw, manifest, name, stat.Size(), stat.ModTime(), f)
+}
+
+func addReader(tw *tar.Writer, manifest *Manifest, name string, size int64, modTime time.Time, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: size, ModTime: modTime}); err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, h), r); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	manifest.Files = append(manifest.Files, Entry{Name: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))})
+	return nil
+}
+
+func vacuumInto(db dbx.Builder, dst string) error {
+	_, err := db.NewQuery("VACUUM INTO {:dst}").Bind(dbx.Params{"dst": dst}).Execute()
+	return err
+}
+
+// List returns the stored backups, newest first.
+func (m *Manager) List(ctx context.Context) ([]Info, error) {
+	store, err := m.cfg.Store()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup store: %w", err)
+	}
+	defer store.Close()
+	store.SetContext(ctx)
+
+	return m.list(store)
+}
+
+func (m *Manager) list(store *filesystem.System) ([]Info, error) {
+	objects, err := store.List(m.cfg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Info
+	for _, obj := range objects {
+		name := strings.TrimPrefix(obj.Key, m.cfg.Prefix)
+		id, ok := strings.CutSuffix(name, archiveExt)
+		if !ok || strings.Contains(id, "/") {
+			continue
+		}
+		created, err := time.Parse(idLayout, id)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Info{Id: id, Key: obj.Key, Created: created, Size: obj.Size})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Id > backups[j].Id })
+	return backups, nil
+}
+
+// Find returns the latest backup taken at or before at.
+func (m *Manager) Find(ctx context.Context, at time.Time) (*Info, error) {
+	backups, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if !b.Created.After(at) {
+			return &b, nil
+		}
+	}
+	return nil, fmt.Errorf("%w at or before %s", ErrNotFound, at.UTC().Format(time.RFC3339))
+}
+
+// Prune deletes the backups the retention rules don't keep and returns
+// their ids.
+func (m *Manager) Prune(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	store, err := m.cfg.Store()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup store: %w", err)
+	}
+	defer store.Close()
+	store.SetContext(ctx)
+
+	return m.prune(ctx, store)
+}
+
+func (m *Manager) prune(ctx context.Context, store *filesystem.System) ([]string, error) {
+	backups, err := m.list(store)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for i, b := range backups {
+		expired := m.cfg.Retention.MaxAge > 0 && time.Since(b.Created) > m.cfg.Retention.MaxAge
+		if i == 0 || (i < m.cfg.Retention.KeepLast && !expired) {
+			continue
+		}
+		if err := store.Delete(b.Key); err != nil {
+			return deleted, fmt.Errorf("failed to delete backup %s: %w", b.Id, err)
+		}
+		deleted = append(deleted, b.Id)
+	}
+	return deleted, nil
+}
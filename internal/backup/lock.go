@@ -0,0 +1,42 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFile is created in the data directory by a serving instance.
+const LockFile = ".instance.lock"
+
+// ErrInstanceRunning is returned by LockInstance while another process
+// holds the data directory.
+var ErrInstanceRunning = errors.New("a server instance is running on this data directory")
+
+// LockInstance takes the data directory lock and returns its release
+// func. Serving instances hold it so restores can't overwrite their
+// databases; the OS drops it if the process dies.
+func LockInstance(dataDir string) (func(), error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dataDir, LockFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	f.Truncate(0)
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
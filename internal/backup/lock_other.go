@@ -0,0 +1,11 @@
+//go:build !unix
+
+package backup
+
+import "os"
+
+// Without flock the lock is advisory only: restores can't detect a
+// running instance on these platforms.
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) {}
@@ -0,0 +1,250 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// Verify downloads the backup and checks every entry against the
+// manifest and the databases with SQLite's integrity_check.
+func (m *Manager) Verify(ctx context.Context, id string) (*Manifest, error) {
+	tmp, err := os.MkdirTemp("", "pb-verify-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	return m.Extract(ctx, id, tmp)
+}
+
+// Extract downloads the backup into dir and verifies it like Verify.
+func (m *Manager) Extract(ctx context.Context, id, dir string) (*Manifest, error) {
+	store, err := m.cfg.Store()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup store: %w", err)
+	}
+	defer store.Close()
+	store.SetContext(ctx)
+
+	key := m.cfg.Prefix + id + archiveExt
+	if ok, err := store.Exists(key); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	r, err := store.GetReader(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	hashes, manifest, err := extract(r, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	for _, entry := range manifest.Files {
+		if hashes[entry.Name] != entry.SHA256 {
+			return manifest, fmt.Errorf("%w: %s checksum mismatch", ErrCorrupt, entry.Name)
+		}
+		delete(hashes, entry.Name)
+	}
+	for name := range hashes {
+		return manifest, fmt.Errorf("%w: %s is not in the manifest", ErrCorrupt, name)
+	}
+
+	for _, name := range []string{DataDB, AuxDB} {
+		if name == DataDB && manifest.DataDBEncrypted {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := integrityCheck(path); err != nil {
+			return manifest, fmt.Errorf("%w: %s: %v", ErrCorrupt, name, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// extract unpacks the archive into dir and returns the SHA-256 of every
+// entry and the manifest.
+func extract(r io.Reader, dir string) (map[string]string, *Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gz.Close()
+
+	hashes := map[string]string{}
+	var manifest *Manifest
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if hdr.Name == manifestEntry {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return nil, nil, fmt.Errorf("entry %q escapes the archive", hdr.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, nil, err
+		}
+
+		out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return nil, nil, err
+		}
+		h := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, h), tr)
+		out.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		hashes[hdr.Name] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if manifest == nil {
+		return nil, nil, errors.New("missing manifest")
+	}
+	return hashes, manifest, nil
+}
+
+func integrityCheck(path string) error {
+	db, err := core.DefaultDBConnect(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.NewQuery("PRAGMA integrity_check").Row(&result); err != nil {
+		return err
+	}
+	if result != "ok" {
+		return errors.New(result)
+	}
+	return nil
+}
+
+// RestoreDatabases copies the databases extracted into dir over the ones
+// in dataDir, dropping their -wal/-shm files. data.db is only replaced
+// when withDataDB is set. Nothing may hold the databases open.
+func RestoreDatabases(dir, dataDir string, withDataDB bool) ([]string, error) {
+	names := []string{AuxDB}
+	if withDataDB {
+		names = append([]string{DataDB}, names...)
+	}
+
+	var restored []string
+	for _, name := range names {
+		src := filepath.Join(dir, name)
+		if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		dst := filepath.Join(dataDir, name)
+		for _, suffix := range []string{"-wal", "-shm"} {
+			if err := os.Remove(dst + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return restored, err
+			}
+		}
+		if err := copyFile(src, dst); err != nil {
+			return restored, err
+		}
+		restored = append(restored, name)
+	}
+	return restored, nil
+}
+
+// RestoreFiles uploads the files extracted into dir to the app's file
+// storage, overwriting existing keys. Files uploaded after the backup are
+// left in place.
+func RestoreFiles(ctx context.Context, app core.App, dir string) (int, error) {
+	root := filepath.Join(dir, filepath.FromSlash(StorageDir))
+	if _, err := os.Stat(root); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return 0, err
+	}
+	defer fsys.Close()
+	fsys.SetContext(ctx)
+
+	count := 0
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		file, err := filesystem.NewFileFromPath(path)
+		if err != nil {
+			return err
+		}
+		if err := fsys.UploadFile(file, filepath.ToSlash(rel)); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", rel, err)
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".restore"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
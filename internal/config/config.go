@@ -0,0 +1,282 @@
+// Package config loads the service configuration from layered sources.
+//
+// Values are resolved in increasing order of precedence:
+//
+//  1. built-in defaults (Default)
+//  2. a YAML or TOML file (--config flag or CONFIG_FILE env)
+//  3. environment variables
+//  4. CLI flags
+//
+// Load validates the merged result and reports every problem at once.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/caarlos0/env/v11"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server" json:"server"`
+	Admin     AdminConfig     `yaml:"admin" toml:"admin" json:"admin"`
+	LibSQL    LibSQLConfig    `yaml:"libsql" toml:"libsql" json:"libsql"`
+	S3        S3Config        `yaml:"s3" toml:"s3" json:"s3"`
+	OAuth2    OAuth2Config    `yaml:"oauth2" toml:"oauth2" json:"oauth2"`
+	Log       LogConfig       `yaml:"log" toml:"log" json:"log"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics" json:"metrics"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit"`
+	Billing   BillingConfig   `yaml:"billing" toml:"billing" json:"billing"`
+	Backup    BackupConfig    `yaml:"backup" toml:"backup" json:"backup"`
+}
+
+type ServerConfig struct {
+	Addr    string `yaml:"addr" toml:"addr" json:"addr" env:"PB_ADDR"`
+	DataDir string `yaml:"data_dir" toml:"data_dir" json:"data_dir" env:"PB_DATA_DIR"`
+	Dev     bool   `yaml:"dev" toml:"dev" json:"dev" env:"PB_DEV"`
+
+	// How long to wait for in-flight requests to finish on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout" env:"PB_SHUTDOWN_TIMEOUT"`
+}
+
+type AdminConfig struct {
+	Email string `yaml:"email" toml:"email" json:"email" env:"PB_ADMIN_EMAIL"`
+	Pass  string `yaml:"pass" toml:"pass" json:"pass" env:"PB_ADMIN_PASS"`
+}
+
+type LibSQLConfig struct {
+	// "local" (plain SQLite file), "replica" (embedded replica of URL),
+	// "remote" (every query goes to URL) or "memory" (tests). Empty picks
+	// local when URL is empty or "local", replica otherwise.
+	Mode string `yaml:"mode" toml:"mode" json:"mode" env:"LIBSQL_MODE"`
+	// e.g., "http://localhost:8080" for local, "libsql://xxx.turso.io" for cloud
+	URL          string        `yaml:"url" toml:"url" json:"url" env:"LIBSQL_URL"`
+	Token        string        `yaml:"token" toml:"token" json:"token" env:"LIBSQL_AUTH_TOKEN"`
+	SyncInterval time.Duration `yaml:"sync_interval" toml:"sync_interval" json:"sync_interval" env:"LIBSQL_SYNC_INTERVAL"`
+
+	// Failed syncs back off exponentially up to SyncMaxBackoff; after
+	// SyncAlertAfter failures in a row platform admins are alerted.
+	SyncMaxBackoff time.Duration `yaml:"sync_max_backoff" toml:"sync_max_backoff" json:"sync_max_backoff" env:"LIBSQL_SYNC_MAX_BACKOFF"`
+	SyncAlertAfter int           `yaml:"sync_alert_after" toml:"sync_alert_after" json:"sync_alert_after" env:"LIBSQL_SYNC_ALERT_AFTER"`
+
+	// Encrypts the embedded replica file at rest (replica mode only). Set
+	// the key directly or point EncryptionKeyFile at a file holding it.
+	EncryptionKey     string `yaml:"encryption_key" toml:"encryption_key" json:"encryption_key" env:"LIBSQL_ENCRYPTION_KEY"`
+	EncryptionKeyFile string `yaml:"encryption_key_file" toml:"encryption_key_file" json:"encryption_key_file" env:"LIBSQL_ENCRYPTION_KEY_FILE"`
+}
+
+// EffectiveMode returns Mode, or the mode implied by URL when unset.
+func (c LibSQLConfig) EffectiveMode() string {
+	if c.Mode != "" {
+		return c.Mode
+	}
+	if c.URL == "" || c.URL == "local" {
+		return "local"
+	}
+	return "replica"
+}
+
+// ResolveEncryptionKey returns EncryptionKey, or the trimmed contents of
+// EncryptionKeyFile. Empty means the replica is not encrypted.
+func (c LibSQLConfig) ResolveEncryptionKey() (string, error) {
+	if c.EncryptionKeyFile == "" {
+		return c.EncryptionKey, nil
+	}
+	if c.EncryptionKey != "" {
+		return "", errors.New("set either an encryption key or a key file, not both")
+	}
+
+	data, err := os.ReadFile(c.EncryptionKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read encryption key file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("encryption key file %s is empty", c.EncryptionKeyFile)
+	}
+	return key, nil
+}
+
+// S3Config configures S3 file storage (optional — leave blank to use local disk).
+type S3Config struct {
+	Bucket         string `yaml:"bucket" toml:"bucket" json:"bucket" env:"S3_BUCKET"`
+	Region         string `yaml:"region" toml:"region" json:"region" env:"S3_REGION"`
+	Endpoint       string `yaml:"endpoint" toml:"endpoint" json:"endpoint" env:"S3_ENDPOINT"`
+	AccessKey      string `yaml:"access_key" toml:"access_key" json:"access_key" env:"S3_ACCESS_KEY"`
+	Secret         string `yaml:"secret" toml:"secret" json:"secret" env:"S3_SECRET"`
+	ForcePathStyle bool   `yaml:"force_path_style" toml:"force_path_style" json:"force_path_style" env:"S3_FORCE_PATH_STYLE"`
+}
+
+// Enabled reports whether any S3 setting was provided.
+func (c S3Config) Enabled() bool {
+	return c.Bucket != "" || c.Region != "" || c.Endpoint != "" || c.AccessKey != "" || c.Secret != ""
+}
+
+type OAuth2Config struct {
+	GoogleClientID     string `yaml:"google_client_id" toml:"google_client_id" json:"google_client_id" env:"GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string `yaml:"google_client_secret" toml:"google_client_secret" json:"google_client_secret" env:"GOOGLE_CLIENT_SECRET"`
+	GithubClientID     string `yaml:"github_client_id" toml:"github_client_id" json:"github_client_id" env:"GITHUB_CLIENT_ID"`
+	GithubClientSecret string `yaml:"github_client_secret" toml:"github_client_secret" json:"github_client_secret" env:"GITHUB_CLIENT_SECRET"`
+}
+
+type LogConfig struct {
+	Level   string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`
+	Console bool   `yaml:"console" toml:"console" json:"console" env:"LOG_CONSOLE"`
+}
+
+// MetricsConfig controls where /metrics is served. With no Addr it is
+// served on the main listener and requires superuser auth; with an Addr
+// (e.g. 127.0.0.1:9090) it gets its own unauthenticated listener instead.
+type MetricsConfig struct {
+	Addr string `yaml:"addr" toml:"addr" json:"addr" env:"METRICS_ADDR"`
+}
+
+// RateLimitConfig holds the per-route rate limit policies for the public
+// and expensive custom endpoints.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" json:"enabled" env:"RATE_LIMIT_ENABLED"`
+
+	// "memory" (per instance) or "db" (shared through the main database)
+	Store string `yaml:"store" toml:"store" json:"store" env:"RATE_LIMIT_STORE"`
+
+	// GET /api/invites/verify
+	InviteVerify RateLimitPolicy `yaml:"invite_verify" toml:"invite_verify" json:"invite_verify" envPrefix:"RATE_LIMIT_INVITE_VERIFY_"`
+	// POST /api/orgs/{orgId}/invites/bulk
+	InviteBulk RateLimitPolicy `yaml:"invite_bulk" toml:"invite_bulk" json:"invite_bulk" envPrefix:"RATE_LIMIT_INVITE_BULK_"`
+}
+
+// RateLimitPolicy is a token bucket of Requests per Per (bursting up to
+// Burst), keyed by "ip", "user" or "org".
+type RateLimitPolicy struct {
+	Requests int           `yaml:"requests" toml:"requests" json:"requests" env:"REQUESTS"`
+	Per      time.Duration `yaml:"per" toml:"per" json:"per" env:"PER"`
+	Burst    int           `yaml:"burst" toml:"burst" json:"burst" env:"BURST"`
+	Key      string        `yaml:"key" toml:"key" json:"key" env:"KEY"`
+}
+
+// BillingConfig selects the payment provider behind plan changes.
+// Leave Provider empty to disable plan changes and the billing webhook.
+type BillingConfig struct {
+	// "" (disabled) or "fake" (local provider for development and tests)
+	Provider      string `yaml:"provider" toml:"provider" json:"provider" env:"BILLING_PROVIDER"`
+	WebhookSecret string `yaml:"webhook_secret" toml:"webhook_secret" json:"webhook_secret" env:"BILLING_WEBHOOK_SECRET"`
+}
+
+// BackupConfig schedules backups of the databases and uploaded files.
+type BackupConfig struct {
+	// Cron expression, e.g. "0 3 * * *"; empty disables scheduled backups
+	Schedule string `yaml:"schedule" toml:"schedule" json:"schedule" env:"BACKUP_SCHEDULE"`
+	// "local" (Dir) or "s3" (the s3 bucket, under the backups/ prefix)
+	Target string `yaml:"target" toml:"target" json:"target" env:"BACKUP_TARGET"`
+	Dir    string `yaml:"dir" toml:"dir" json:"dir" env:"BACKUP_DIR"`
+
+	// Keep the newest KeepLast backups and drop any older than MaxAge
+	// (0 keeps them regardless of age). The newest is always kept.
+	KeepLast int           `yaml:"keep_last" toml:"keep_last" json:"keep_last" env:"BACKUP_KEEP_LAST"`
+	MaxAge   time.Duration `yaml:"max_age" toml:"max_age" json:"max_age" env:"BACKUP_MAX_AGE"`
+}
+
+// Default returns the built-in defaults.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            "0.0.0.0:8080",
+			DataDir:         "./db/pb_data",
+			ShutdownTimeout: 15 * time.Second,
+		},
+		LibSQL: LibSQLConfig{
+			URL:            "http://localhost:8080",
+			SyncInterval:   30 * time.Second,
+			SyncMaxBackoff: 10 * time.Minute,
+			SyncAlertAfter: 3,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Backup: BackupConfig{
+			Target:   "local",
+			Dir:      "./db/backups",
+			KeepLast: 7,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:      true,
+			Store:        "memory",
+			InviteVerify: RateLimitPolicy{Requests: 10, Per: time.Minute, Burst: 10, Key: "ip"},
+			InviteBulk:   RateLimitPolicy{Requests: 10, Per: time.Hour, Burst: 3, Key: "org"},
+		},
+	}
+}
+
+// Load resolves the configuration from defaults, the config file,
+// the environment and the CLI flags in args (unknown flags are ignored),
+// then validates the result.
+func Load(args []string) (*Config, error) {
+	flags, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	file := flags.file
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	if file != "" {
+		if err := cfg.loadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := env.Parse(cfg); err != nil {
+		return nil, fmt.Errorf("config: failed to parse environment: %w", err)
+	}
+
+	flags.apply(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: failed to read %s: %w", path, err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("config: unsupported file type %q (expected .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config: failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// ValidationError collects every problem found by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// IsValidationError reports whether err is (or wraps) a ValidationError.
+func IsValidationError(err error) bool {
+	var verr *ValidationError
+	return errors.As(err, &verr)
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// flagValues holds the CLI overrides. Only flags that were explicitly
+// passed are applied on top of the file and environment layers.
+type flagValues struct {
+	fs *pflag.FlagSet
+
+	file            string
+	addr            string
+	dataDir         string
+	shutdownTimeout time.Duration
+	libsqlMode      string
+	libsqlURL       string
+	libsqlInterval  time.Duration
+	logLevel        string
+}
+
+// RegisterFlags declares the config flags on fs. Commands should register
+// them (typically as persistent flags) so cobra accepts them; the values
+// themselves are read eagerly by Load.
+func RegisterFlags(fs *pflag.FlagSet) {
+	registerFlags(fs, &flagValues{})
+}
+
+func registerFlags(fs *pflag.FlagSet, v *flagValues) {
+	d := Default()
+	fs.StringVar(&v.file, "config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+	fs.StringVar(&v.addr, "addr", d.Server.Addr, "HTTP listen address (env PB_ADDR)")
+	fs.StringVar(&v.dataDir, "data-dir", d.Server.DataDir, "PocketBase data directory (env PB_DATA_DIR)")
+	fs.DurationVar(&v.shutdownTimeout, "shutdown-timeout", d.Server.ShutdownTimeout, "graceful shutdown timeout (env PB_SHUTDOWN_TIMEOUT)")
+	fs.StringVar(&v.libsqlMode, "libsql-mode", d.LibSQL.Mode, "libSQL mode: local, replica, remote or memory (env LIBSQL_MODE)")
+	fs.StringVar(&v.libsqlURL, "libsql-url", d.LibSQL.URL, "libSQL primary URL (env LIBSQL_URL)")
+	fs.DurationVar(&v.libsqlInterval, "libsql-sync-interval", d.LibSQL.SyncInterval, "libSQL replica sync interval (env LIBSQL_SYNC_INTERVAL)")
+	fs.StringVar(&v.logLevel, "log-level", d.Log.Level, "log level (env LOG_LEVEL)")
+}
+
+func parseFlags(args []string) (*flagValues, error) {
+	v := &flagValues{fs: pflag.NewFlagSet("config", pflag.ContinueOnError)}
+	v.fs.ParseErrorsAllowlist.UnknownFlags = true
+	v.fs.Usage = func() {}
+	registerFlags(v.fs, v)
+
+	if err := v.fs.Parse(args); err != nil && !errors.Is(err, pflag.ErrHelp) {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *flagValues) apply(cfg *Config) {
+	if v.fs.Changed("addr") {
+		cfg.Server.Addr = v.addr
+	}
+	if v.fs.Changed("data-dir") {
+		cfg.Server.DataDir = v.dataDir
+	}
+	if v.fs.Changed("shutdown-timeout") {
+		cfg.Server.ShutdownTimeout = v.shutdownTimeout
+	}
+	if v.fs.Changed("libsql-mode") {
+		cfg.LibSQL.Mode = v.libsqlMode
+	}
+	if v.fs.Changed("libsql-url") {
+		cfg.LibSQL.URL = v.libsqlURL
+	}
+	if v.fs.Changed("libsql-sync-interval") {
+		cfg.LibSQL.SyncInterval = v.libsqlInterval
+	}
+	if v.fs.Changed("log-level") {
+		cfg.Log.Level = v.logLevel
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const mask = "********"
+
+// Redacted returns a copy of c with every secret masked.
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.Admin.Pass = redact(c.Admin.Pass)
+	out.LibSQL.Token = redact(c.LibSQL.Token)
+	out.LibSQL.EncryptionKey = redact(c.LibSQL.EncryptionKey)
+	out.S3.AccessKey = redact(c.S3.AccessKey)
+	out.S3.Secret = redact(c.S3.Secret)
+	out.OAuth2.GoogleClientSecret = redact(c.OAuth2.GoogleClientSecret)
+	out.OAuth2.GithubClientSecret = redact(c.OAuth2.GithubClientSecret)
+	out.Billing.WebhookSecret = redact(c.Billing.WebhookSecret)
+	return &out
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return mask
+}
+
+// Print writes the redacted configuration to w as yaml, toml or json.
+func (c *Config) Print(w io.Writer, format string) error {
+	r := c.Redacted()
+
+	switch format {
+	case "", "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(r)
+	case "toml":
+		return toml.NewEncoder(w).Encode(r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	default:
+		return fmt.Errorf("config: unsupported format %q (expected yaml, toml or json)", format)
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/cron"
+	"github.com/rs/zerolog"
+)
+
+// Validate checks every value and returns a *ValidationError listing
+// all problems, or nil when the configuration is usable.
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Server
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		add("server.addr (PB_ADDR) %q must be host:port: %v", c.Server.Addr, err)
+	}
+	if c.Server.DataDir == "" {
+		add("server.data_dir (PB_DATA_DIR) is required")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		add("server.shutdown_timeout (PB_SHUTDOWN_TIMEOUT) must be positive, got %v", c.Server.ShutdownTimeout)
+	}
+
+	// Admin
+	if (c.Admin.Email == "") != (c.Admin.Pass == "") {
+		add("admin.email (PB_ADMIN_EMAIL) and admin.pass (PB_ADMIN_PASS) must be set together")
+	}
+	if c.Admin.Email != "" {
+		if _, err := mail.ParseAddress(c.Admin.Email); err != nil {
+			add("admin.email (PB_ADMIN_EMAIL) %q is not a valid email address", c.Admin.Email)
+		}
+	}
+	if c.Admin.Pass != "" && len(c.Admin.Pass) < 8 {
+		add("admin.pass (PB_ADMIN_PASS) must be at least 8 characters")
+	}
+
+	// libSQL
+	switch mode := c.LibSQL.EffectiveMode(); mode {
+	case "local", "memory":
+	case "replica", "remote":
+		schemes := []string{"http", "https", "libsql", "ws", "wss"}
+		if mode == "remote" {
+			// the remote driver has no websocket transport
+			schemes = schemes[:3]
+		}
+		if c.LibSQL.URL == "" || c.LibSQL.URL == "local" {
+			add("libsql.url (LIBSQL_URL) is required in %s mode", mode)
+		} else if err := validateURL(c.LibSQL.URL, schemes...); err != nil {
+			add("libsql.url (LIBSQL_URL) %v", err)
+		}
+	default:
+		add("libsql.mode (LIBSQL_MODE) %q must be local, replica, remote or memory", mode)
+	}
+	if c.LibSQL.SyncInterval < time.Second {
+		add("libsql.sync_interval (LIBSQL_SYNC_INTERVAL) must be at least 1s, got %v", c.LibSQL.SyncInterval)
+	}
+	if c.LibSQL.SyncMaxBackoff < c.LibSQL.SyncInterval {
+		add("libsql.sync_max_backoff (LIBSQL_SYNC_MAX_BACKOFF) must be at least the sync interval, got %v", c.LibSQL.SyncMaxBackoff)
+	}
+	if key, err := c.LibSQL.ResolveEncryptionKey(); err != nil {
+		add("libsql.encryption_key (LIBSQL_ENCRYPTION_KEY/LIBSQL_ENCRYPTION_KEY_FILE): %v", err)
+	} else if key != "" && c.LibSQL.EffectiveMode() != "replica" {
+		add("libsql.encryption_key (LIBSQL_ENCRYPTION_KEY) only applies to replica mode, not %s", c.LibSQL.EffectiveMode())
+	}
+	if c.LibSQL.SyncAlertAfter < 1 {
+		add("libsql.sync_alert_after (LIBSQL_SYNC_ALERT_AFTER) must be at least 1, got %d", c.LibSQL.SyncAlertAfter)
+	}
+
+	// S3 — all or nothing
+	if c.S3.Enabled() {
+		required := []struct{ name, value string }{
+			{"s3.bucket (S3_BUCKET)", c.S3.Bucket},
+			{"s3.region (S3_REGION)", c.S3.Region},
+			{"s3.access_key (S3_ACCESS_KEY)", c.S3.AccessKey},
+			{"s3.secret (S3_SECRET)", c.S3.Secret},
+		}
+		for _, r := range required {
+			if r.value == "" {
+				add("%s is required when S3 storage is configured", r.name)
+			}
+		}
+		if c.S3.Endpoint != "" {
+			if err := validateURL(c.S3.Endpoint, "http", "https"); err != nil {
+				add("s3.endpoint (S3_ENDPOINT) %v", err)
+			}
+		}
+	}
+
+	// OAuth2 — client id and secret come in pairs
+	if (c.OAuth2.GoogleClientID == "") != (c.OAuth2.GoogleClientSecret == "") {
+		add("oauth2.google_client_id and oauth2.google_client_secret (GOOGLE_CLIENT_ID/GOOGLE_CLIENT_SECRET) must be set together")
+	}
+	if (c.OAuth2.GithubClientID == "") != (c.OAuth2.GithubClientSecret == "") {
+		add("oauth2.github_client_id and oauth2.github_client_secret (GITHUB_CLIENT_ID/GITHUB_CLIENT_SECRET) must be set together")
+	}
+
+	// Logging
+	if _, err := zerolog.ParseLevel(c.Log.Level); err != nil || c.Log.Level == "" {
+		add("log.level (LOG_LEVEL) %q is not a valid level", c.Log.Level)
+	}
+
+	// Metrics
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			add("metrics.addr (METRICS_ADDR) %q must be host:port: %v", c.Metrics.Addr, err)
+		}
+	}
+
+	// Rate limits
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Store != "memory" && c.RateLimit.Store != "db" {
+			add("rate_limit.store (RATE_LIMIT_STORE) %q must be memory or db", c.RateLimit.Store)
+		}
+		policies := []struct {
+			name   string
+			policy RateLimitPolicy
+		}{
+			{"invite_verify", c.RateLimit.InviteVerify},
+			{"invite_bulk", c.RateLimit.InviteBulk},
+		}
+		for _, p := range policies {
+			if p.policy.Requests <= 0 || p.policy.Per <= 0 || p.policy.Burst < 0 {
+				add("rate_limit.%s needs positive requests and per, got %d per %v", p.name, p.policy.Requests, p.policy.Per)
+			}
+			switch p.policy.Key {
+			case "ip", "user", "org":
+			default:
+				add("rate_limit.%s.key %q must be ip, user or org", p.name, p.policy.Key)
+			}
+		}
+	}
+
+	// Backups
+	if c.Backup.Schedule != "" {
+		if _, err := cron.NewSchedule(c.Backup.Schedule); err != nil {
+			add("backup.schedule (BACKUP_SCHEDULE) %q is not a valid cron expression: %v", c.Backup.Schedule, err)
+		}
+	}
+	switch c.Backup.Target {
+	case "local":
+		if c.Backup.Dir == "" {
+			add("backup.dir (BACKUP_DIR) is required for local backups")
+		}
+	case "s3":
+		if !c.S3.Enabled() {
+			add("backup.target (BACKUP_TARGET) s3 needs the s3 settings")
+		}
+	default:
+		add("backup.target (BACKUP_TARGET) %q must be local or s3", c.Backup.Target)
+	}
+	if c.Backup.KeepLast < 1 {
+		add("backup.keep_last (BACKUP_KEEP_LAST) must be at least 1, got %d", c.Backup.KeepLast)
+	}
+	if c.Backup.MaxAge < 0 {
+		add("backup.max_age (BACKUP_MAX_AGE) must not be negative, got %v", c.Backup.MaxAge)
+	}
+
+	// Billing
+	switch c.Billing.Provider {
+	case "":
+	case "fake":
+		if c.Billing.WebhookSecret == "" {
+			add("billing.webhook_secret (BILLING_WEBHOOK_SECRET) is required when billing.provider is set")
+		}
+	default:
+		add("billing.provider (BILLING_PROVIDER) %q must be empty or fake", c.Billing.Provider)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validateURL(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid URL: %v", raw, err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q is missing a host", raw)
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q has unsupported scheme %q (expected one of %v)", raw, u.Scheme, schemes)
+}
@@ -0,0 +1,24 @@
+package cronjobs
+
+import (
+	"context"
+	"log"
+
+	"github.com/pocketbase/pocketbase"
+
+	"pocketbase-server/internal/backup"
+)
+
+// RegisterBackups registers the scheduled backup job. Each run creates a
+// backup and prunes old ones according to the manager's retention.
+func RegisterBackups(app *pocketbase.PocketBase, manager *backup.Manager, schedule string) {
+	app.Cron().MustAdd("backup", schedule, track("backup", func() error {
+		info, err := manager.Create(context.Background())
+		if err != nil {
+			log.Printf("backup: failed: %v", err)
+			return err
+		}
+		log.Printf("backup: created %s (%d bytes)", info.Id, info.Size)
+		return nil
+	}))
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/caarlos0/env/v11"
 	"github.com/pocketbase/pocketbase/tools/cron"
+
+	"pocketbase-server/internal/metrics"
 )
 
 // Keep in mind that the app.Cron() is also used for running the system scheduled jobs like the logs cleanup or auto backups (the jobs id is in the format __pb*__) and replacing these system jobs or calling RemoveAll()/Stop() could have unintended side-effects.
@@ -34,7 +36,7 @@ type Cronjob interface {
 
 type cronjobOptions struct {
 	Name              string
-	CronjobExpression string `env:CRONJOB_EXPRESSION`
+	CronjobExpression string `env:"CRONJOB_EXPRESSION"`
 }
 
 func NewCronjobOptions() cronjobOptions {
@@ -44,3 +46,11 @@ func NewCronjobOptions() cronjobOptions {
 	}
 	return opt
 }
+
+// track wraps a cron job so each run and failure is counted in the
+// cron_job_runs_total / cron_job_errors_total metrics.
+func track(name string, job func() error) func() {
+	return func() {
+		metrics.CronRun(name, job())
+	}
+}
@@ -1,6 +1,7 @@
 package cronjobs
 
 import (
+	"fmt"
 	"log"
 	"time"
 
@@ -10,7 +11,7 @@ import (
 // RegisterExpireInvites registers an hourly cron job that marks
 // pending invites past their expires_at as "expired".
 func RegisterExpireInvites(app *pocketbase.PocketBase) {
-	app.Cron().MustAdd("expire_invites", "0 * * * *", func() {
+	app.Cron().MustAdd("expire_invites", "0 * * * *", track("expire_invites", func() error {
 		now := time.Now().UTC().Format("2006-01-02 15:04:05.000Z")
 
 		records, err := app.FindRecordsByFilter(
@@ -22,20 +23,26 @@ func RegisterExpireInvites(app *pocketbase.PocketBase) {
 		)
 		if err != nil {
 			log.Printf("expire_invites: failed to query: %v", err)
-			return
+			return err
 		}
 
 		if len(records) == 0 {
-			return
+			return nil
 		}
 
+		failed := 0
 		for _, record := range records {
 			record.Set("status", "expired")
 			if err := app.Save(record); err != nil {
 				log.Printf("expire_invites: failed to expire invite %s: %v", record.Id, err)
+				failed++
 			}
 		}
 
-		log.Printf("expire_invites: expired %d invite(s)", len(records))
-	})
+		log.Printf("expire_invites: expired %d invite(s)", len(records)-failed)
+		if failed > 0 {
+			return fmt.Errorf("failed to expire %d invite(s)", failed)
+		}
+		return nil
+	}))
 }
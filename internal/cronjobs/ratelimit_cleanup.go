@@ -0,0 +1,26 @@
+package cronjobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+
+	"pocketbase-server/internal/ratelimit"
+)
+
+// RegisterRateLimitCleanup registers an hourly cron job that deletes
+// rate limit buckets unused for a day from the database store.
+func RegisterRateLimitCleanup(app *pocketbase.PocketBase, store *ratelimit.DBStore) {
+	app.Cron().MustAdd("ratelimit_cleanup", "30 * * * *", track("ratelimit_cleanup", func() error {
+		deleted, err := store.Cleanup(time.Now().Add(-24 * time.Hour))
+		if err != nil {
+			log.Printf("ratelimit_cleanup: failed to delete stale buckets: %v", err)
+			return err
+		}
+		if deleted > 0 {
+			log.Printf("ratelimit_cleanup: deleted %d stale bucket(s)", deleted)
+		}
+		return nil
+	}))
+}
@@ -1,68 +1,276 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/google/uuid"
 	"github.com/tursodatabase/go-libsql"
 )
 
+// Mode selects how the main database is opened.
+type Mode string
+
+const (
+	// ModeLocal is a plain SQLite file in DataDir; nothing is synced.
+	ModeLocal Mode = "local"
+	// ModeReplica is an embedded replica in DataDir synced with URL.
+	ModeReplica Mode = "replica"
+	// ModeRemote sends every query to URL; nothing is stored locally.
+	ModeRemote Mode = "remote"
+	// ModeMemory is a private in-memory database, mainly for tests.
+	ModeMemory Mode = "memory"
+)
+
 type LibSQLConfig struct {
-	DataDir  string
-	URL      string
-	Token    string
-	Interval time.Duration
+	Mode    Mode
+	DataDir string
+	URL     string
+	Token   string
+
+	// EncryptionKey encrypts the local replica file (replica mode only).
+	EncryptionKey string
 }
 
+// ErrEncryptionKey is returned when the replica file can't be read with
+// the configured encryption key.
+var ErrEncryptionKey = errors.New("wrong encryption key")
+
 type LibSQLConnection struct {
 	Connector *libsql.Connector
 	DB        *sql.DB
+
+	cfg  LibSQLConfig
+	mode Mode
+	// keeps an in-memory database alive while the pools close idle conns;
+	// held on DB, which PocketBase's pool settings never touch
+	keep *sql.Conn
+
+	syncMu  sync.Mutex // serializes syncs
+	running atomic.Bool
+
+	mu     sync.RWMutex
+	status SyncStatus
 }
 
+// NewLibSQLConnection opens the main database in cfg.Mode. An empty mode
+// is ModeReplica, matching the behaviour before modes existed.
 func NewLibSQLConnection(cfg *LibSQLConfig) (*LibSQLConnection, error) {
-	if cfg.Interval == 0 {
-		cfg.Interval = time.Minute * 5
+	if cfg.Mode == "" {
+		cfg.Mode = ModeReplica
+	}
+	if cfg.EncryptionKey != "" && cfg.Mode != ModeReplica {
+		return nil, fmt.Errorf("encryption at rest needs replica mode, not %s", cfg.Mode)
+	}
+
+	c := &LibSQLConnection{cfg: *cfg, mode: cfg.Mode}
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *LibSQLConnection) open() error {
+	cfg := &c.cfg
+
+	var (
+		connector *libsql.Connector
+		err       error
+	)
+	switch cfg.Mode {
+	case ModeLocal:
+		connector, err = openLocal(cfg)
+	case ModeReplica:
+		connector, err = openReplica(cfg)
+	case ModeRemote:
+		connector, err = openRemote(cfg)
+	case ModeMemory:
+		connector, err = openConnector("file:" + uuid.NewString() + "?mode=memory&cache=shared")
+	default:
+		err = fmt.Errorf("unknown libSQL mode %q", cfg.Mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.Connector = connector
+	c.DB = sql.OpenDB(connector)
+
+	// Fail fast on a wrong key instead of on the first query.
+	if cfg.EncryptionKey != "" {
+		if err := c.checkReadable(); err != nil {
+			c.Close()
+			return keyError(filepath.Join(cfg.DataDir, "data.db"), err)
+		}
+	}
+
+	if cfg.Mode == ModeMemory {
+		if c.keep, err = c.DB.Conn(context.Background()); err != nil {
+			c.Close()
+			return fmt.Errorf("failed to open in-memory database: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// OpenDB returns a new pool over the connector. PocketBase opens data.db
+// twice and sizes each pool itself, so each call gets its own *sql.DB;
+// closing it leaves the connector open for Close.
+func (c *LibSQLConnection) OpenDB() *sql.DB {
+	return sql.OpenDB(poolConnector{c.Connector})
+}
+
+// poolConnector hides the connector's Close from sql.DB.Close.
+type poolConnector struct {
+	connector *libsql.Connector
+}
+
+func (p poolConnector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	return p.connector.Connect(ctx)
+}
+
+func (p poolConnector) Driver() sqldriver.Driver {
+	return p.connector.Driver()
+}
+
+// Reopen closes the connection and opens it again with the same config,
+// e.g. after the database files were replaced. Callers must release
+// every handle on the old DB first.
+func (c *LibSQLConnection) Reopen() error {
+	if err := c.Close(); err != nil {
+		return err
 	}
+	return c.open()
+}
 
+func openLocal(cfg *LibSQLConfig) (*libsql.Connector, error) {
 	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
 
 	localDBPath := filepath.Join(cfg.DataDir, "data.db")
+	connector, err := openConnector("file:" + localDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", localDBPath, err)
+	}
+	return connector, nil
+}
 
-	opts := []libsql.Option{
-		libsql.WithSyncInterval(cfg.Interval),
+// openReplica opens an embedded replica. The connector's own background
+// sync is left off; Syncer schedules syncs so they are tracked.
+func openReplica(cfg *LibSQLConfig) (*libsql.Connector, error) {
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
 
+	localDBPath := filepath.Join(cfg.DataDir, "data.db")
+
+	var opts []libsql.Option
 	if cfg.Token != "" {
 		opts = append(opts, libsql.WithAuthToken(cfg.Token))
 	}
+	if cfg.EncryptionKey != "" {
+		opts = append(opts, libsql.WithEncryption(cfg.EncryptionKey))
+	}
 
 	connector, err := libsql.NewEmbeddedReplicaConnector(localDBPath, cfg.URL, opts...)
 	if err != nil {
+		if isKeyError(err) {
+			return nil, keyError(localDBPath, err)
+		}
 		return nil, fmt.Errorf("failed to open database %s %s: %w", localDBPath, cfg.URL, err)
 	}
+	return connector, nil
+}
 
-	return &LibSQLConnection{
-		Connector: connector,
-		DB:        sql.OpenDB(connector),
-	}, nil
+// checkReadable reads the schema, which fails when the file can't be
+// decrypted.
+func (c *LibSQLConnection) checkReadable() error {
+	var n int
+	return c.DB.QueryRow("SELECT count(*) FROM sqlite_master").Scan(&n)
 }
 
-func (c *LibSQLConnection) Sync() error {
-	if c.Connector != nil {
-		_, err := c.Connector.Sync()
-		return err
+// isKeyError reports whether err is SQLite failing to decode the file,
+// which for an encrypted replica means a wrong or missing key.
+func isKeyError(err error) bool {
+	return strings.Contains(err.Error(), "file is not a database")
+}
+
+func keyError(path string, err error) error {
+	return fmt.Errorf("%w: cannot read %s; check LIBSQL_ENCRYPTION_KEY/LIBSQL_ENCRYPTION_KEY_FILE "+
+		"(a plaintext replica must be encrypted with the rotate-key command first): %v", ErrEncryptionKey, path, err)
+}
+
+func openRemote(cfg *LibSQLConfig) (*libsql.Connector, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid libSQL URL %q: %w", cfg.URL, err)
 	}
-	return nil
+	if cfg.Token != "" {
+		q := u.Query()
+		q.Set("authToken", cfg.Token)
+		u.RawQuery = q.Encode()
+	}
+
+	connector, err := openConnector(u.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open remote database %s: %w", cfg.URL, err)
+	}
+	return connector, nil
+}
+
+// openConnector opens a connector through the registered "libsql" driver,
+// which handles file:, :memory: and remote URLs.
+func openConnector(dsn string) (*libsql.Connector, error) {
+	drv, ok := (&libsql.Connector{}).Driver().(sqldriver.DriverContext)
+	if !ok {
+		return nil, fmt.Errorf("libsql driver does not support connectors")
+	}
+	connector, err := drv.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.(*libsql.Connector), nil
+}
+
+// Mode returns the mode the database was opened in.
+func (c *LibSQLConnection) Mode() Mode {
+	return c.mode
 }
 
+// Ping verifies the database is reachable through the connector.
+func (c *LibSQLConnection) Ping(ctx context.Context) error {
+	if c.DB == nil {
+		return fmt.Errorf("database not opened")
+	}
+	var one int
+	return c.DB.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// Close releases the connector. Later calls, and syncs after it, are
+// no-ops.
 func (c *LibSQLConnection) Close() error {
-	if c.Connector != nil {
-		return c.Connector.Close()
+	if c.keep != nil {
+		c.keep.Close()
+		c.keep = nil
 	}
-	return nil
+
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	if c.Connector == nil {
+		return nil
+	}
+	err := c.Connector.Close()
+	c.Connector = nil
+	return err
 }
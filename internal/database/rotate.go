@@ -0,0 +1,99 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RotateKey re-encrypts the local replica in cfg.DataDir with newKey.
+// cfg.EncryptionKey is the current key (empty for a plaintext replica).
+//
+// A replica is a copy of the primary, so it is rebuilt rather than
+// rewritten in place: the current files are synced, moved aside, and a
+// fresh replica encrypted with newKey is pulled from the primary. The old
+// files are restored if anything fails. The server must be stopped.
+func RotateKey(cfg LibSQLConfig, newKey string) error {
+	if cfg.Mode != "" && cfg.Mode != ModeReplica {
+		return fmt.Errorf("key rotation needs replica mode, not %s", cfg.Mode)
+	}
+	if newKey == "" {
+		return errors.New("the new encryption key must not be empty")
+	}
+	if newKey == cfg.EncryptionKey {
+		return errors.New("the new encryption key is the current key")
+	}
+	cfg.Mode = ModeReplica
+
+	// Make sure the current key works and the replica is up to date.
+	old, err := NewLibSQLConnection(&cfg)
+	if err != nil {
+		return err
+	}
+	tables, err := old.tableCount()
+	if err == nil {
+		err = old.Sync()
+	}
+	old.DB.Close()
+	old.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read the current replica: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.DataDir, "data.db*"))
+	if err != nil {
+		return err
+	}
+
+	backupDir := filepath.Join(cfg.DataDir, "key-rotation-"+time.Now().UTC().Format("20060102T150405"))
+	if err := os.Mkdir(backupDir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", backupDir, err)
+	}
+	for _, f := range files {
+		if err := os.Rename(f, filepath.Join(backupDir, filepath.Base(f))); err != nil {
+			restoreReplica(cfg.DataDir, backupDir)
+			return fmt.Errorf("failed to move %s aside: %w", f, err)
+		}
+	}
+
+	next := cfg
+	next.EncryptionKey = newKey
+	conn, err := NewLibSQLConnection(&next)
+	if err == nil {
+		var got int
+		got, err = conn.tableCount()
+		if err == nil && got != tables {
+			err = fmt.Errorf("re-encrypted replica has %d tables, expected %d", got, tables)
+		}
+		conn.DB.Close()
+		conn.Close()
+	}
+	if err != nil {
+		restoreReplica(cfg.DataDir, backupDir)
+		return fmt.Errorf("failed to rebuild the replica with the new key (old replica restored): %w", err)
+	}
+
+	return os.RemoveAll(backupDir)
+}
+
+// restoreReplica puts the files moved aside by RotateKey back.
+func restoreReplica(dataDir, backupDir string) {
+	current, _ := filepath.Glob(filepath.Join(dataDir, "data.db*"))
+	for _, f := range current {
+		os.Remove(f)
+	}
+
+	moved, _ := filepath.Glob(filepath.Join(backupDir, "data.db*"))
+	for _, f := range moved {
+		os.Rename(f, filepath.Join(dataDir, filepath.Base(f)))
+	}
+	os.Remove(backupDir)
+}
+
+func (c *LibSQLConnection) tableCount() (int, error) {
+	var n int
+	err := c.DB.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table'").Scan(&n)
+	return n, err
+}
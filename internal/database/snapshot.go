@@ -0,0 +1,72 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoLocalCopy is returned by Snapshot in remote mode, where the data
+// only exists on the primary.
+var ErrNoLocalCopy = errors.New("remote mode keeps no local copy of the database")
+
+// Encrypted reports whether the local database file is encrypted.
+func (c *LibSQLConnection) Encrypted() bool {
+	return c.cfg.EncryptionKey != ""
+}
+
+// Snapshot writes a consistent copy of the database to dst.
+//
+// Local and in-memory databases are copied with VACUUM INTO. A replica
+// only changes when it syncs, and syncs write to its WAL, so while syncs
+// are held off the WAL is checkpointed into the file and the file is
+// copied; the copy stays encrypted when the replica is.
+func (c *LibSQLConnection) Snapshot(dst string) error {
+	switch c.mode {
+	case ModeLocal, ModeMemory:
+		_, err := c.DB.Exec("VACUUM INTO ?", dst)
+		return err
+	case ModeReplica:
+		c.syncMu.Lock()
+		defer c.syncMu.Unlock()
+		if err := c.checkpoint(); err != nil {
+			return err
+		}
+		return copyFile(filepath.Join(c.cfg.DataDir, "data.db"), dst)
+	default:
+		return ErrNoLocalCopy
+	}
+}
+
+// checkpoint moves every WAL frame into the database file and empties the
+// WAL. It fails rather than leave frames behind when readers hold it up.
+func (c *LibSQLConnection) checkpoint() error {
+	var busy, frames, checkpointed int
+	if err := c.DB.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &frames, &checkpointed); err != nil {
+		return fmt.Errorf("failed to checkpoint the replica: %w", err)
+	}
+	if busy != 0 {
+		return errors.New("failed to checkpoint the replica: the database is busy, try again")
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return out.Close()
+}
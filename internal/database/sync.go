@@ -0,0 +1,133 @@
+package database
+
+import (
+	"time"
+
+	"pocketbase-server/internal/metrics"
+)
+
+// historySize is how many syncs SyncStatus.History keeps.
+const historySize = 50
+
+// SyncRecord is the outcome of a single sync.
+type SyncRecord struct {
+	Started      time.Time     `json:"started"`
+	Duration     time.Duration `json:"duration"`
+	FramesSynced int           `json:"frames_synced"`
+	FrameNo      int           `json:"frame_no"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// SyncStatus describes the recent syncs of an embedded replica, whether
+// run by Syncer or on demand.
+type SyncStatus struct {
+	LastAttempt  time.Time
+	LastSuccess  time.Time
+	LastError    error
+	LastDuration time.Duration
+
+	// Frames pulled by the last successful sync and the frame number
+	// the replica reached.
+	FramesSynced int
+	FrameNo      int
+
+	ConsecutiveFailures int
+	Running             bool
+
+	// Most recent first, up to 50 entries.
+	History []SyncRecord
+}
+
+// Syncs reports whether the connection is an embedded replica, the only
+// mode where Sync does anything.
+func (c *LibSQLConnection) Syncs() bool {
+	return c.mode == ModeReplica
+}
+
+// Sync pulls changes from the primary and records the result. It is a
+// no-op unless the connection is an embedded replica. Concurrent calls
+// run one after the other.
+func (c *LibSQLConnection) Sync() error {
+	if !c.Syncs() {
+		return nil
+	}
+
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	if c.Connector == nil {
+		return nil // closed
+	}
+	return c.sync()
+}
+
+// SyncAsync starts a sync in the background and returns immediately.
+// It returns false without starting one when a sync is already running
+// or the connection isn't a replica. The outcome shows in SyncStatus.
+func (c *LibSQLConnection) SyncAsync() bool {
+	if !c.Syncs() || !c.syncMu.TryLock() {
+		return false
+	}
+	if c.Connector == nil {
+		c.syncMu.Unlock()
+		return false
+	}
+
+	c.running.Store(true)
+	go func() {
+		defer c.syncMu.Unlock()
+		c.sync()
+	}()
+	return true
+}
+
+// sync runs one sync; the caller holds syncMu.
+func (c *LibSQLConnection) sync() error {
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	start := time.Now()
+	rep, err := c.Connector.Sync()
+	c.recordSync(SyncRecord{
+		Started:      start,
+		Duration:     time.Since(start),
+		FramesSynced: rep.FramesSynced,
+		FrameNo:      rep.FrameNo,
+	}, err)
+	return err
+}
+
+func (c *LibSQLConnection) recordSync(rec SyncRecord, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.status.LastAttempt = rec.Started
+	c.status.LastError = err
+	c.status.LastDuration = rec.Duration
+	if err != nil {
+		rec.Error = err.Error()
+		c.status.ConsecutiveFailures++
+	} else {
+		c.status.LastSuccess = rec.Started
+		c.status.FramesSynced = rec.FramesSynced
+		c.status.FrameNo = rec.FrameNo
+		c.status.ConsecutiveFailures = 0
+	}
+
+	c.status.History = append([]SyncRecord{rec}, c.status.History...)
+	if len(c.status.History) > historySize {
+		c.status.History = c.status.History[:historySize]
+	}
+
+	metrics.ObserveSync(rec.Duration, rec.FramesSynced, rec.FrameNo, c.status.ConsecutiveFailures, err)
+}
+
+// SyncStatus returns a snapshot of the recent syncs.
+func (c *LibSQLConnection) SyncStatus() SyncStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := c.status
+	status.History = append([]SyncRecord(nil), c.status.History...)
+	status.Running = c.running.Load()
+	return status
+}
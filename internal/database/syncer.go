@@ -0,0 +1,122 @@
+package database
+
+import (
+	"sync"
+	"time"
+)
+
+// Syncable is a connection Syncer can schedule, normally a
+// *LibSQLConnection in replica mode.
+type Syncable interface {
+	Sync() error
+	SyncStatus() SyncStatus
+}
+
+// SyncerConfig controls the sync schedule.
+type SyncerConfig struct {
+	// Time between syncs while they succeed.
+	Interval time.Duration
+	// Upper bound for the delay after failures (default 10 minutes).
+	MaxBackoff time.Duration
+	// Consecutive failures that trigger OnAlert (default 3). OnAlert runs
+	// once per failure streak; OnRecover runs when that streak ends.
+	AlertAfter int
+	OnAlert    func(status SyncStatus)
+	OnRecover  func(status SyncStatus)
+}
+
+// Syncer runs syncs on a schedule, doubling the delay after each
+// consecutive failure up to MaxBackoff.
+type Syncer struct {
+	conn Syncable
+	cfg  SyncerConfig
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSyncer creates a Syncer for conn. Call Start to begin syncing.
+func NewSyncer(conn Syncable, cfg SyncerConfig) *Syncer {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.MaxBackoff < cfg.Interval {
+		cfg.MaxBackoff = max(10*time.Minute, cfg.Interval)
+	}
+	if cfg.AlertAfter <= 0 {
+		cfg.AlertAfter = 3
+	}
+	return &Syncer{conn: conn, cfg: cfg}
+}
+
+// Backoff returns the delay before the next sync after failures
+// consecutive failures: interval, then doubling up to maxBackoff.
+func Backoff(interval, maxBackoff time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// Start begins the sync loop. It is a no-op when already running.
+func (s *Syncer) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.loop(s.stop, s.done)
+}
+
+// Stop ends the sync loop and waits for a sync in progress to finish.
+func (s *Syncer) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (s *Syncer) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	timer := time.NewTimer(s.cfg.Interval)
+	defer timer.Stop()
+
+	alerted := false
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+
+		err := s.conn.Sync()
+		status := s.conn.SyncStatus()
+
+		switch {
+		case err != nil && !alerted && status.ConsecutiveFailures >= s.cfg.AlertAfter:
+			alerted = true
+			if s.cfg.OnAlert != nil {
+				s.cfg.OnAlert(status)
+			}
+		case err == nil && alerted:
+			alerted = false
+			if s.cfg.OnRecover != nil {
+				s.cfg.OnRecover(status)
+			}
+		}
+
+		timer.Reset(Backoff(s.cfg.Interval, s.cfg.MaxBackoff, status.ConsecutiveFailures))
+	}
+}
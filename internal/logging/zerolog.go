@@ -7,7 +7,6 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/caarlos0/env/v11"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -32,35 +31,29 @@ var (
 	}
 )
 
-// Config represents the settings populated by caarlos0/env
+// option holds the logger settings; level and console output come
+// from config.LogConfig via WithLevel and WithConsole.
 type option struct {
-	LogLevel          string `env:"LOG_LEVEL" envDefault:"info"`
-	ConsoleWriter     bool   `env:"LOG_CONSOLE" envDefault:"false"`
+	LogLevel          string
+	ConsoleWriter     bool
 	CallerMarshalFunc func(pc uintptr, file string, line int) string
 	Writer            io.Writer
 }
 type Option func(*option)
 
-func NewOption() (*option, error) {
-	var cfg option
-	if err := env.Parse(&cfg); err != nil {
-		return nil, err
-	}
-	return &cfg, nil
+func newOption() *option {
+	return &option{LogLevel: "info"}
 }
 
 func SetGlobal(opts ...Option) error {
-	newLogger := NewLogger()
+	newLogger := NewLogger(opts...)
 	log.Logger = newLogger
 	zerolog.DefaultContextLogger = &newLogger
 	return nil
 }
 
 func NewLogger(opts ...Option) zerolog.Logger {
-	cfg, err := NewOption()
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to parse zerlog option")
-	}
+	cfg := newOption()
 	for _, opt := range opts {
 		opt(cfg)
 	}
@@ -0,0 +1,148 @@
+// Package metrics holds the Prometheus collectors for the service.
+//
+// Collectors live on a package registry so instrumented packages
+// (database, cronjobs, notifications, organizations) only call the
+// Observe/Count helpers below; the server exposes Handler at /metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry is the registry served by Handler.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route pattern and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	syncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "libsql_sync_duration_seconds",
+		Help:    "Duration of libSQL embedded-replica syncs.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	})
+
+	syncFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "libsql_sync_failures_total",
+		Help: "libSQL embedded-replica syncs that returned an error.",
+	})
+
+	syncFrames = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "libsql_sync_frames_total",
+		Help: "WAL frames pulled from the primary by libSQL syncs.",
+	})
+
+	syncFrameNo = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "libsql_sync_frame_number",
+		Help: "Replication frame number after the last successful sync.",
+	})
+
+	syncConsecutiveFailures = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "libsql_sync_consecutive_failures",
+		Help: "libSQL syncs that failed in a row; 0 after a success.",
+	})
+
+	cronRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cron_job_runs_total",
+		Help: "Cron job runs by job name.",
+	}, []string{"job"})
+
+	cronErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cron_job_errors_total",
+		Help: "Cron job runs that failed, by job name.",
+	}, []string{"job"})
+
+	notificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifications_sent_total",
+		Help: "Notification records created, by notification type and result.",
+	}, []string{"type", "result"})
+
+	inviteEmails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "invite_emails_total",
+		Help: "Invite emails by result (sent or failed).",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		syncDuration,
+		syncFailures,
+		syncFrames,
+		syncFrameNo,
+		syncConsecutiveFailures,
+		cronRuns,
+		cronErrors,
+		notificationsSent,
+		inviteEmails,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTPRequest records one HTTP request. route is the matched
+// route pattern (e.g. /api/orgs/{orgId}/invites/bulk), not the raw path,
+// to keep label cardinality bounded.
+func ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// ObserveSync records a libSQL sync: its duration, the frames it pulled,
+// the resulting frame number and the current run of failures.
+func ObserveSync(d time.Duration, framesSynced, frameNo, consecutiveFailures int, err error) {
+	syncDuration.Observe(d.Seconds())
+	syncConsecutiveFailures.Set(float64(consecutiveFailures))
+	if err != nil {
+		syncFailures.Inc()
+		return
+	}
+	syncFrames.Add(float64(framesSynced))
+	syncFrameNo.Set(float64(frameNo))
+}
+
+// CronRun records a run of the named cron job.
+func CronRun(job string, err error) {
+	cronRuns.WithLabelValues(job).Inc()
+	if err != nil {
+		cronErrors.WithLabelValues(job).Inc()
+	}
+}
+
+// NotificationSent records a notification send of the given type.
+func NotificationSent(notificationType string, err error) {
+	notificationsSent.WithLabelValues(notificationType, result(err)).Inc()
+}
+
+// InviteEmail records an invite email send.
+func InviteEmail(err error) {
+	inviteEmails.WithLabelValues(result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failed"
+	}
+	return "sent"
+}
@@ -2,6 +2,8 @@ package notifications
 
 import (
 	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/metrics"
 )
 
 // Notification types
@@ -77,6 +79,12 @@ func NewClient(app core.App) NotificationClient {
 
 // Send creates a record and returns the typed Notification struct
 func (s *notificationService) Send(opts NotificationOpts) (*Notification, error) {
+	n, err := s.send(opts)
+	metrics.NotificationSent(opts.Type, err)
+	return n, err
+}
+
+func (s *notificationService) send(opts NotificationOpts) (*Notification, error) {
 	col, err := s.app.FindCollectionByNameOrId("notifications")
 	if err != nil {
 		return nil, err
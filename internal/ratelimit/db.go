@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TableName is the table holding DBStore buckets in the main database.
+const TableName = "_rate_limits"
+
+// DBStore keeps buckets in the app database, so every instance sharing
+// the (libSQL) database shares the limits.
+type DBStore struct {
+	app core.App
+}
+
+func NewDBStore(app core.App) *DBStore {
+	return &DBStore{app: app}
+}
+
+// Ensure creates the bucket table if it doesn't exist yet.
+func (s *DBStore) Ensure() error {
+	_, err := s.app.DB().NewQuery(`
+		CREATE TABLE IF NOT EXISTS {{` + TableName + `}} (
+			[[key]]     TEXT PRIMARY KEY NOT NULL,
+			[[tokens]]  REAL NOT NULL,
+			[[updated]] INTEGER NOT NULL
+		)
+	`).Execute()
+	return err
+}
+
+func (s *DBStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	var allowed bool
+	var retryAfter time.Duration
+
+	err := s.app.RunInTransaction(func(tx core.App) error {
+		row := struct {
+			Tokens  float64 `db:"tokens"`
+			Updated int64   `db:"updated"`
+		}{}
+
+		err := tx.DB().Select("tokens", "updated").
+			From(TableName).
+			Where(dbx.HashExp{"key": key}).
+			One(&row)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			row.Tokens = limit.capacity()
+			row.Updated = now.UnixMilli()
+		case err != nil:
+			return err
+		}
+
+		var tokens float64
+		tokens, allowed, retryAfter = limit.take(row.Tokens, time.UnixMilli(row.Updated), now)
+
+		_, err = tx.DB().NewQuery(`
+			INSERT INTO {{` + TableName + `}} ([[key]], [[tokens]], [[updated]])
+			VALUES ({:key}, {:tokens}, {:updated})
+			ON CONFLICT([[key]]) DO UPDATE SET [[tokens]] = excluded.[[tokens]], [[updated]] = excluded.[[updated]]
+		`).Bind(dbx.Params{
+			"key":     key,
+			"tokens":  tokens,
+			"updated": now.UnixMilli(),
+		}).Execute()
+		return err
+	})
+
+	return allowed, retryAfter, err
+}
+
+// Cleanup removes buckets not used since before.
+func (s *DBStore) Cleanup(before time.Time) (int64, error) {
+	res, err := s.app.DB().Delete(TableName, dbx.NewExp("[[updated]] < {:before}", dbx.Params{"before": before.UnixMilli()})).Execute()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,56 @@
+// Package ratelimit implements token-bucket rate limiting with pluggable
+// state stores: MemoryStore for a single instance and DBStore to share
+// buckets between instances through the app database.
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: Requests tokens are added every Per,
+// up to Burst tokens (Requests when Burst is zero). Each request takes
+// one token.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// Store keeps bucket state and takes a token from the bucket named key.
+// When the bucket is empty it reports how long until a token is available.
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error)
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate is the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// take refills a bucket that held tokens at last and takes one token.
+// It returns the new token count, whether a token was taken and, if not,
+// the wait until one is available.
+func (l Limit) take(tokens float64, last, now time.Time) (float64, bool, time.Duration) {
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(l.capacity(), tokens+elapsed*l.rate())
+	}
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	wait := (1 - tokens) / l.rate()
+	return tokens, false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// idle reports how long a bucket takes to refill completely; buckets not
+// touched for longer than this are equivalent to new ones.
+func (l Limit) idle() time.Duration {
+	return time.Duration(l.capacity() / l.rate() * float64(time.Second))
+}
@@ -0,0 +1,108 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Export writes every exportable collection of app to w. Only
+// opts.Organization applies.
+func Export(ctx context.Context, app core.App, w io.Writer, opts Options) (*Summary, error) {
+	d, err := load(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Organization != "" {
+		if err := d.filterOrganization(opts.Organization); err != nil {
+			return nil, err
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	now := time.Now().UTC()
+	if err := enc.Encode(Line{Type: TypeMeta, Version: Version, Exported: &now, Organization: opts.Organization}); err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{}
+	for _, name := range d.order() {
+		c := d.collections[name]
+		line := Line{Type: TypeCollection, Name: name, Relations: c.relations, Schema: c.schema}
+		if err := enc.Encode(line); err != nil {
+			return nil, err
+		}
+		for _, r := range c.records {
+			if err := enc.Encode(Line{Type: TypeRecord, Collection: name, Data: r}); err != nil {
+				return nil, err
+			}
+		}
+		summary.Collections = append(summary.Collections, CollectionSummary{Name: name, Records: len(c.records)})
+	}
+	return summary, nil
+}
+
+// load reads every exportable collection and its records.
+func load(ctx context.Context, app core.App) (*dataset, error) {
+	collections, err := app.FindAllCollections()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(collections))
+	for _, c := range collections {
+		names[c.Id] = c.Name
+	}
+
+	d := &dataset{collections: map[string]*collectionData{}}
+	for _, c := range collections {
+		if !exportable(c) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		schema, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		data := &collectionData{name: c.Name, relations: map[string]Relation{}, schema: schema}
+		for _, f := range c.Fields {
+			if rel, ok := f.(*core.RelationField); ok {
+				data.relations[rel.Name] = Relation{Collection: names[rel.CollectionId], Cascade: rel.CascadeDelete}
+			}
+		}
+
+		var records []*core.Record
+		if err := app.RecordQuery(c).OrderBy("id").All(&records); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", c.Name, err)
+		}
+		for _, r := range records {
+			data.records = append(data.records, recordData(r))
+		}
+		d.collections[c.Name] = data
+	}
+	return d, nil
+}
+
+// recordData returns every field of r, including hidden ones and the
+// password hash.
+func recordData(r *core.Record) map[string]any {
+	fields := r.Collection().Fields
+	data := make(map[string]any, len(fields))
+	for _, f := range fields {
+		name := f.GetName()
+		if _, ok := f.(*core.PasswordField); ok {
+			if pw, ok := r.GetRaw(name).(*core.PasswordFieldValue); ok {
+				data[name] = pw.Hash
+			}
+			continue
+		}
+		data[name] = r.GetRaw(name)
+	}
+	return data
+}
@@ -0,0 +1,274 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxDangling caps the dangling relations listed in an import error.
+const maxDangling = 10
+
+var errDryRun = errors.New("dry run")
+
+// Import reads an export from r and writes it to app in one transaction.
+// The target collections must exist (run bootstrap first); exported fields
+// they lack are skipped and reported, fields whose type changed fail the
+// import, and so does any relation left pointing at a missing record.
+func Import(ctx context.Context, app core.App, r io.Reader, opts Options) (*Summary, error) {
+	d, err := read(r)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Organization != "" {
+		if err := d.filterOrganization(opts.Organization); err != nil {
+			return nil, err
+		}
+	}
+
+	order := d.order()
+	targets := make(map[string]*core.Collection, len(order))
+	summary := &Summary{}
+	for _, name := range order {
+		target, skipped, err := matchSchema(app, d.collections[name])
+		if err != nil {
+			return nil, err
+		}
+		targets[name] = target
+		summary.Collections = append(summary.Collections, CollectionSummary{
+			Name:          name,
+			Records:       len(d.collections[name].records),
+			SkippedFields: skipped,
+		})
+	}
+
+	ids := d.assignIds(opts.RemapIds)
+
+	err = app.RunInTransaction(func(txApp core.App) error {
+		for i, name := range order {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			c := d.collections[name]
+			for _, data := range c.records {
+				created, err := write(txApp, targets[name], c, data, ids)
+				if err != nil {
+					return fmt.Errorf("failed to import %s %v: %w", name, data["id"], err)
+				}
+				if created {
+					summary.Collections[i].Created++
+				} else {
+					summary.Collections[i].Updated++
+				}
+			}
+		}
+
+		if err := checkRelations(txApp, d, order, ids); err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// read parses an export into memory.
+func read(r io.Reader) (*dataset, error) {
+	d := &dataset{collections: map[string]*collectionData{}}
+	dec := json.NewDecoder(r)
+
+	for n := 1; ; n++ {
+		var line Line
+		if err := dec.Decode(&line); err == io.EOF {
+			if n == 1 {
+				return nil, fmt.Errorf("%w: empty stream", ErrFormat)
+			}
+			return d, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrFormat, n, err)
+		}
+
+		switch {
+		case n == 1:
+			if line.Type != TypeMeta {
+				return nil, fmt.Errorf("%w: missing meta line", ErrFormat)
+			}
+			if line.Version != Version {
+				return nil, fmt.Errorf("%w: unsupported version %d", ErrFormat, line.Version)
+			}
+		case line.Type == TypeCollection:
+			if line.Name == "" || d.collections[line.Name] != nil {
+				return nil, fmt.Errorf("%w: line %d: missing or repeated collection %q", ErrFormat, n, line.Name)
+			}
+			d.collections[line.Name] = &collectionData{name: line.Name, relations: line.Relations, schema: line.Schema}
+		case line.Type == TypeRecord:
+			c := d.collections[line.Collection]
+			if c == nil {
+				return nil, fmt.Errorf("%w: line %d: record before its collection %q", ErrFormat, n, line.Collection)
+			}
+			if id, _ := line.Data["id"].(string); id == "" {
+				return nil, fmt.Errorf("%w: line %d: record without id", ErrFormat, n)
+			}
+			c.records = append(c.records, line.Data)
+		default:
+			return nil, fmt.Errorf("%w: line %d: unexpected %q line", ErrFormat, n, line.Type)
+		}
+	}
+}
+
+// matchSchema finds the target collection of c and returns the exported
+// fields it lacks.
+func matchSchema(app core.App, c *collectionData) (*core.Collection, []string, error) {
+	target, err := app.FindCollectionByNameOrId(c.name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("collection %q does not exist; run bootstrap first", c.name)
+	}
+
+	var schema struct {
+		Fields []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(c.schema, &schema); err != nil {
+		return nil, nil, fmt.Errorf("%w: schema of %s: %v", ErrFormat, c.name, err)
+	}
+
+	var skipped []string
+	for _, f := range schema.Fields {
+		existing := target.Fields.GetByName(f.Name)
+		if existing == nil {
+			skipped = append(skipped, f.Name)
+			continue
+		}
+		if existing.Type() != f.Type {
+			return nil, nil, fmt.Errorf("%s.%s is a %s field here but %s in the export", c.name, f.Name, existing.Type(), f.Type)
+		}
+	}
+	return target, skipped, nil
+}
+
+// assignIds maps every exported id to the id it gets in the target.
+func (d *dataset) assignIds(remap bool) map[string]map[string]string {
+	ids := make(map[string]map[string]string, len(d.collections))
+	for name, c := range d.collections {
+		ids[name] = make(map[string]string, len(c.records))
+		for _, r := range c.records {
+			id := r["id"].(string)
+			if remap {
+				ids[name][id] = core.GenerateDefaultRandomId()
+			} else {
+				ids[name][id] = id
+			}
+		}
+	}
+	return ids
+}
+
+// write inserts or overwrites one record and reports whether it was
+// created.
+func write(txApp core.App, target *core.Collection, c *collectionData, data map[string]any, ids map[string]map[string]string) (bool, error) {
+	id := ids[c.name][data["id"].(string)]
+
+	record := core.NewRecord(target)
+	for name, v := range data {
+		field := target.Fields.GetByName(name)
+		if field == nil {
+			continue
+		}
+		if rel, ok := c.relations[name]; ok && ids[rel.Collection] != nil {
+			v = mapRelation(v, func(old string) string {
+				if id, ok := ids[rel.Collection][old]; ok {
+					return id
+				}
+				return old
+			})
+		}
+		// PrepareValue rather than Set, which hashes passwords again and
+		// ignores autodate values.
+		value, err := field.PrepareValue(record, v)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		record.SetRaw(name, value)
+	}
+	record.SetRaw(core.FieldNameId, id)
+
+	row, err := record.DBExport(txApp)
+	if err != nil {
+		return false, err
+	}
+
+	var exists int
+	err = txApp.DB().Select("count(*)").From(target.Name).Where(dbx.HashExp{"id": id}).Row(&exists)
+	if err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		_, err = txApp.DB().Update(target.Name, dbx.Params(row), dbx.HashExp{"id": id}).Execute()
+		return false, err
+	}
+	_, err = txApp.DB().Insert(target.Name, dbx.Params(row)).Execute()
+	return true, err
+}
+
+// checkRelations fails when an imported relation points at a record that
+// is neither imported nor already in the target.
+func checkRelations(txApp core.App, d *dataset, order []string, ids map[string]map[string]string) error {
+	var dangling []string
+	exists := map[string]map[string]bool{}
+
+	for _, name := range order {
+		c := d.collections[name]
+		fields := make([]string, 0, len(c.relations))
+		for field := range c.relations {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, r := range c.records {
+			for _, field := range fields {
+				target := c.relations[field].Collection
+				for _, old := range relationIds(r[field]) {
+					if _, ok := ids[target][old]; ok {
+						continue
+					}
+					if exists[target] == nil {
+						exists[target] = map[string]bool{}
+					}
+					ok, checked := exists[target][old]
+					if !checked {
+						_, err := txApp.FindRecordById(target, old)
+						ok = err == nil
+						exists[target][old] = ok
+					}
+					if !ok {
+						dangling = append(dangling, fmt.Sprintf("%s %s.%s -> %s %s", name, r["id"], field, target, old))
+					}
+				}
+			}
+		}
+	}
+
+	if len(dangling) == 0 {
+		return nil
+	}
+	more := ""
+	if len(dangling) > maxDangling {
+		more = fmt.Sprintf(" (and %d more)", len(dangling)-maxDangling)
+		dangling = dangling[:maxDangling]
+	}
+	return fmt.Errorf("relations point at missing records: %s%s", strings.Join(dangling, "; "), more)
+}
@@ -0,0 +1,327 @@
+// Package transfer exports collections as JSONL and imports them into
+// another environment, e.g. from a local SQLite file to Turso.
+//
+// An export is one JSONL stream: a "meta" line, then for every collection
+// a "collection" line with its schema followed by one "record" line per
+// record. Collections come in dependency order, so the targets of a
+// collection's relation fields precede it (cycles are broken by name).
+//
+// Import writes rows directly, like restoring a dump: record hooks don't
+// run, so no notifications are sent and no plan limits are applied, and
+// created/updated timestamps are kept. Uploaded files are not included;
+// copy the storage with a backup.
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Version is the format version written to the meta line.
+const Version = 1
+
+// OrganizationsCollection is the root of an organization filter.
+const OrganizationsCollection = "organizations"
+
+// Line types.
+const (
+	TypeMeta       = "meta"
+	TypeCollection = "collection"
+	TypeRecord     = "record"
+)
+
+// ErrFormat is returned for streams that aren't a valid export.
+var ErrFormat = errors.New("invalid export")
+
+// Line is one line of an export. Only the fields of its Type are set.
+type Line struct {
+	Type string `json:"type"`
+
+	// meta
+	Version      int        `json:"version,omitempty"`
+	Exported     *time.Time `json:"exported,omitempty"`
+	Organization string     `json:"organization,omitempty"`
+
+	// collection
+	Name      string              `json:"name,omitempty"`
+	Relations map[string]Relation `json:"relations,omitempty"`
+	Schema    json.RawMessage     `json:"schema,omitempty"`
+
+	// record
+	Collection string         `json:"collection,omitempty"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// Relation describes a relation field by target collection name, since
+// collection ids differ between environments.
+type Relation struct {
+	Collection string `json:"collection"`
+	Cascade    bool   `json:"cascade,omitempty"`
+}
+
+// Options select what is exported or imported.
+type Options struct {
+	// Organization limits the data to one organization: its record,
+	// records reaching it through relation fields, the records those
+	// reference (e.g. member users) and records owned by those through
+	// cascade-delete relations (e.g. user settings).
+	Organization string
+
+	// RemapIds gives imported records new ids and rewrites relations to
+	// match. Without it ids are kept and existing records are overwritten.
+	RemapIds bool
+
+	// DryRun runs the import and rolls it back.
+	DryRun bool
+}
+
+// Summary reports the records handled per collection.
+type Summary struct {
+	Collections []CollectionSummary `json:"collections"`
+}
+
+// CollectionSummary reports one collection of an export or import.
+type CollectionSummary struct {
+	Name    string `json:"name"`
+	Records int    `json:"records"`
+	Created int    `json:"created,omitempty"`
+	Updated int    `json:"updated,omitempty"`
+
+	// SkippedFields were exported but don't exist in the target.
+	SkippedFields []string `json:"skipped_fields,omitempty"`
+}
+
+// collectionData is a collection held in memory between reading and
+// writing.
+type collectionData struct {
+	name      string
+	relations map[string]Relation
+	schema    json.RawMessage
+	records   []map[string]any
+}
+
+type dataset struct {
+	collections map[string]*collectionData
+}
+
+// order returns the collection names with relation targets first. Among
+// collections that are ready at the same time, and to break cycles, names
+// are taken alphabetically.
+func (d *dataset) order() []string {
+	names := make([]string, 0, len(d.collections))
+	for name := range d.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	done := make(map[string]bool, len(names))
+	ready := func(name string) bool {
+		for _, rel := range d.collections[name].relations {
+			if rel.Collection != name && !done[rel.Collection] && d.collections[rel.Collection] != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	order := make([]string, 0, len(names))
+	for len(order) < len(names) {
+		next := ""
+		for _, name := range names {
+			if !done[name] && ready(name) {
+				next = name
+				break
+			}
+		}
+		if next == "" { // cycle
+			for _, name := range names {
+				if !done[name] {
+					next = name
+					break
+				}
+			}
+		}
+		done[next] = true
+		order = append(order, next)
+	}
+	return order
+}
+
+// relationIds returns the ids of a relation value, which is a string for
+// single relations and a list for multiple ones.
+func relationIds(v any) []string {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		ids := make([]string, 0, len(v))
+		for _, id := range v {
+			if s, ok := id.(string); ok && s != "" {
+				ids = append(ids, s)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+// mapRelation rewrites the ids of a relation value, keeping its shape.
+func mapRelation(v any, fn func(string) string) any {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return v
+		}
+		return fn(v)
+	case []string:
+		out := make([]string, len(v))
+		for i, id := range v {
+			out[i] = fn(id)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, id := range v {
+			if s, ok := id.(string); ok {
+				out[i] = fn(s)
+			} else {
+				out[i] = id
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// filterOrganization drops everything outside the organization with id;
+// see Options.Organization.
+func (d *dataset) filterOrganization(id string) error {
+	root := d.collections[OrganizationsCollection]
+	if root == nil {
+		return fmt.Errorf("no %s collection to filter by", OrganizationsCollection)
+	}
+
+	included := map[string]map[string]bool{}
+	include := func(collection, id string) bool {
+		if included[collection] == nil {
+			included[collection] = map[string]bool{}
+		}
+		if included[collection][id] {
+			return false
+		}
+		included[collection][id] = true
+		return true
+	}
+
+	found := false
+	for _, r := range root.records {
+		if r["id"] == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("organization %q not found", id)
+	}
+	include(OrganizationsCollection, id)
+
+	// Collections reaching the organization through relations, parents
+	// first.
+	scoped := map[string]bool{OrganizationsCollection: true}
+	order := d.order()
+	for _, name := range order {
+		c := d.collections[name]
+		if scoped[name] {
+			continue
+		}
+
+		var fields []string
+		for field, rel := range c.relations {
+			if rel.Collection != name && scoped[rel.Collection] {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		scoped[name] = true
+		for _, r := range c.records {
+			if rid, _ := r["id"].(string); d.references(r, c, fields, included) {
+				include(name, rid)
+			}
+		}
+	}
+
+	// Pull in unscoped records that included ones reference or own.
+	for changed := true; changed; {
+		changed = false
+		for _, name := range order {
+			c := d.collections[name]
+			for _, r := range c.records {
+				rid, _ := r["id"].(string)
+				if included[name][rid] {
+					for field, rel := range c.relations {
+						if scoped[rel.Collection] {
+							continue
+						}
+						for _, target := range relationIds(r[field]) {
+							changed = include(rel.Collection, target) || changed
+						}
+					}
+					continue
+				}
+				if scoped[name] {
+					continue
+				}
+				for field, rel := range c.relations {
+					if rel.Cascade && d.references(r, c, []string{field}, included) {
+						changed = include(name, rid) || changed
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for name, c := range d.collections {
+		kept := c.records[:0]
+		for _, r := range c.records {
+			if rid, _ := r["id"].(string); included[name][rid] {
+				kept = append(kept, r)
+			}
+		}
+		c.records = kept
+	}
+	return nil
+}
+
+// references reports whether any of the relation fields of r points at an
+// included record.
+func (d *dataset) references(r map[string]any, c *collectionData, fields []string, included map[string]map[string]bool) bool {
+	for _, field := range fields {
+		target := c.relations[field].Collection
+		for _, id := range relationIds(r[field]) {
+			if included[target][id] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exportable reports whether the collection holds data worth moving:
+// system collections (superusers, OTPs, ...) and views are skipped.
+func exportable(c *core.Collection) bool {
+	return !c.System && !c.IsView()
+}
@@ -0,0 +1,191 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+
+	"pocketbase-server/internal/webhooks"
+	"pocketbase-server/pb/collections/entitlements"
+)
+
+var (
+	ErrUnknownPlan = errors.New("unknown plan")
+	ErrSamePlan    = errors.New("organization is already on this plan")
+)
+
+// managedFields can only be changed through the billing workflow.
+var managedFields = []string{"billing_plan", "features", "pending_plan", "subscription_id", "subscription_status"}
+
+// DowngradeError reports usage that doesn't fit the requested plan.
+type DowngradeError struct {
+	Plan    string
+	Feature string
+	Limit   int
+	Used    int
+}
+
+func (e *DowngradeError) Error() string {
+	return fmt.Sprintf("cannot move to the %s plan: %s is %d, the plan allows %d", e.Plan, e.Feature, e.Used, e.Limit)
+}
+
+// RequestPlanChange validates a plan change for orgId and hands it to the
+// provider. Downgrades are refused while current usage exceeds the target
+// plan. The requested plan is recorded as pending_plan until the provider
+// confirms it through a subscription event.
+func RequestPlanChange(ctx context.Context, app core.App, provider Provider, orgId, planName, requestedBy string) (*ChangeResult, error) {
+	target, ok := entitlements.LookupPlan(planName)
+	if !ok {
+		return nil, ErrUnknownPlan
+	}
+
+	settings, err := orgSettings(app, orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	current := settings.GetString("billing_plan")
+	if current == "" {
+		current = entitlements.DefaultPlan
+	}
+	if current == target.Name {
+		return nil, ErrSamePlan
+	}
+
+	if currentPlan, _ := entitlements.LookupPlan(current); target.Rank < currentPlan.Rank {
+		ent := &entitlements.Entitlements{OrgId: orgId, Plan: target.Name, Limits: target.Limits}
+		usage, err := ent.Usage(app)
+		if err != nil {
+			return nil, err
+		}
+		for feature, u := range usage {
+			if u.Limit >= 0 && u.Used > u.Limit {
+				return nil, &DowngradeError{Plan: target.Name, Feature: feature, Limit: u.Limit, Used: u.Used}
+			}
+		}
+	}
+
+	result, err := provider.RequestChange(ctx, ChangeRequest{
+		OrgId:          orgId,
+		CurrentPlan:    current,
+		Plan:           target.Name,
+		SubscriptionId: settings.GetString("subscription_id"),
+		RequestedBy:    requestedBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("billing provider %s: %w", provider.Name(), err)
+	}
+
+	settings.Set("pending_plan", target.Name)
+	if err := app.Save(settings); err != nil {
+		return nil, err
+	}
+
+	log.Printf("billing: org %s requested plan change %s -> %s", orgId, current, target.Name)
+	return result, nil
+}
+
+// ApplySubscription applies a subscription event to the org's settings.
+// Updates switch to the event plan and reset features to its limits,
+// cancellations fall back to the default plan, and failed changes only
+// clear pending_plan. Applying the same event twice is harmless.
+func ApplySubscription(app core.App, sub *Subscription) error {
+	settings, err := orgSettings(app, sub.OrgId)
+	if err != nil {
+		return err
+	}
+
+	planName := sub.Plan
+	switch sub.Type {
+	case EventSubscriptionUpdated:
+	case EventSubscriptionCanceled:
+		planName = entitlements.DefaultPlan
+	case EventChangeFailed:
+		settings.Set("pending_plan", "")
+		return app.Save(settings)
+	default:
+		log.Printf("billing: ignoring %s event for org %s", sub.Type, sub.OrgId)
+		return nil
+	}
+
+	plan, ok := entitlements.LookupPlan(planName)
+	if !ok {
+		return fmt.Errorf("%w %q in %s event", ErrUnknownPlan, planName, sub.Type)
+	}
+
+	settings.Set("billing_plan", plan.Name)
+	settings.Set("features", plan.Features())
+	settings.Set("pending_plan", "")
+	settings.Set("subscription_id", sub.SubscriptionId)
+	settings.Set("subscription_status", sub.Status)
+	if err := app.Save(settings); err != nil {
+		return err
+	}
+
+	log.Printf("billing: org %s is now on the %s plan (%s)", sub.OrgId, plan.Name, sub.Type)
+	return nil
+}
+
+// HandleEvent returns a webhooks.Handler applying provider events.
+func HandleEvent(app core.App, provider Provider) webhooks.Handler {
+	return func(event webhooks.Event) error {
+		sub, err := provider.Subscription(event)
+		if err != nil {
+			return err
+		}
+		return ApplySubscription(app, sub)
+	}
+}
+
+// RegisterHooks stops org admins from editing the billing fields of
+// org_settings through the records API; only superusers may.
+func RegisterHooks(app core.App) {
+	app.OnRecordUpdateRequest("org_settings").BindFunc(func(e *core.RecordRequestEvent) error {
+		if e.HasSuperuserAuth() {
+			return e.Next()
+		}
+
+		original := e.Record.Original()
+		for _, field := range managedFields {
+			if fmt.Sprint(e.Record.Get(field)) != fmt.Sprint(original.Get(field)) {
+				return router.NewApiError(http.StatusForbidden,
+					field+" is managed by billing; use POST /api/orgs/{orgId}/plan", nil)
+			}
+		}
+		return e.Next()
+	})
+}
+
+// orgSettings returns the org's settings record, creating it on the
+// default plan when missing (personal orgs don't get one on signup).
+func orgSettings(app core.App, orgId string) (*core.Record, error) {
+	settings, err := app.FindFirstRecordByFilter(
+		"org_settings",
+		"organization = {:orgId}",
+		dbx.Params{"orgId": orgId},
+	)
+	if err == nil {
+		return settings, nil
+	}
+
+	if _, err := app.FindRecordById("organizations", orgId); err != nil {
+		return nil, fmt.Errorf("organization %s not found: %w", orgId, err)
+	}
+
+	col, err := app.FindCollectionByNameOrId("org_settings")
+	if err != nil {
+		return nil, err
+	}
+	plan, _ := entitlements.LookupPlan(entitlements.DefaultPlan)
+	settings = core.NewRecord(col)
+	settings.Set("organization", orgId)
+	settings.Set("billing_plan", plan.Name)
+	settings.Set("features", plan.Features())
+	return settings, nil
+}
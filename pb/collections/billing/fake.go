@@ -0,0 +1,115 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"pocketbase-server/internal/webhooks"
+)
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of the webhook body.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeProvider is a local Provider for development and tests. Plan changes
+// are only recorded; confirm them by posting a signed Subscription to the
+// webhook (see Sign).
+type FakeProvider struct {
+	Secret string
+
+	mu       sync.Mutex
+	requests []ChangeRequest
+}
+
+// NewFakeProvider returns a FakeProvider signing webhooks with secret.
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{Secret: secret}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) RequestChange(ctx context.Context, req ChangeRequest) (*ChangeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	return &ChangeResult{Status: "pending"}, nil
+}
+
+// Requests returns the plan changes requested so far.
+func (p *FakeProvider) Requests() []ChangeRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ChangeRequest(nil), p.requests...)
+}
+
+// Sign returns the signature header value for body.
+func (p *FakeProvider) Sign(body []byte) string {
+	return hex.EncodeToString(p.mac(body))
+}
+
+func (p *FakeProvider) mac(body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func (p *FakeProvider) Verify(r *http.Request) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(r.Header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(sig, p.mac(body)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (p *FakeProvider) Parse(r *http.Request) (*webhooks.Event, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(body, &sub); err != nil {
+		return nil, err
+	}
+	return &webhooks.Event{
+		Provider: p.Name(),
+		Type:     sub.Type,
+		Payload:  body,
+		Headers:  r.Header,
+	}, nil
+}
+
+func (p *FakeProvider) Subscription(event webhooks.Event) (*Subscription, error) {
+	var sub Subscription
+	if err := json.Unmarshal(event.Payload, &sub); err != nil {
+		return nil, err
+	}
+	if sub.OrgId == "" {
+		return nil, fmt.Errorf("%s event without org_id", event.Type)
+	}
+	return &sub, nil
+}
+
+// readBody reads the request body and puts it back, since Verify and
+// Parse both need it.
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,60 @@
+// Package billing implements the plan-change workflow on top of the
+// entitlements plan catalog.
+//
+// Org owners request a plan change; the configured Provider (a payment
+// processor integration) starts it and later confirms it through a
+// webhook. Subscription events are applied to org_settings: billing_plan,
+// features (reset to the plan limits), subscription_id/status and
+// pending_plan.
+package billing
+
+import (
+	"context"
+
+	"pocketbase-server/internal/webhooks"
+)
+
+// Subscription event types understood by ApplySubscription.
+const (
+	EventSubscriptionUpdated  = "subscription.updated"
+	EventSubscriptionCanceled = "subscription.canceled"
+	EventChangeFailed         = "subscription.change_failed"
+)
+
+// Provider is a payment processor integration. Verify and Parse handle
+// its webhooks (see internal/webhooks); Subscription decodes a parsed
+// event into the processor-neutral form.
+type Provider interface {
+	webhooks.Provider
+
+	// RequestChange starts moving an org to another plan.
+	RequestChange(ctx context.Context, req ChangeRequest) (*ChangeResult, error)
+
+	// Subscription decodes a subscription event.
+	Subscription(event webhooks.Event) (*Subscription, error)
+}
+
+// ChangeRequest asks the provider to move an org to Plan.
+type ChangeRequest struct {
+	OrgId          string
+	CurrentPlan    string
+	Plan           string
+	SubscriptionId string
+	RequestedBy    string
+}
+
+// ChangeResult is the provider's answer to a ChangeRequest. CheckoutURL
+// is set when the owner has to complete a payment flow first.
+type ChangeResult struct {
+	Status      string `json:"status"` // "pending" until the webhook confirms
+	CheckoutURL string `json:"checkout_url,omitempty"`
+}
+
+// Subscription is a provider-neutral subscription event.
+type Subscription struct {
+	Type           string `json:"type"`
+	OrgId          string `json:"org_id"`
+	Plan           string `json:"plan"`
+	SubscriptionId string `json:"subscription_id"`
+	Status         string `json:"status"`
+}
@@ -0,0 +1,161 @@
+// Package entitlements enforces per-organization plan limits.
+//
+// Limits come from org_settings: billing_plan selects the Catalog plan
+// defaults and any key in the features JSON overrides them, e.g.
+//
+//	{"max_members": 5, "max_properties": 100}
+//
+// A negative limit means unlimited. Orgs without an org_settings record
+// (e.g. personal orgs created on signup) get the free plan.
+package entitlements
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+)
+
+// Feature keys in org_settings.features.
+const (
+	MaxMembers    = "max_members"
+	MaxProperties = "max_properties"
+)
+
+// Unlimited disables a limit.
+const Unlimited = -1
+
+// DefaultPlan applies when an org has no org_settings or billing_plan.
+const DefaultPlan = "free"
+
+// Limits maps feature keys to their limit.
+type Limits map[string]int
+
+// counted maps each limited feature to the org-scoped collection it counts.
+var counted = map[string]string{
+	MaxMembers:    "org_members",
+	MaxProperties: "properties",
+}
+
+// Entitlements are the resolved plan and limits of an organization.
+type Entitlements struct {
+	OrgId  string `json:"org_id"`
+	Plan   string `json:"plan"`
+	Limits Limits `json:"limits"`
+}
+
+// For resolves the entitlements of orgId from its org_settings.
+func For(app core.App, orgId string) (*Entitlements, error) {
+	plan := DefaultPlan
+	var features map[string]any
+
+	settings, err := app.FindFirstRecordByFilter(
+		"org_settings",
+		"organization = {:orgId}",
+		dbx.Params{"orgId": orgId},
+	)
+	if err == nil {
+		if p := settings.GetString("billing_plan"); p != "" {
+			plan = p
+		}
+		if err := settings.UnmarshalJSONField("features", &features); err != nil {
+			return nil, fmt.Errorf("invalid features for org %s: %w", orgId, err)
+		}
+	}
+
+	defaults, ok := LookupPlan(plan)
+	if !ok {
+		defaults, _ = LookupPlan(DefaultPlan)
+	}
+	limits := Limits{}
+	for feature, limit := range defaults.Limits {
+		limits[feature] = limit
+	}
+	for feature := range counted {
+		if n, ok := features[feature].(float64); ok {
+			limits[feature] = int(n)
+		}
+	}
+
+	return &Entitlements{OrgId: orgId, Plan: plan, Limits: limits}, nil
+}
+
+// Usage is the current count of a limited feature.
+type Usage struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit"`
+}
+
+// Usage counts every limited feature of the org against its limit.
+func (e *Entitlements) Usage(app core.App) (map[string]Usage, error) {
+	usage := make(map[string]Usage, len(counted))
+	for feature, collection := range counted {
+		used, err := count(app, collection, e.OrgId)
+		if err != nil {
+			return nil, err
+		}
+		usage[feature] = Usage{Used: used, Limit: e.Limits[feature]}
+	}
+	return usage, nil
+}
+
+// Check returns a *LimitError when adding one more record of feature
+// would exceed the org's limit.
+func Check(app core.App, orgId, feature string) error {
+	e, err := For(app, orgId)
+	if err != nil {
+		return err
+	}
+
+	limit, ok := e.Limits[feature]
+	if !ok || limit < 0 {
+		return nil
+	}
+
+	used, err := count(app, counted[feature], orgId)
+	if err != nil {
+		return err
+	}
+	if used >= limit {
+		return &LimitError{OrgId: orgId, Plan: e.Plan, Feature: feature, Limit: limit, Used: used}
+	}
+	return nil
+}
+
+func count(app core.App, collection, orgId string) (int, error) {
+	n, err := app.CountRecords(collection, dbx.HashExp{"organization": orgId})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s for org %s: %w", collection, orgId, err)
+	}
+	return int(n), nil
+}
+
+// LimitError reports a plan limit that was reached.
+type LimitError struct {
+	OrgId   string
+	Plan    string
+	Feature string
+	Limit   int
+	Used    int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("organization has reached its %s limit (%d/%d on the %s plan); upgrade the plan to add more",
+		e.Feature, e.Used, e.Limit, e.Plan)
+}
+
+// ApiError converts e to a 402 Payment Required response, which
+// PocketBase's record APIs pass through unchanged when returned from a hook.
+func (e *LimitError) ApiError() *router.ApiError {
+	apiErr := router.NewApiError(http.StatusPaymentRequired, e.Error(), nil)
+	// set directly; NewApiError only keeps validation-error shaped data
+	apiErr.Data = map[string]any{
+		"feature": e.Feature,
+		"limit":   e.Limit,
+		"used":    e.Used,
+		"plan":    e.Plan,
+	}
+	return apiErr
+}
@@ -0,0 +1,55 @@
+package entitlements
+
+import (
+	"errors"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// RegisterHooks enforces the plan limits on:
+//   - org_members create (max_members)
+//   - org_invites update to "accepted" (max_members), so the invite stays
+//     pending instead of being accepted without a membership
+//   - properties create (max_properties)
+//
+// Hooks are model-level so direct app.Save calls are limited too.
+func RegisterHooks(app core.App) {
+	app.OnRecordCreate("org_members").BindFunc(func(e *core.RecordEvent) error {
+		if err := check(e.App, e.Record.GetString("organization"), MaxMembers); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+
+	app.OnRecordUpdate("org_invites").BindFunc(func(e *core.RecordEvent) error {
+		accepting := e.Record.GetString("status") == "accepted" &&
+			e.Record.Original().GetString("status") != "accepted"
+		if accepting {
+			if err := check(e.App, e.Record.GetString("organization"), MaxMembers); err != nil {
+				return err
+			}
+		}
+		return e.Next()
+	})
+
+	app.OnRecordCreate("properties").BindFunc(func(e *core.RecordEvent) error {
+		if err := check(e.App, e.Record.GetString("organization"), MaxProperties); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+}
+
+// check runs Check and converts a reached limit into a 402 API error.
+func check(app core.App, orgId, feature string) error {
+	if orgId == "" {
+		return nil
+	}
+
+	err := Check(app, orgId, feature)
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.ApiError()
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package entitlements
+
+// Plan is a billing plan and the limits it grants.
+type Plan struct {
+	Name   string `json:"name"`
+	Title  string `json:"title"`
+	Rank   int    `json:"rank"` // higher ranks are upgrades
+	Limits Limits `json:"limits"`
+}
+
+// Catalog lists the plans in upgrade order. org_settings.billing_plan
+// accepts exactly these names.
+var Catalog = []Plan{
+	{Name: "free", Title: "Free", Rank: 0, Limits: Limits{MaxMembers: 5, MaxProperties: 100}},
+	{Name: "starter", Title: "Starter", Rank: 1, Limits: Limits{MaxMembers: 15, MaxProperties: 500}},
+	{Name: "pro", Title: "Pro", Rank: 2, Limits: Limits{MaxMembers: 50, MaxProperties: 5000}},
+	{Name: "enterprise", Title: "Enterprise", Rank: 3, Limits: Limits{MaxMembers: Unlimited, MaxProperties: Unlimited}},
+}
+
+// LookupPlan returns the catalog plan with the given name.
+func LookupPlan(name string) (Plan, bool) {
+	for _, p := range Catalog {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Plan{}, false
+}
+
+// PlanNames returns the catalog plan names in upgrade order.
+func PlanNames() []string {
+	names := make([]string, len(Catalog))
+	for i, p := range Catalog {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Features returns the plan limits in the org_settings.features format.
+func (p Plan) Features() map[string]any {
+	features := make(map[string]any, len(p.Limits))
+	for feature, limit := range p.Limits {
+		features[feature] = limit
+	}
+	return features
+}
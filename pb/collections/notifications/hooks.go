@@ -7,8 +7,6 @@ import (
 )
 
 func RegisterHooks(app core.App) {
-	client := notifications.NewClient(app)
-
 	// --- Invite Hooks ---
 	app.OnRecordCreate("org_invites").BindFunc(func(e *core.RecordEvent) error {
 		if err := e.Next(); err != nil {
@@ -18,12 +16,13 @@ func RegisterHooks(app core.App) {
 		email := e.Record.GetString("email")
 		orgId := e.Record.GetString("organization")
 
-		user, err := app.FindAuthRecordByEmail("users", email)
+		user, err := e.App.FindAuthRecordByEmail("users", email)
 		if err != nil {
 			return nil // User doesn't exist, skip notification
 		}
 
-		_, err = client.Send(
+		// e.App so the notification joins the caller's transaction
+		_, err = notifications.NewClient(e.App).Send(
 			notifications.NotificationOpts{
 				Recipient:    user.Id,
 				Organization: orgId,
@@ -46,13 +45,14 @@ func RegisterHooks(app core.App) {
 		orgId := e.Record.GetString("organization")
 
 		// Notify admins about the new member
-		admins, _ := app.FindRecordsByFilter(
+		admins, _ := e.App.FindRecordsByFilter(
 			"org_members",
 			"organization = {:orgId} && (role = 'owner' || role = 'admin') && user != {:userId}",
 			"", 0, 0,
 			map[string]any{"orgId": orgId, "userId": userId},
 		)
 
+		client := notifications.NewClient(e.App)
 		for _, admin := range admins {
 			client.Send(notifications.NotificationOpts{
 				Recipient:    admin.GetString("user"),
@@ -72,7 +72,7 @@ func RegisterHooks(app core.App) {
 			return err
 		}
 
-		// Similar logic: find admins and call client.Send()
+		// Similar logic: find admins and call notifications.NewClient(e.App).Send()
 		return nil
 	})
 }
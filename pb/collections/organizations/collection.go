@@ -4,27 +4,15 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
+	"pocketbase-server/pb/rules"
 )
 
-func EnsureCollectionOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureCollection(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsureCollection(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("organizations")
-	if existing != nil {
-		return patch.Collection(app, "organizations",
-			patch.AutodateFields(),
-		)
-	}
-
-	collection := core.NewBaseCollection("organizations")
-	collection.Fields.Add(
+// organizationsSpec leaves the member-based rules to the managed rules in
+// rules.go, which need org_members to exist.
+var organizationsSpec = patch.Spec{
+	Name: "organizations",
+	Fields: []core.Field{
 		&core.TextField{Name: "name", Required: true},
 		&core.TextField{Name: "slug", Required: true},
 		&core.URLField{Name: "website"},
@@ -33,16 +21,27 @@ func EnsureCollection(app core.App) error {
 		&core.TextField{Name: "city"},
 		&core.TextField{Name: "state", Max: 2},
 		&core.TextField{Name: "zip_code"},
-	)
-
-	authRule := "@request.auth.id != ''"
-	collection.CreateRule = &authRule
-
-	collection.Fields.Add(
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-	collection.AddIndex("idx_organizations_slug", true, "slug", "")
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_organizations_slug", Unique: true, Columns: "slug"},
+	},
+	Rules: patch.Rules{
+		Create: rules.Ptr(rules.AuthOnly),
+	},
+}
+
+func init() {
+	patch.RegisterSpec(organizationsSpec)
+	registry.Register(registry.Collection{
+		Name:      "organizations",
+		DependsOn: organizationsSpec.Dependencies(),
+		Setup:     EnsureCollection,
+		Rules:     func(app core.App) error { return patch.EnsureRules(app, "organizations") },
+	})
+}
 
-	return app.Save(collection)
+func EnsureCollection(app core.App) error {
+	return patch.Ensure(app, organizationsSpec)
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/pocketbase/pocketbase/core"
 
+	"pocketbase-server/pb/collections/entitlements"
 	"pocketbase-server/pb/collections/roles"
 )
 
@@ -42,11 +43,9 @@ func RegisterHooks(app core.App) {
 
 		settings := core.NewRecord(settingsCol)
 		settings.Set("organization", e.Record.Id)
-		settings.Set("billing_plan", "free")
-		settings.Set("features", map[string]any{
-			"max_members":    5,
-			"max_properties": 100,
-		})
+		plan, _ := entitlements.LookupPlan(entitlements.DefaultPlan)
+		settings.Set("billing_plan", plan.Name)
+		settings.Set("features", plan.Features())
 		settings.Set("notification_preferences", map[string]any{
 			"member_joined":    true,
 			"member_removed":   true,
@@ -12,45 +12,18 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/mailer"
 
+	"pocketbase-server/internal/metrics"
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
 	"pocketbase-server/pb/collections/roles"
-	"pocketbase-server/pb/rules"
 )
 
-// EnsureInvitesOnBeforeServe registers the org_invites collection setup on server start.
-func EnsureInvitesOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureInvites(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-// EnsureInvites creates the org_invites collection if it doesn't exist.
-func EnsureInvites(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("org_invites")
-	if existing != nil {
-		return patch.Collection(app, "org_invites",
-			patch.AutodateFields(),
-		)
-	}
-
-	orgsCol, err := app.FindCollectionByNameOrId("organizations")
-	if err != nil {
-		return err
-	}
-
-	usersCol, err := app.FindCollectionByNameOrId("users")
-	if err != nil {
-		return err
-	}
-
-	collection := core.NewBaseCollection("org_invites")
-	collection.Fields.Add(
+var invitesSpec = patch.Spec{
+	Name: "org_invites",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "organization",
-			CollectionId:  orgsCol.Id,
+			CollectionId:  "organizations",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -81,19 +54,31 @@ func EnsureInvites(app core.App) error {
 		},
 		&core.RelationField{
 			Name:         "invited_by",
-			CollectionId: usersCol.Id,
+			CollectionId: "users",
 			MaxSelect:    1,
 		},
-	)
-
-	collection.Fields.Add(
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-	collection.AddIndex("idx_org_invites_token", true, "token", "")
-	collection.AddIndex("idx_org_invites_email_org", false, "email, organization", "")
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_org_invites_token", Unique: true, Columns: "token"},
+		{Name: "idx_org_invites_email_org", Columns: "email, organization"},
+	},
+}
 
-	return app.Save(collection)
+func init() {
+	patch.RegisterSpec(invitesSpec)
+	registry.Register(registry.Collection{
+		Name:      "org_invites",
+		DependsOn: invitesSpec.Dependencies(),
+		Setup:     EnsureInvites,
+		Rules:     func(app core.App) error { return patch.EnsureRules(app, "org_invites") },
+	})
+}
+
+// EnsureInvites creates the org_invites collection if it doesn't exist.
+func EnsureInvites(app core.App) error {
+	return patch.Ensure(app, invitesSpec)
 }
 
 func generateToken() string {
@@ -102,20 +87,27 @@ func generateToken() string {
 	return hex.EncodeToString(b)
 }
 
+// PrepareInvite fills in the token, status and expiry of a new invite, and
+// invited_by when auth is a user. The create request hook calls it; code
+// saving invites with app.Save, which skips request hooks, must call it
+// too.
+func PrepareInvite(invite, auth *core.Record) {
+	invite.Set("token", generateToken())
+	invite.Set("status", "pending")
+	invite.Set("expires_at", time.Now().Add(7*24*time.Hour).UTC().Format(time.RFC3339))
+
+	if auth != nil && !auth.IsSuperuser() {
+		invite.Set("invited_by", auth.Id)
+	}
+}
+
 // RegisterInviteHooks sets up hooks for the invite lifecycle:
 //   - On create: generate token, set defaults, send invite email
 //   - On update: when status becomes "accepted", create the org_member record
 func RegisterInviteHooks(app core.App) {
 	// Before create: fill in token, status, expiry
 	app.OnRecordCreateRequest("org_invites").BindFunc(func(e *core.RecordRequestEvent) error {
-		e.Record.Set("token", generateToken())
-		e.Record.Set("status", "pending")
-		e.Record.Set("expires_at", time.Now().Add(7*24*time.Hour).UTC().Format(time.RFC3339))
-
-		if e.Auth != nil {
-			e.Record.Set("invited_by", e.Auth.Id)
-		}
-
+		PrepareInvite(e.Record, e.Auth)
 		return e.Next()
 	})
 
@@ -164,7 +156,9 @@ func RegisterInviteHooks(app core.App) {
 			),
 		}
 
-		if err := app.NewMailClient().Send(message); err != nil {
+		err := app.NewMailClient().Send(message)
+		metrics.InviteEmail(err)
+		if err != nil {
 			log.Printf("Failed to send invite email to %s: %v", email, err)
 		} else {
 			log.Printf("Sent invite email to %s for org %s", email, orgName)
@@ -224,7 +218,9 @@ func RegisterInviteHooks(app core.App) {
 				),
 			}
 
-			if err := app.NewMailClient().Send(message); err != nil {
+			err := app.NewMailClient().Send(message)
+			metrics.InviteEmail(err)
+			if err != nil {
 				log.Printf("Failed to resend invite email to %s: %v", email, err)
 			} else {
 				log.Printf("Resent invite email to %s for org %s", email, orgName)
@@ -248,7 +244,7 @@ func RegisterInviteHooks(app core.App) {
 		expiresAt := e.Record.GetDateTime("expires_at")
 		if !expiresAt.IsZero() && expiresAt.Time().Before(time.Now()) {
 			e.Record.Set("status", "expired")
-			app.Save(e.Record)
+			e.App.Save(e.Record)
 			log.Printf("Invite %s expired", e.Record.Id)
 			return nil
 		}
@@ -258,14 +254,14 @@ func RegisterInviteHooks(app core.App) {
 		role := e.Record.GetString("role")
 
 		// Find the user by email
-		user, err := app.FindAuthRecordByEmail("users", email)
+		user, err := e.App.FindAuthRecordByEmail("users", email)
 		if err != nil {
 			log.Printf("Invite accepted but no user found for email %s: %v", email, err)
 			return nil
 		}
 
 		// Check if already a member
-		existing, _ := app.FindFirstRecordByFilter(
+		existing, _ := e.App.FindFirstRecordByFilter(
 			"org_members",
 			"user = {:userId} && organization = {:orgId}",
 			dbx.Params{"userId": user.Id, "orgId": orgId},
@@ -276,7 +272,7 @@ func RegisterInviteHooks(app core.App) {
 		}
 
 		// Create org_member
-		membersCol, err := app.FindCollectionByNameOrId("org_members")
+		membersCol, err := e.App.FindCollectionByNameOrId("org_members")
 		if err != nil {
 			log.Printf("org_members collection not found: %v", err)
 			return nil
@@ -287,7 +283,7 @@ func RegisterInviteHooks(app core.App) {
 		member.Set("organization", orgId)
 		member.Set("role", role)
 
-		if err := app.Save(member); err != nil {
+		if err := e.App.Save(member); err != nil {
 			log.Printf("Failed to create org_member on invite accept: %v", err)
 		} else {
 			log.Printf("Added user %s to org %s with role %s via invite", user.Id, orgId, role)
@@ -296,29 +292,3 @@ func RegisterInviteHooks(app core.App) {
 		return nil
 	})
 }
-
-// ApplyInviteRules sets access rules on org_invites.
-// Org owners/admins can create and manage invites.
-func ApplyInviteRules(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		collection, err := app.FindCollectionByNameOrId("org_invites")
-		if err != nil || collection.ListRule != nil {
-			return e.Next()
-		}
-
-		orgAdmin := rules.Ptr(rules.OrgAdmin("organization"))
-		collection.ListRule = orgAdmin
-		collection.ViewRule = orgAdmin
-		collection.CreateRule = orgAdmin
-		collection.UpdateRule = orgAdmin
-		collection.DeleteRule = orgAdmin
-
-		if err := app.Save(collection); err != nil {
-			log.Printf("Failed to apply org_invites rules: %v", err)
-		} else {
-			log.Println("Applied org_invites access rules")
-		}
-
-		return e.Next()
-	})
-}
@@ -4,48 +4,23 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
 	"pocketbase-server/pb/collections/roles"
 )
 
-func EnsureMembersOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureMembers(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsureMembers(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("org_members")
-	if existing != nil {
-		return patch.Collection(app, "org_members",
-			patch.AutodateFields(),
-			patch.Index("idx_org_members_created", false, "created"),
-		)
-	}
-
-	usersCol, err := app.FindCollectionByNameOrId("users")
-	if err != nil {
-		return err
-	}
-	orgsCol, err := app.FindCollectionByNameOrId("organizations")
-	if err != nil {
-		return err
-	}
-
-	collection := core.NewBaseCollection("org_members")
-	collection.Fields.Add(
+var membersSpec = patch.Spec{
+	Name: "org_members",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "user",
-			CollectionId:  usersCol.Id,
+			CollectionId:  "users",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
 		},
 		&core.RelationField{
 			Name:          "organization",
-			CollectionId:  orgsCol.Id,
+			CollectionId:  "organizations",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -56,15 +31,26 @@ func EnsureMembers(app core.App) error {
 			MaxSelect: 1,
 			Values:    roles.AllOrg,
 		},
-	)
-
-	collection.Fields.Add(
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-	collection.AddIndex("idx_org_members_unique", true, "user, organization", "")
-	collection.AddIndex("idx_org_members_org", false, "organization", "")
-	collection.AddIndex("idx_org_members_created", false, "created", "")
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_org_members_unique", Unique: true, Columns: "user, organization"},
+		{Name: "idx_org_members_org", Columns: "organization"},
+		{Name: "idx_org_members_created", Columns: "created"},
+	},
+}
+
+func init() {
+	patch.RegisterSpec(membersSpec)
+	registry.Register(registry.Collection{
+		Name:      "org_members",
+		DependsOn: membersSpec.Dependencies(),
+		Setup:     EnsureMembers,
+		Rules:     func(app core.App) error { return patch.EnsureRules(app, "org_members") },
+	})
+}
 
-	return app.Save(collection)
+func EnsureMembers(app core.App) error {
+	return patch.Ensure(app, membersSpec)
 }
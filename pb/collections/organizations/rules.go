@@ -1,83 +1,82 @@
 package organizations
 
 import (
-	"log"
-
-	"github.com/pocketbase/pocketbase/core"
-
+	"pocketbase-server/pb/collections/patch"
 	"pocketbase-server/pb/rules"
 )
 
-// ApplyRules sets access rules on organizations and org_members.
-// Must run after both collections have been created (Phase 2).
-func ApplyRules(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		applyOrganizationsRules(app)
-		applyOrgMembersRules(app)
-		return e.Next()
-	})
-}
-
-func applyOrganizationsRules(app core.App) {
-	collection, err := app.FindCollectionByNameOrId("organizations")
-	if err != nil || collection.ListRule != nil {
-		return
+// The member-based rules need org_members to exist, so they are applied
+// after every collection is created rather than by the specs. They are
+// managed: bump the version in init when changing them.
+var (
+	organizationsRules = patch.Rules{
+		List:   rules.Ptr(rules.DirectOrgMember),
+		View:   rules.Ptr(rules.DirectOrgMember),
+		Create: organizationsSpec.Rules.Create,
+		Update: rules.Ptr(rules.DirectOrgAdmin),
+		Delete: rules.Ptr(rules.DirectOrgOwner),
 	}
-
-	collection.ListRule = rules.Ptr(rules.DirectOrgMember)
-	collection.ViewRule = rules.Ptr(rules.DirectOrgMember)
-	collection.UpdateRule = rules.Ptr(rules.DirectOrgAdmin)
-	collection.DeleteRule = rules.Ptr(rules.DirectOrgOwner)
-
-	if err := app.Save(collection); err != nil {
-		log.Printf("Failed to apply organizations rules: %v", err)
-	} else {
-		log.Println("Applied organizations access rules")
+	orgMembersRules = patch.Rules{
+		List:   rules.Ptr(rules.OrgMember("organization")),
+		View:   rules.Ptr(rules.OrgMember("organization")),
+		Create: rules.Ptr(rules.OrgAdmin("organization")),
+		Update: rules.Ptr(rules.OrgAdmin("organization")),
+		Delete: rules.Ptr(rules.OrgAdmin("organization")),
 	}
-}
-
-// ApplyOrgSettingsRules sets access rules on org_settings.
-func ApplyOrgSettingsRules(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		applyOrgSettingsRules(app)
-		return e.Next()
-	})
-}
-
-func applyOrgSettingsRules(app core.App) {
-	collection, err := app.FindCollectionByNameOrId("org_settings")
-	if err != nil || collection.ListRule != nil {
-		return
+	orgSettingsRules = patch.Rules{
+		List:   rules.Ptr(rules.OrgMember("organization")),
+		View:   rules.Ptr(rules.OrgMember("organization")),
+		Update: rules.Ptr(rules.OrgAdmin("organization")),
+		// create/delete: system/hooks only
 	}
-
-	collection.ListRule = rules.Ptr(rules.OrgMember("organization"))
-	collection.ViewRule = rules.Ptr(rules.OrgMember("organization"))
-	collection.UpdateRule = rules.Ptr(rules.OrgAdmin("organization"))
-	collection.CreateRule = nil // system/hooks only
-	collection.DeleteRule = nil
-
-	if err := app.Save(collection); err != nil {
-		log.Printf("Failed to apply org_settings rules: %v", err)
-	} else {
-		log.Println("Applied org_settings access rules")
+	// Org owners/admins can create and manage invites.
+	orgInvitesRules = patch.Rules{
+		List:   rules.Ptr(rules.OrgAdmin("organization")),
+		View:   rules.Ptr(rules.OrgAdmin("organization")),
+		Create: rules.Ptr(rules.OrgAdmin("organization")),
+		Update: rules.Ptr(rules.OrgAdmin("organization")),
+		Delete: rules.Ptr(rules.OrgAdmin("organization")),
 	}
-}
+)
 
-func applyOrgMembersRules(app core.App) {
-	collection, err := app.FindCollectionByNameOrId("org_members")
-	if err != nil || collection.ListRule != nil {
-		return
+// Version 1, also what releases before the rules ledger applied.
+var (
+	organizationsRulesV1 = patch.Rules{
+		List:   rules.Ptr(rules.DirectOrgMember),
+		View:   rules.Ptr(rules.DirectOrgMember),
+		Create: organizationsSpec.Rules.Create,
+		Update: rules.Ptr(rules.LegacyDirectOrgAdmin),
+		Delete: rules.Ptr(rules.LegacyDirectOrgOwner),
+	}
+	orgMembersRulesV1 = patch.Rules{
+		List:   rules.Ptr(rules.OrgMember("organization")),
+		View:   rules.Ptr(rules.OrgMember("organization")),
+		Create: rules.Ptr(rules.LegacyOrgAdmin("organization")),
+		Update: rules.Ptr(rules.LegacyOrgAdmin("organization")),
+		Delete: rules.Ptr(rules.LegacyOrgAdmin("organization")),
 	}
+	orgSettingsRulesV1 = patch.Rules{
+		List:   rules.Ptr(rules.OrgMember("organization")),
+		View:   rules.Ptr(rules.OrgMember("organization")),
+		Update: rules.Ptr(rules.LegacyOrgAdmin("organization")),
+	}
+	orgInvitesRulesV1 = patch.Rules{
+		List:   rules.Ptr(rules.LegacyOrgAdmin("organization")),
+		View:   rules.Ptr(rules.LegacyOrgAdmin("organization")),
+		Create: rules.Ptr(rules.LegacyOrgAdmin("organization")),
+		Update: rules.Ptr(rules.LegacyOrgAdmin("organization")),
+		Delete: rules.Ptr(rules.LegacyOrgAdmin("organization")),
+	}
+)
 
-	collection.ListRule = rules.Ptr(rules.OrgMember("organization"))
-	collection.ViewRule = rules.Ptr(rules.OrgMember("organization"))
-	collection.CreateRule = rules.Ptr(rules.OrgAdmin("organization"))
-	collection.UpdateRule = rules.Ptr(rules.OrgAdmin("organization"))
-	collection.DeleteRule = rules.Ptr(rules.OrgAdmin("organization"))
+func init() {
+	patch.DeclareRules("organizations", 2, organizationsRules)
+	patch.DeclareRules("org_members", 2, orgMembersRules)
+	patch.DeclareRules("org_settings", 2, orgSettingsRules)
+	patch.DeclareRules("org_invites", 2, orgInvitesRules)
 
-	if err := app.Save(collection); err != nil {
-		log.Printf("Failed to apply org_members rules: %v", err)
-	} else {
-		log.Println("Applied org_members access rules")
-	}
+	patch.DeclareEarlierRules("organizations", 1, organizationsRulesV1)
+	patch.DeclareEarlierRules("org_members", 1, orgMembersRulesV1)
+	patch.DeclareEarlierRules("org_settings", 1, orgSettingsRulesV1)
+	patch.DeclareEarlierRules("org_invites", 1, orgInvitesRulesV1)
 }
@@ -3,36 +3,17 @@ package organizations
 import (
 	"github.com/pocketbase/pocketbase/core"
 
+	"pocketbase-server/pb/collections/entitlements"
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
 )
 
-func EnsureOrgSettingsOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureOrgSettings(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsureOrgSettings(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("org_settings")
-	if existing != nil {
-		return patch.Collection(app, "org_settings",
-			patch.AutodateFields(),
-		)
-	}
-
-	orgsCol, err := app.FindCollectionByNameOrId("organizations")
-	if err != nil {
-		return err
-	}
-
-	collection := core.NewBaseCollection("org_settings")
-	collection.Fields.Add(
+var orgSettingsSpec = patch.Spec{
+	Name: "org_settings",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "organization",
-			CollectionId:  orgsCol.Id,
+			CollectionId:  "organizations",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -43,8 +24,12 @@ func EnsureOrgSettings(app core.App) error {
 		&core.SelectField{
 			Name:      "billing_plan",
 			MaxSelect: 1,
-			Values:    []string{"free", "starter", "pro", "enterprise"},
+			Values:    entitlements.PlanNames(),
 		},
+		// Set by the billing workflow; see pb/collections/billing
+		&core.TextField{Name: "pending_plan"},
+		&core.TextField{Name: "subscription_id"},
+		&core.TextField{Name: "subscription_status"},
 		&core.JSONField{
 			Name:    "features",
 			MaxSize: 65536,
@@ -53,13 +38,24 @@ func EnsureOrgSettings(app core.App) error {
 			Name:    "notification_preferences",
 			MaxSize: 65536,
 		},
-	)
-
-	collection.Fields.Add(
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-	collection.AddIndex("idx_org_settings_org", true, "organization", "")
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_org_settings_org", Unique: true, Columns: "organization"},
+	},
+}
+
+func init() {
+	patch.RegisterSpec(orgSettingsSpec)
+	registry.Register(registry.Collection{
+		Name:      "org_settings",
+		DependsOn: orgSettingsSpec.Dependencies(),
+		Setup:     EnsureOrgSettings,
+		Rules:     func(app core.App) error { return patch.EnsureRules(app, "org_settings") },
+	})
+}
 
-	return app.Save(collection)
+func EnsureOrgSettings(app core.App) error {
+	return patch.Ensure(app, orgSettingsSpec)
 }
@@ -0,0 +1,142 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+)
+
+// collectionSnapshot is the part of a collection schema changes are
+// reported on.
+type collectionSnapshot struct {
+	fields  map[string]string // name -> field JSON
+	types   map[string]string
+	order   []string
+	indexes map[string]string // name -> SQL
+	rules   map[string]string
+}
+
+func snapshot(app core.App) (map[string]collectionSnapshot, error) {
+	collections, err := app.FindAllCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	snaps := make(map[string]collectionSnapshot, len(collections))
+	for _, c := range collections {
+		s := collectionSnapshot{
+			fields:  map[string]string{},
+			types:   map[string]string{},
+			indexes: map[string]string{},
+			rules:   map[string]string{},
+		}
+		for name, rule := range RulesOf(c).byName() {
+			s.rules[name] = ruleString(rule)
+		}
+		for _, f := range c.Fields {
+			raw, err := json.Marshal(f)
+			if err != nil {
+				return nil, err
+			}
+			s.fields[f.GetName()] = string(raw)
+			s.types[f.GetName()] = f.Type()
+			s.order = append(s.order, f.GetName())
+		}
+		for _, idx := range c.Indexes {
+			s.indexes[dbutils.ParseIndex(idx).IndexName] = idx
+		}
+		snaps[c.Name] = s
+	}
+	return snaps, nil
+}
+
+func ruleString(rule *string) string {
+	if rule == nil {
+		return "<locked>"
+	}
+	return *rule
+}
+
+// diffSnapshots describes the changes from before to after, one line per
+// change, sorted by collection.
+func diffSnapshots(before, after map[string]collectionSnapshot) []string {
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, name := range sorted {
+		b, hadBefore := before[name]
+		a, hasAfter := after[name]
+		switch {
+		case !hadBefore:
+			changes = append(changes, "create collection "+name)
+			continue
+		case !hasAfter:
+			changes = append(changes, "delete collection "+name)
+			continue
+		}
+
+		for _, f := range a.order {
+			switch old, ok := b.fields[f]; {
+			case !ok:
+				changes = append(changes, fmt.Sprintf("%s: add field %s (%s)", name, f, a.types[f]))
+			case old != a.fields[f]:
+				changes = append(changes, fmt.Sprintf("%s: change field %s", name, f))
+			}
+		}
+		for _, f := range b.order {
+			if _, ok := a.fields[f]; !ok {
+				changes = append(changes, fmt.Sprintf("%s: remove field %s", name, f))
+			}
+		}
+
+		changes = append(changes, diffMap(name, "index", b.indexes, a.indexes)...)
+		for _, rule := range ruleNames {
+			if b.rules[rule] != a.rules[rule] {
+				changes = append(changes, fmt.Sprintf("%s: change %s", name, rule))
+			}
+		}
+	}
+	return changes
+}
+
+func diffMap(collection, kind string, before, after map[string]string) []string {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []string
+	for _, k := range keys {
+		old, hadBefore := before[k]
+		cur, hasAfter := after[k]
+		switch {
+		case !hadBefore:
+			changes = append(changes, fmt.Sprintf("%s: add %s %s", collection, kind, k))
+		case !hasAfter:
+			changes = append(changes, fmt.Sprintf("%s: drop %s %s", collection, kind, k))
+		case old != cur:
+			changes = append(changes, fmt.Sprintf("%s: change %s %s", collection, kind, k))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,304 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+
+	"pocketbase-server/pb/collections/registry"
+)
+
+// Drift statuses.
+const (
+	// DriftMissing is declared in code but absent from the database.
+	DriftMissing = "missing"
+	// DriftExtra is in the database but not declared in code.
+	DriftExtra = "extra"
+	// DriftChanged differs between the two.
+	DriftChanged = "changed"
+)
+
+// Drift is one difference between a live collection and the code.
+type Drift struct {
+	Collection string `json:"collection"`
+	// Kind is "collection", "field", "index" or "rule".
+	Kind     string `json:"kind"`
+	Name     string `json:"name,omitempty"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func (d Drift) String() string {
+	if d.Kind == "collection" {
+		return fmt.Sprintf("%s collection %s", d.Status, d.Collection)
+	}
+	return fmt.Sprintf("%s: %s %s %s", d.Collection, d.Status, d.Kind, d.Name)
+}
+
+// fixable reports whether FixDrift repairs d. Extra fields, indexes and
+// collections hold data or admin additions and are left alone, and field
+// types can't be changed in place.
+func (d Drift) fixable() bool {
+	return d.Status != DriftExtra && !(d.Kind == "field" && d.Status == DriftChanged && d.typeChanged())
+}
+
+func (d Drift) typeChanged() bool {
+	var expected, actual struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal([]byte(d.Expected), &expected)
+	json.Unmarshal([]byte(d.Actual), &actual)
+	return expected.Type != actual.Type
+}
+
+// DetectDrift compares the live collections with specs: fields, indexes
+// and all five rules, plus collections no spec declares. System fields,
+// indexes on them and system collections are ignored.
+func DetectDrift(app core.App, specs []Spec) ([]Drift, error) {
+	collections, err := app.FindAllCollections()
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]*core.Collection, len(collections))
+	for _, c := range collections {
+		live[c.Name] = c
+	}
+
+	var drift []Drift
+	declared := make(map[string]bool, len(specs))
+	for _, s := range specs {
+		declared[s.Name] = true
+		col := live[s.Name]
+		if col == nil {
+			drift = append(drift, Drift{Collection: s.Name, Kind: "collection", Status: DriftMissing})
+			continue
+		}
+		d, err := collectionDrift(app, s, col)
+		if err != nil {
+			return nil, err
+		}
+		drift = append(drift, d...)
+	}
+
+	for _, c := range collections {
+		if !declared[c.Name] && !c.System {
+			drift = append(drift, Drift{Collection: c.Name, Kind: "collection", Status: DriftExtra})
+		}
+	}
+	return drift, nil
+}
+
+func collectionDrift(app core.App, s Spec, col *core.Collection) ([]Drift, error) {
+	fields, err := s.resolveFields(app, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []Drift
+	for _, f := range fields {
+		expected, err := fieldJSON(f)
+		if err != nil {
+			return nil, err
+		}
+		existing := col.Fields.GetByName(f.GetName())
+		if existing == nil {
+			drift = append(drift, Drift{Collection: s.Name, Kind: "field", Name: f.GetName(), Status: DriftMissing, Expected: expected})
+			continue
+		}
+		actual, err := fieldJSON(existing)
+		if err != nil {
+			return nil, err
+		}
+		if actual != expected {
+			drift = append(drift, Drift{Collection: s.Name, Kind: "field", Name: f.GetName(), Status: DriftChanged, Expected: expected, Actual: actual})
+		}
+	}
+	for _, f := range col.Fields {
+		if !f.GetSystem() && !slices.ContainsFunc(s.Fields, func(sf core.Field) bool { return sf.GetName() == f.GetName() }) {
+			actual, err := fieldJSON(f)
+			if err != nil {
+				return nil, err
+			}
+			drift = append(drift, Drift{Collection: s.Name, Kind: "field", Name: f.GetName(), Status: DriftExtra, Actual: actual})
+		}
+	}
+
+	expectedIndexes := s.indexes()
+	for _, idx := range s.Indexes {
+		expected := expectedIndexes.GetIndex(idx.Name)
+		switch actual := col.GetIndex(idx.Name); {
+		case actual == "":
+			drift = append(drift, Drift{Collection: s.Name, Kind: "index", Name: idx.Name, Status: DriftMissing, Expected: expected})
+		case actual != expected:
+			drift = append(drift, Drift{Collection: s.Name, Kind: "index", Name: idx.Name, Status: DriftChanged, Expected: expected, Actual: actual})
+		}
+	}
+	for _, idx := range col.Indexes {
+		parsed := dbutils.ParseIndex(idx)
+		if expectedIndexes.GetIndex(parsed.IndexName) == "" && !onSystemFields(col, parsed) {
+			drift = append(drift, Drift{Collection: s.Name, Kind: "index", Name: parsed.IndexName, Status: DriftExtra, Actual: idx})
+		}
+	}
+
+	expectedRules := ExpectedRules(s).byName()
+	actualRules := RulesOf(col).byName()
+	for _, name := range ruleNames {
+		expected, actual := ruleString(expectedRules[name]), ruleString(actualRules[name])
+		if expected != actual {
+			drift = append(drift, Drift{Collection: s.Name, Kind: "rule", Name: name, Status: DriftChanged, Expected: expected, Actual: actual})
+		}
+	}
+	return drift, nil
+}
+
+// fieldJSON returns the options of f without its id, for comparison.
+func fieldJSON(f core.Field) (string, error) {
+	raw, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	var options map[string]any
+	if err := json.Unmarshal(raw, &options); err != nil {
+		return "", err
+	}
+	delete(options, "id")
+	raw, err = json.Marshal(options)
+	return string(raw), err
+}
+
+// indexes returns a scratch collection holding the spec indexes, so their
+// SQL matches what AddIndex wrote for the live collection.
+func (s Spec) indexes() *core.Collection {
+	scratch := core.NewBaseCollection(s.Name)
+	for _, idx := range s.Indexes {
+		scratch.AddIndex(idx.Name, idx.Unique, idx.Columns, "")
+	}
+	return scratch
+}
+
+// onSystemFields reports whether every column of idx is a system field,
+// like the email and token key indexes of auth collections.
+func onSystemFields(col *core.Collection, idx dbutils.Index) bool {
+	if len(idx.Columns) == 0 {
+		return false
+	}
+	for _, c := range idx.Columns {
+		f := col.Fields.GetByName(c.Name)
+		if f == nil || !f.GetSystem() {
+			return false
+		}
+	}
+	return true
+}
+
+// FixDrift makes the live schema match specs in one transaction: it
+// creates missing collections and adds missing fields and indexes, resets
+// changed field options, indexes and rules to the code. Extra fields,
+// indexes and collections are kept, and so are fields whose type changed.
+// It returns the drift it fixed.
+func FixDrift(app core.App, specs []Spec) ([]Drift, error) {
+	sorted, err := SortSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixed []Drift
+	err = app.RunInTransaction(func(txApp core.App) error {
+		fixed = nil
+		// A second pass fixes the rules of created collections, which can
+		// refer to collections created after them.
+		for pass := 0; pass < 2; pass++ {
+			created := false
+			for _, s := range sorted {
+				d, err := fixCollection(txApp, s)
+				if err != nil {
+					return err
+				}
+				fixed = append(fixed, d...)
+				created = created || slices.ContainsFunc(d, func(d Drift) bool { return d.Kind == "collection" })
+			}
+			if !created {
+				break
+			}
+		}
+		return nil
+	})
+	return fixed, err
+}
+
+func fixCollection(app core.App, s Spec) ([]Drift, error) {
+	col, err := app.FindCollectionByNameOrId(s.Name)
+	if err != nil {
+		if _, err := Reconcile(app, s); err != nil {
+			return nil, err
+		}
+		return []Drift{{Collection: s.Name, Kind: "collection", Status: DriftMissing}}, nil
+	}
+
+	drift, err := collectionDrift(app, s, col)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := s.resolveFields(app, true)
+	if err != nil {
+		return nil, err
+	}
+	indexes := s.indexes()
+
+	var fixed []Drift
+	for _, d := range drift {
+		if !d.fixable() {
+			continue
+		}
+		switch d.Kind {
+		case "field":
+			// An id-less field replaces the one with the same name.
+			col.Fields.Add(fields.GetByName(d.Name))
+		case "index":
+			col.RemoveIndex(d.Name)
+			col.Indexes = append(col.Indexes, indexes.GetIndex(d.Name))
+		case "rule":
+			ExpectedRules(s).Apply(col)
+		}
+		fixed = append(fixed, d)
+	}
+	if len(fixed) == 0 {
+		return nil, nil
+	}
+	if err := app.Save(col); err != nil {
+		return nil, fmt.Errorf("failed to fix %s: %w", s.Name, err)
+	}
+	// Record reset managed rules, or the next EnsureRules would take them
+	// for an admin customization.
+	if m, ok := declaredRules[s.Name]; ok && slices.ContainsFunc(fixed, func(d Drift) bool { return d.Kind == "rule" }) {
+		if err := recordRules(app, s.Name, m); err != nil {
+			return nil, err
+		}
+	}
+	return fixed, nil
+}
+
+// SortSpecs orders specs so every spec comes after its dependencies, and
+// fails on a cycle. Dependencies outside specs are ignored.
+func SortSpecs(specs []Spec) ([]Spec, error) {
+	byName := make(map[string]Spec, len(specs))
+	names := make([]string, len(specs))
+	for i, s := range specs {
+		byName[s.Name] = s
+		names[i] = s.Name
+	}
+
+	ordered, err := registry.Order(names, func(name string) []string { return byName[name].Dependencies() })
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]Spec, len(ordered))
+	for i, name := range ordered {
+		sorted[i] = byName[name]
+	}
+	return sorted, nil
+}
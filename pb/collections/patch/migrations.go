@@ -0,0 +1,258 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// LedgerTable records the applied migrations.
+const LedgerTable = "_patch_migrations"
+
+// Migration is a one-off schema change. Ids must be unique and sort in
+// apply order, e.g. "20260301_properties_listing_fields".
+type Migration struct {
+	Id          string
+	Description string
+	Up          func(app core.App) error
+	// Down reverts Up; optional.
+	Down func(app core.App) error
+}
+
+// MigrationStatus is a migration as seen by the ledger.
+type MigrationStatus struct {
+	Id          string    `json:"id"`
+	Description string    `json:"description"`
+	Applied     bool      `json:"applied"`
+	AppliedAt   time.Time `json:"applied_at,omitzero"`
+	Reversible  bool      `json:"reversible"`
+	// Unknown is set for ledger entries no registered migration matches.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Result reports one migration run by Up or Down.
+type Result struct {
+	Id string
+	// Changes describes the schema changes it made.
+	Changes []string
+}
+
+var errDryRun = errors.New("dry run")
+
+// Ledger holds an ordered set of migrations.
+type Ledger struct {
+	migrations []Migration
+}
+
+// NewLedger returns an empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{}
+}
+
+// Add registers m. Ids must be unique and Up must be set.
+func (l *Ledger) Add(m Migration) error {
+	if m.Id == "" || m.Up == nil {
+		return fmt.Errorf("migration %q needs an id and an up step", m.Id)
+	}
+	for _, existing := range l.migrations {
+		if existing.Id == m.Id {
+			return fmt.Errorf("duplicate migration id %q", m.Id)
+		}
+	}
+	l.migrations = append(l.migrations, m)
+	sort.Slice(l.migrations, func(i, j int) bool { return l.migrations[i].Id < l.migrations[j].Id })
+	return nil
+}
+
+// Migrations returns the registered migrations in apply order.
+func (l *Ledger) Migrations() []Migration {
+	return append([]Migration(nil), l.migrations...)
+}
+
+type ledgerRow struct {
+	Id      string `db:"id"`
+	Applied string `db:"applied"`
+}
+
+// ensureTable creates the ledger table if missing.
+func ensureTable(app core.App) error {
+	_, err := app.DB().NewQuery(`
+		CREATE TABLE IF NOT EXISTS {{` + LedgerTable + `}} (
+			[[id]]          TEXT PRIMARY KEY NOT NULL,
+			[[description]] TEXT NOT NULL DEFAULT '',
+			[[applied]]     TEXT NOT NULL
+		)
+	`).Execute()
+	return err
+}
+
+func applied(app core.App) ([]ledgerRow, error) {
+	if err := ensureTable(app); err != nil {
+		return nil, err
+	}
+	var rows []ledgerRow
+	err := app.DB().Select("id", "applied").From(LedgerTable).OrderBy("id").All(&rows)
+	return rows, err
+}
+
+// Status lists the registered migrations and whether they are applied,
+// followed by applied ids no registered migration matches.
+func (l *Ledger) Status(app core.App) ([]MigrationStatus, error) {
+	rows, err := applied(app)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[string]time.Time, len(rows))
+	for _, r := range rows {
+		t, _ := time.Parse(time.RFC3339, r.Applied)
+		appliedAt[r.Id] = t
+	}
+
+	var statuses []MigrationStatus
+	known := make(map[string]bool, len(l.migrations))
+	for _, m := range l.migrations {
+		at, ok := appliedAt[m.Id]
+		known[m.Id] = true
+		statuses = append(statuses, MigrationStatus{
+			Id:          m.Id,
+			Description: m.Description,
+			Applied:     ok,
+			AppliedAt:   at,
+			Reversible:  m.Down != nil,
+		})
+	}
+	for _, r := range rows {
+		if !known[r.Id] {
+			statuses = append(statuses, MigrationStatus{Id: r.Id, Applied: true, AppliedAt: appliedAt[r.Id], Unknown: true})
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies the pending migrations in order, each in its own
+// transaction, and stops at the first failure. With dryRun they all run
+// in one transaction that is rolled back, and the results describe what
+// would change.
+func (l *Ledger) Up(app core.App, dryRun bool) ([]Result, error) {
+	statuses, err := l.Status(app)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for i, s := range statuses {
+		if !s.Applied && !s.Unknown {
+			pending = append(pending, l.migrations[i])
+		}
+	}
+
+	return run(app, pending, dryRun, func(txApp core.App, m Migration) error {
+		if err := m.Up(txApp); err != nil {
+			return err
+		}
+		_, err := txApp.DB().Insert(LedgerTable, dbx.Params{
+			"id":          m.Id,
+			"description": m.Description,
+			"applied":     time.Now().UTC().Format(time.RFC3339),
+		}).Execute()
+		return err
+	})
+}
+
+// Down reverts the last steps applied migrations, newest first. Every one
+// of them must have a down step.
+func (l *Ledger) Down(app core.App, steps int, dryRun bool) ([]Result, error) {
+	rows, err := applied(app)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]Migration, len(l.migrations))
+	for _, m := range l.migrations {
+		byId[m.Id] = m
+	}
+
+	var revert []Migration
+	for i := len(rows) - 1; i >= 0 && len(revert) < steps; i-- {
+		m, ok := byId[rows[i].Id]
+		if !ok {
+			return nil, fmt.Errorf("migration %s is applied but not registered", rows[i].Id)
+		}
+		if m.Down == nil {
+			return nil, fmt.Errorf("migration %s has no down step", m.Id)
+		}
+		revert = append(revert, m)
+	}
+
+	return run(app, revert, dryRun, func(txApp core.App, m Migration) error {
+		if err := m.Down(txApp); err != nil {
+			return err
+		}
+		_, err := txApp.DB().Delete(LedgerTable, dbx.HashExp{"id": m.Id}).Execute()
+		return err
+	})
+}
+
+// run applies step to each migration and records the schema changes.
+func run(app core.App, migrations []Migration, dryRun bool, step func(core.App, Migration) error) ([]Result, error) {
+	var results []Result
+	apply := func(txApp core.App, m Migration) error {
+		before, err := snapshot(txApp)
+		if err != nil {
+			return err
+		}
+		if err := step(txApp, m); err != nil {
+			return fmt.Errorf("migration %s: %w", m.Id, err)
+		}
+		after, err := snapshot(txApp)
+		if err != nil {
+			return err
+		}
+		results = append(results, Result{Id: m.Id, Changes: diffSnapshots(before, after)})
+		return nil
+	}
+
+	if dryRun {
+		err := app.RunInTransaction(func(txApp core.App) error {
+			for _, m := range migrations {
+				if err := apply(txApp, m); err != nil {
+					return err
+				}
+			}
+			return errDryRun
+		})
+		if !errors.Is(err, errDryRun) {
+			return results, err
+		}
+		return results, nil
+	}
+
+	for _, m := range migrations {
+		err := app.RunInTransaction(func(txApp core.App) error {
+			return apply(txApp, m)
+		})
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// defaultLedger holds the migrations registered by the collection packages.
+var defaultLedger = NewLedger()
+
+// Register adds m to the default ledger. Call it from init; it panics on a
+// duplicate id or a missing up step.
+func Register(m Migration) {
+	if err := defaultLedger.Add(m); err != nil {
+		panic(err)
+	}
+}
+
+// Migrations returns the default ledger.
+func Migrations() *Ledger {
+	return defaultLedger
+}
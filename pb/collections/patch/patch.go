@@ -90,18 +90,14 @@ func TextField(name string, mutate func(f *core.TextField) bool) Func {
 	}
 }
 
-// ClearRules sets all access rules to nil so they will be re-applied by tenancy on next startup.
-func ClearRules() Func {
+// ReplaceRules sets the rules to r while they are exactly from, such as the
+// defaults of a built-in collection. Rules changed since are left alone.
+func ReplaceRules(from, r Rules) Func {
 	return func(col *core.Collection) bool {
-		if col.ListRule == nil && col.ViewRule == nil &&
-			col.CreateRule == nil && col.UpdateRule == nil && col.DeleteRule == nil {
+		if rulesJSON(RulesOf(col)) != rulesJSON(from) {
 			return false
 		}
-		col.ListRule = nil
-		col.ViewRule = nil
-		col.CreateRule = nil
-		col.UpdateRule = nil
-		col.DeleteRule = nil
+		r.Apply(col)
 		return true
 	}
 }
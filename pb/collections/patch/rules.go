@@ -0,0 +1,236 @@
+package patch
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/pb/rules"
+)
+
+// RulesTable records the managed rules applied to each collection.
+const RulesTable = "_patch_rules"
+
+// RulesResult is what ReconcileRules did with a collection.
+type RulesResult string
+
+const (
+	// RulesApplied: the declared rules were set, on a collection without
+	// rules or over an older version.
+	RulesApplied RulesResult = "applied"
+	// RulesAdopted: the collection already had the declared rules and
+	// they are now recorded.
+	RulesAdopted RulesResult = "adopted"
+	// RulesCurrent: the recorded version is current.
+	RulesCurrent RulesResult = "current"
+	// RulesCustomized: the live rules differ from the last ones applied,
+	// so an admin changed them; they are left alone.
+	RulesCustomized RulesResult = "customized"
+	// RulesStale: the declared rules changed without a version bump, so
+	// they are not applied.
+	RulesStale RulesResult = "stale"
+)
+
+// managedRules are declared rules and their version.
+type managedRules struct {
+	version int
+	rules   Rules
+}
+
+// declaredRules holds the rules applied after bootstrap creates every
+// collection (organizations, tenancy), keyed by collection.
+var declaredRules = map[string]managedRules{}
+
+// DeclareRules makes r the managed rules of a collection, applied by
+// EnsureRules after the collections exist. Bump version whenever r
+// changes so existing databases get the new rules. Call it from init.
+func DeclareRules(collection string, version int, r Rules) {
+	declaredRules[collection] = managedRules{version: version, rules: r}
+}
+
+// earlierRules holds rules earlier releases applied, keyed by collection.
+var earlierRules = map[string][]managedRules{}
+
+// DeclareEarlierRules records r as the version of a collection's managed
+// rules an earlier release applied. Releases before the ledger recorded
+// nothing, so a collection without a record whose rules match an earlier
+// version is upgraded instead of counting as customized. Call it from
+// init, with the rules as that release rendered them.
+func DeclareEarlierRules(collection string, version int, r Rules) {
+	earlierRules[collection] = append(earlierRules[collection], managedRules{version: version, rules: r})
+}
+
+// isEarlier reports whether live are the rules of an earlier version of
+// collection.
+func isEarlier(collection, live string) bool {
+	for _, m := range earlierRules[collection] {
+		if rulesJSON(m.rules) == live {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectedRules returns the rules the code declares for s: the managed
+// ones, or else the spec's own.
+func ExpectedRules(s Spec) Rules {
+	if m, ok := declaredRules[s.Name]; ok {
+		return m.rules
+	}
+	return s.Rules
+}
+
+type rulesRow struct {
+	Version int    `db:"version"`
+	Rules   string `db:"rules"`
+}
+
+func ensureRulesTable(app core.App) error {
+	_, err := app.DB().NewQuery(`
+		CREATE TABLE IF NOT EXISTS {{` + RulesTable + `}} (
+			[[collection]] TEXT PRIMARY KEY NOT NULL,
+			[[version]]    INTEGER NOT NULL,
+			[[rules]]      TEXT NOT NULL,
+			[[applied]]    TEXT NOT NULL
+		)
+	`).Execute()
+	return err
+}
+
+// rulesJSON encodes r for the ledger and for comparisons.
+func rulesJSON(r Rules) string {
+	raw, _ := json.Marshal(r.byName())
+	return string(raw)
+}
+
+// ReconcileRules brings the managed rules of collection up to date:
+//   - no record: a collection without rules, with those of its spec, or
+//     with those of an earlier version (see DeclareEarlierRules) gets
+//     them; one that already has them is adopted; any other rules count
+//     as customized.
+//   - recorded: if the live rules still match the recorded ones, a newer
+//     declared version replaces them. Otherwise an admin customized them.
+func ReconcileRules(app core.App, collection string) (RulesResult, error) {
+	m, ok := declaredRules[collection]
+	if !ok {
+		return "", fmt.Errorf("no managed rules declared for %q", collection)
+	}
+
+	var result RulesResult
+	err := app.RunInTransaction(func(txApp core.App) error {
+		if err := ensureRulesTable(txApp); err != nil {
+			return err
+		}
+		col, err := txApp.FindCollectionByNameOrId(collection)
+		if err != nil {
+			return fmt.Errorf("collection %q not found", collection)
+		}
+
+		var row rulesRow
+		err = txApp.DB().Select("version", "rules").From(RulesTable).
+			Where(dbx.HashExp{"collection": collection}).One(&row)
+		found := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		live := rulesJSON(RulesOf(col))
+		declared := rulesJSON(m.rules)
+		spec, _ := LookupSpec(collection)
+		switch {
+		case !found && (RulesOf(col).empty() || live == rulesJSON(spec.Rules)):
+			// Untouched since the spec created it.
+			result = RulesApplied
+		case !found && live == declared:
+			result = RulesAdopted
+		case !found && isEarlier(collection, live):
+			// Applied by a release from before the ledger.
+			result = RulesApplied
+		case !found, live != row.Rules:
+			result = RulesCustomized
+		case row.Version < m.version:
+			result = RulesApplied
+		case row.Version == m.version && row.Rules != declared:
+			result = RulesStale
+		default:
+			result = RulesCurrent
+		}
+
+		switch result {
+		case RulesApplied:
+			m.rules.Apply(col)
+			if err := txApp.Save(col); err != nil {
+				return fmt.Errorf("failed to apply %s rules: %w", collection, err)
+			}
+		case RulesAdopted:
+		default:
+			return nil
+		}
+		return recordRules(txApp, collection, m)
+	})
+	return result, err
+}
+
+// recordRules notes that collection has the rules of m.
+func recordRules(app core.App, collection string, m managedRules) error {
+	if err := ensureRulesTable(app); err != nil {
+		return err
+	}
+	_, err := app.DB().NewQuery(`
+		INSERT OR REPLACE INTO {{` + RulesTable + `}} ([[collection]], [[version]], [[rules]], [[applied]])
+		VALUES ({:collection}, {:version}, {:rules}, {:applied})
+	`).Bind(dbx.Params{
+		"collection": collection,
+		"version":    m.version,
+		"rules":      rulesJSON(m.rules),
+		"applied":    time.Now().UTC().Format(time.RFC3339),
+	}).Execute()
+	return err
+}
+
+// EnsureRules reconciles the managed rules of collection and logs the
+// outcome, with a warning when they are left alone. A missing collection
+// is skipped.
+func EnsureRules(app core.App, collection string) error {
+	if _, err := app.FindCollectionByNameOrId(collection); err != nil {
+		log.Printf("rules: collection %q not found, skipping", collection)
+		return nil
+	}
+	result, err := ReconcileRules(app, collection)
+	if err != nil {
+		return err
+	}
+	version := declaredRules[collection].version
+	switch result {
+	case RulesApplied:
+		log.Printf("rules: applied version %d to %s", version, collection)
+	case RulesCustomized:
+		log.Printf("rules: WARNING %s rules were changed outside the code; keeping them instead of version %d (see `drift`)", collection, version)
+	case RulesStale:
+		log.Printf("rules: WARNING the %s rules changed in code without a version bump; keeping version %d", collection, version)
+	}
+	return nil
+}
+
+// ValidateRules checks that the fields referenced by the rules the code
+// declares for specs exist in the live schema.
+func ValidateRules(app core.App, specs []Spec) error {
+	var errs []error
+	for _, s := range specs {
+		byName := ExpectedRules(s).byName()
+		for _, name := range ruleNames {
+			if r := byName[name]; r != nil {
+				if err := rules.Validate(app, s.Name, *r); err != nil {
+					errs = append(errs, fmt.Errorf("%s %s: %w", s.Name, name, err))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,244 @@
+package patch
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Spec declares a collection once: Reconcile creates it from the spec, or
+// brings an existing one up to date with it.
+type Spec struct {
+	Name string
+	// Auth marks an auth collection (users is PocketBase's built-in one).
+	Auth bool
+	// Requires lists collections that must exist first, besides the
+	// targets of relation fields.
+	Requires []string
+	// Fields in order. The CollectionId of a RelationField holds the
+	// target collection name; Reconcile resolves it.
+	Fields  []core.Field
+	Indexes []IndexSpec
+	// Rules are set on create, and on an existing collection that has no
+	// rules at all. Rules declared with DeclareRules stay nil here.
+	Rules Rules
+	// Patches run on an existing collection after reconciling, for changes
+	// a spec can't express.
+	Patches []Func
+}
+
+// IndexSpec declares a collection index.
+type IndexSpec struct {
+	Name    string
+	Unique  bool
+	Columns string
+}
+
+// Rules are the five access rules of a collection; nil is superuser only.
+type Rules struct {
+	List, View, Create, Update, Delete *string
+}
+
+func (r Rules) empty() bool {
+	return r.List == nil && r.View == nil && r.Create == nil && r.Update == nil && r.Delete == nil
+}
+
+// Apply sets all five rules of col.
+func (r Rules) Apply(col *core.Collection) {
+	col.ListRule, col.ViewRule, col.CreateRule = r.List, r.View, r.Create
+	col.UpdateRule, col.DeleteRule = r.Update, r.Delete
+}
+
+// RulesOf returns the rules of col.
+func RulesOf(col *core.Collection) Rules {
+	return Rules{List: col.ListRule, View: col.ViewRule, Create: col.CreateRule, Update: col.UpdateRule, Delete: col.DeleteRule}
+}
+
+// byName returns the rules keyed by their collection field name.
+func (r Rules) byName() map[string]*string {
+	return map[string]*string{
+		"listRule":   r.List,
+		"viewRule":   r.View,
+		"createRule": r.Create,
+		"updateRule": r.Update,
+		"deleteRule": r.Delete,
+	}
+}
+
+// ruleNames lists the rules in display order.
+var ruleNames = []string{"listRule", "viewRule", "createRule", "updateRule", "deleteRule"}
+
+// Dependencies returns the collections s needs: Requires plus the targets
+// of its relation fields, without duplicates or s itself.
+func (s Spec) Dependencies() []string {
+	deps := append([]string(nil), s.Requires...)
+	for _, f := range s.Fields {
+		if rel, ok := f.(*core.RelationField); ok {
+			deps = append(deps, rel.CollectionId)
+		}
+	}
+	slices.Sort(deps)
+	deps = slices.Compact(deps)
+	return slices.DeleteFunc(deps, func(d string) bool { return d == s.Name })
+}
+
+// resolveFields returns a copy of the spec fields with relation targets
+// resolved to collection ids. A missing target is an error when strict,
+// and otherwise stays a name. The spec itself is never modified.
+func (s Spec) resolveFields(app core.App, strict bool) (core.FieldsList, error) {
+	fields, err := core.FieldsList(s.Fields).Clone()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		rel, ok := f.(*core.RelationField)
+		if !ok {
+			continue
+		}
+		target, err := app.FindCollectionByNameOrId(rel.CollectionId)
+		if err != nil {
+			if !strict {
+				continue
+			}
+			return nil, fmt.Errorf("%s.%s: relation target %q does not exist", s.Name, rel.Name, rel.CollectionId)
+		}
+		rel.CollectionId = target.Id
+	}
+	return fields, nil
+}
+
+// Reconcile creates the collection of s if it is missing. Otherwise it adds
+// the missing fields, select values and indexes, and runs s.Patches. It
+// never removes anything or changes a field's other options; that takes a
+// migration. It returns the changes made, one line each.
+func Reconcile(app core.App, s Spec) ([]string, error) {
+	for _, dep := range s.Requires {
+		if _, err := app.FindCollectionByNameOrId(dep); err != nil {
+			return nil, fmt.Errorf("%s requires collection %q", s.Name, dep)
+		}
+	}
+	fields, err := s.resolveFields(app, true)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := app.FindCollectionByNameOrId(s.Name)
+	if err != nil {
+		return create(app, s, fields)
+	}
+
+	var changes []string
+	for _, f := range fields {
+		existing := col.Fields.GetByName(f.GetName())
+		if existing == nil {
+			col.Fields.Add(f)
+			changes = append(changes, fmt.Sprintf("%s: add field %s (%s)", s.Name, f.GetName(), f.Type()))
+			continue
+		}
+		if existing.Type() != f.Type() {
+			return nil, fmt.Errorf("%s.%s is a %s field but the spec declares %s; change it with a migration",
+				s.Name, f.GetName(), existing.Type(), f.Type())
+		}
+		if added := addSelectValues(existing, f); len(added) > 0 {
+			changes = append(changes, fmt.Sprintf("%s: add values %s to field %s", s.Name, strings.Join(added, ", "), f.GetName()))
+		}
+	}
+	for _, idx := range s.Indexes {
+		if col.GetIndex(idx.Name) == "" {
+			col.AddIndex(idx.Name, idx.Unique, idx.Columns, "")
+			changes = append(changes, fmt.Sprintf("%s: add index %s", s.Name, idx.Name))
+		}
+	}
+	if !s.Rules.empty() && RulesOf(col).empty() {
+		s.Rules.Apply(col)
+		changes = append(changes, fmt.Sprintf("%s: set rules", s.Name))
+	}
+	for _, p := range s.Patches {
+		if p(col) {
+			changes = append(changes, fmt.Sprintf("%s: apply patch", s.Name))
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := app.Save(col); err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", s.Name, err)
+	}
+	return changes, nil
+}
+
+func create(app core.App, s Spec, fields core.FieldsList) ([]string, error) {
+	var col *core.Collection
+	if s.Auth {
+		col = core.NewAuthCollection(s.Name)
+	} else {
+		col = core.NewBaseCollection(s.Name)
+	}
+	col.Fields.Add(fields...)
+	for _, idx := range s.Indexes {
+		col.AddIndex(idx.Name, idx.Unique, idx.Columns, "")
+	}
+	s.Rules.Apply(col)
+
+	if err := app.Save(col); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", s.Name, err)
+	}
+	return []string{"create collection " + s.Name}, nil
+}
+
+// addSelectValues adds the values of want missing from existing when both
+// are select fields. Values only in existing are kept.
+func addSelectValues(existing, want core.Field) []string {
+	cur, ok := existing.(*core.SelectField)
+	if !ok {
+		return nil
+	}
+	var added []string
+	for _, v := range want.(*core.SelectField).Values {
+		if !slices.Contains(cur.Values, v) {
+			cur.Values = append(cur.Values, v)
+			added = append(added, v)
+		}
+	}
+	return added
+}
+
+// Ensure reconciles s and logs the changes.
+func Ensure(app core.App, s Spec) error {
+	changes, err := Reconcile(app, s)
+	for _, c := range changes {
+		log.Printf("patch: %s", c)
+	}
+	return err
+}
+
+// specs holds the registered specs in registration order.
+var specs []Spec
+
+// RegisterSpec adds s to the specs returned by Specs. Call it from init; it
+// panics on a duplicate name.
+func RegisterSpec(s Spec) {
+	if _, ok := LookupSpec(s.Name); ok {
+		panic(fmt.Sprintf("duplicate collection spec %q", s.Name))
+	}
+	specs = append(specs, s)
+}
+
+// Specs returns the registered specs.
+func Specs() []Spec {
+	return append([]Spec(nil), specs...)
+}
+
+// LookupSpec returns the registered spec with the given name.
+func LookupSpec(name string) (Spec, bool) {
+	for _, s := range specs {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Spec{}, false
+}
@@ -4,35 +4,13 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
 	"pocketbase-server/pb/rules"
 )
 
-func EnsureCollectionOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureCollection(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsureCollection(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("photos")
-	if existing != nil {
-		return patch.Collection(app, "photos",
-			patch.AutodateFields(),
-		)
-	}
-
-	collection := core.NewBaseCollection("photos")
-
-	collection.ListRule = rules.Ptr(rules.Public)
-	collection.ViewRule = rules.Ptr(rules.Public)
-	collection.CreateRule = rules.Ptr(rules.AuthOnly)
-	collection.UpdateRule = rules.Ptr(rules.AuthOnly)
-	collection.DeleteRule = rules.Ptr(rules.AuthOnly)
-
-	collection.Fields.Add(
+var photosSpec = patch.Spec{
+	Name: "photos",
+	Fields: []core.Field{
 		// The actual file — PocketBase serves this from local disk or S3 transparently
 		&core.FileField{
 			Name:      "file",
@@ -46,9 +24,28 @@ func EnsureCollection(app core.App) error {
 		&core.URLField{Name: "source_url"},
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_photos_created", Columns: "created"},
+	},
+	Rules: patch.Rules{
+		List:   rules.Ptr(rules.Public),
+		View:   rules.Ptr(rules.Public),
+		Create: rules.Ptr(rules.AuthOnly),
+		Update: rules.Ptr(rules.AuthOnly),
+		Delete: rules.Ptr(rules.AuthOnly),
+	},
+}
 
-	collection.AddIndex("idx_photos_created", false, "created", "")
+func init() {
+	patch.RegisterSpec(photosSpec)
+	registry.Register(registry.Collection{
+		Name:      "photos",
+		DependsOn: photosSpec.Dependencies(),
+		Setup:     EnsureCollection,
+	})
+}
 
-	return app.Save(collection)
+func EnsureCollection(app core.App) error {
+	return patch.Ensure(app, photosSpec)
 }
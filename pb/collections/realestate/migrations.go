@@ -0,0 +1,53 @@
+package realestate
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/pb/collections/patch"
+)
+
+// listingFields were added to properties after the first release. The
+// properties spec declares them now; the migration stays registered for
+// databases that already applied it. It has no down step: the spec owns
+// the fields, so dropping them would only lose their data until the next
+// reconcile added them back empty.
+var listingFields = []core.Field{
+	&core.NumberField{Name: "lat"},
+	&core.NumberField{Name: "lng"},
+	&core.NumberField{Name: "price"},
+	&core.NumberField{Name: "bedrooms"},
+	&core.NumberField{Name: "bathrooms"},
+	&core.NumberField{Name: "sqft"},
+	&core.TextField{Name: "property_type"},
+	&core.TextField{Name: "notes"},
+}
+
+func init() {
+	patch.Register(patch.Migration{
+		Id:          "20260101_properties_listing_fields",
+		Description: "add the listing fields to properties",
+		Up: func(app core.App) error {
+			fields := make([]patch.Func, len(listingFields))
+			for i, f := range listingFields {
+				fields[i] = patch.Field(f)
+			}
+			return patch.Collection(app, "properties", fields...)
+		},
+	})
+
+	patch.Register(patch.Migration{
+		Id:          "20260102_properties_optional_organization",
+		Description: "make properties.organization optional",
+		Up: func(app core.App) error {
+			return patch.Collection(app, "properties",
+				patch.RelationField("organization", func(f *core.RelationField) bool {
+					if !f.Required {
+						return false
+					}
+					f.Required = false
+					return true
+				}),
+			)
+		},
+	})
+}
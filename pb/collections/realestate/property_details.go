@@ -4,48 +4,59 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
+	"pocketbase-server/pb/collections/visibility"
 	"pocketbase-server/pb/rules"
 )
 
-// ── Property Details ──────────────────────────────────────────────────────────
-// Public-record physical facts that rarely change. One row per property.
-
-func EnsurePropertyDetailsOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsurePropertyDetails(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
+// Org members can read the property record collections; writes are
+// system-only (scraped / imported data).
+var propertyRecordRules = patch.Rules{
+	List: rules.Ptr(rules.AuthOnly),
+	View: rules.Ptr(rules.AuthOnly),
 }
 
-func EnsurePropertyDetails(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("property_details")
-	if existing != nil {
-		return patch.Collection(app, "property_details",
-			patch.AutodateFields(),
-			patch.Index("idx_property_details_apn", false, "apn"),
-		)
-	}
-
-	propertiesCol, err := app.FindCollectionByNameOrId("properties")
-	if err != nil {
-		return err
+func init() {
+	for _, c := range []struct {
+		spec  patch.Spec
+		setup func(app core.App) error
+	}{
+		{propertyDetailsSpec, EnsurePropertyDetails},
+		{propertySaleHistorySpec, EnsurePropertySaleHistory},
+		{propertyTaxHistorySpec, EnsurePropertyTaxHistory},
+		{propertyContactsSpec, EnsurePropertyContacts},
+	} {
+		patch.RegisterSpec(c.spec)
+		registry.Register(registry.Collection{
+			Name:      c.spec.Name,
+			DependsOn: c.spec.Dependencies(),
+			Setup:     c.setup,
+		})
 	}
 
-	col := core.NewBaseCollection("property_details")
+	// Any signed-in user can read contacts; their details are for the
+	// property's org.
+	contactDetails := []visibility.Audience{visibility.OrgMember, visibility.PlatformAdmin}
+	visibility.Register(visibility.Policy{
+		Collection: "property_contacts",
+		OrgField:   "property.organization",
+		Fields: map[string][]visibility.Audience{
+			"phone":          contactDetails,
+			"email":          contactDetails,
+			"license_number": contactDetails,
+		},
+	})
+}
 
-	// Org members can read; writes are system-only (scraped / imported data)
-	col.ListRule = rules.Ptr(rules.AuthOnly)
-	col.ViewRule = rules.Ptr(rules.AuthOnly)
-	col.CreateRule = nil
-	col.UpdateRule = nil
-	col.DeleteRule = nil
+// ── Property Details ──────────────────────────────────────────────────────────
+// Public-record physical facts that rarely change. One row per property.
 
-	col.Fields.Add(
+var propertyDetailsSpec = patch.Spec{
+	Name: "property_details",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "property",
-			CollectionId:  propertiesCol.Id,
+			CollectionId:  "properties",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -85,53 +96,27 @@ func EnsurePropertyDetails(app core.App) error {
 
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-
-	col.AddIndex("idx_property_details_property", true, "property", "") // one row per property
-	col.AddIndex("idx_property_details_apn", false, "apn", "")
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_property_details_property", Unique: true, Columns: "property"}, // one row per property
+		{Name: "idx_property_details_apn", Columns: "apn"},
+	},
+	Rules: propertyRecordRules,
+}
 
-	return app.Save(col)
+func EnsurePropertyDetails(app core.App) error {
+	return patch.Ensure(app, propertyDetailsSpec)
 }
 
 // ── Property Sale History ─────────────────────────────────────────────────────
 // Every recorded sale/listing event (from MLS, public records, etc.).
 
-func EnsurePropertySaleHistoryOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsurePropertySaleHistory(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsurePropertySaleHistory(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("property_sale_history")
-	if existing != nil {
-		return patch.Collection(app, "property_sale_history",
-			patch.AutodateFields(),
-			patch.Index("idx_psh_property", false, "property"),
-			patch.Index("idx_psh_event_date", false, "event_date"),
-		)
-	}
-
-	propertiesCol, err := app.FindCollectionByNameOrId("properties")
-	if err != nil {
-		return err
-	}
-
-	col := core.NewBaseCollection("property_sale_history")
-
-	col.ListRule = rules.Ptr(rules.AuthOnly)
-	col.ViewRule = rules.Ptr(rules.AuthOnly)
-	col.CreateRule = nil
-	col.UpdateRule = nil
-	col.DeleteRule = nil
-
-	col.Fields.Add(
+var propertySaleHistorySpec = patch.Spec{
+	Name: "property_sale_history",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "property",
-			CollectionId:  propertiesCol.Id,
+			CollectionId:  "properties",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -150,52 +135,27 @@ func EnsurePropertySaleHistory(app core.App) error {
 
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-
-	col.AddIndex("idx_psh_property", false, "property", "")
-	col.AddIndex("idx_psh_event_date", false, "event_date", "")
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_psh_property", Columns: "property"},
+		{Name: "idx_psh_event_date", Columns: "event_date"},
+	},
+	Rules: propertyRecordRules,
+}
 
-	return app.Save(col)
+func EnsurePropertySaleHistory(app core.App) error {
+	return patch.Ensure(app, propertySaleHistorySpec)
 }
 
 // ── Property Tax History ──────────────────────────────────────────────────────
 // Annual tax assessments. One row per property per year.
 
-func EnsurePropertyTaxHistoryOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsurePropertyTaxHistory(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsurePropertyTaxHistory(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("property_tax_history")
-	if existing != nil {
-		return patch.Collection(app, "property_tax_history",
-			patch.AutodateFields(),
-			patch.Index("idx_pth_property", false, "property"),
-		)
-	}
-
-	propertiesCol, err := app.FindCollectionByNameOrId("properties")
-	if err != nil {
-		return err
-	}
-
-	col := core.NewBaseCollection("property_tax_history")
-
-	col.ListRule = rules.Ptr(rules.AuthOnly)
-	col.ViewRule = rules.Ptr(rules.AuthOnly)
-	col.CreateRule = nil
-	col.UpdateRule = nil
-	col.DeleteRule = nil
-
-	col.Fields.Add(
+var propertyTaxHistorySpec = patch.Spec{
+	Name: "property_tax_history",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "property",
-			CollectionId:  propertiesCol.Id,
+			CollectionId:  "properties",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -209,52 +169,27 @@ func EnsurePropertyTaxHistory(app core.App) error {
 
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-
-	col.AddIndex("idx_pth_property", false, "property", "")
-	col.AddIndex("idx_pth_property_year", true, "property, tax_year", "") // one row per year
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_pth_property", Columns: "property"},
+		{Name: "idx_pth_property_year", Unique: true, Columns: "property, tax_year"}, // one row per year
+	},
+	Rules: propertyRecordRules,
+}
 
-	return app.Save(col)
+func EnsurePropertyTaxHistory(app core.App) error {
+	return patch.Ensure(app, propertyTaxHistorySpec)
 }
 
 // ── Property Contacts ─────────────────────────────────────────────────────────
 // Listing agents, buyer agents, property managers, etc.
 
-func EnsurePropertyContactsOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsurePropertyContacts(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsurePropertyContacts(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("property_contacts")
-	if existing != nil {
-		return patch.Collection(app, "property_contacts",
-			patch.AutodateFields(),
-			patch.Index("idx_pc_property", false, "property"),
-		)
-	}
-
-	propertiesCol, err := app.FindCollectionByNameOrId("properties")
-	if err != nil {
-		return err
-	}
-
-	col := core.NewBaseCollection("property_contacts")
-
-	col.ListRule = rules.Ptr(rules.AuthOnly)
-	col.ViewRule = rules.Ptr(rules.AuthOnly)
-	col.CreateRule = nil
-	col.UpdateRule = nil
-	col.DeleteRule = nil
-
-	col.Fields.Add(
+var propertyContactsSpec = patch.Spec{
+	Name: "property_contacts",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "property",
-			CollectionId:  propertiesCol.Id,
+			CollectionId:  "properties",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -274,10 +209,14 @@ func EnsurePropertyContacts(app core.App) error {
 
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-
-	col.AddIndex("idx_pc_property", false, "property", "")
-	col.AddIndex("idx_pc_property_role", false, "property, role", "")
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_pc_property", Columns: "property"},
+		{Name: "idx_pc_property_role", Columns: "property, role"},
+	},
+	Rules: propertyRecordRules,
+}
 
-	return app.Save(col)
+func EnsurePropertyContacts(app core.App) error {
+	return patch.Ensure(app, propertyContactsSpec)
 }
@@ -4,41 +4,13 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
 	"pocketbase-server/pb/rules"
 )
 
-func EnsureRentalCompsOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureRentalComps(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsureRentalComps(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("rental_comps")
-	if existing != nil {
-		return patch.Collection(app, "rental_comps",
-			patch.AutodateFields(),
-			patch.Index("idx_rental_comps_created", false, "created"),
-		)
-	}
-
-	photosCol, err := app.FindCollectionByNameOrId("photos")
-	if err != nil {
-		return err
-	}
-
-	collection := core.NewBaseCollection("rental_comps")
-
-	collection.ListRule = rules.Ptr(rules.Public)
-	collection.ViewRule = rules.Ptr(rules.Public)
-	collection.CreateRule = rules.Ptr(rules.AuthOnly)
-	collection.UpdateRule = rules.Ptr(rules.AuthOnly)
-	collection.DeleteRule = rules.Ptr(rules.AuthOnly)
-
-	collection.Fields.Add(
+var rentalCompsSpec = patch.Spec{
+	Name: "rental_comps",
+	Fields: []core.Field{
 		&core.TextField{Name: "address", Required: true},
 		&core.NumberField{Name: "lat"},
 		&core.NumberField{Name: "lng"},
@@ -56,14 +28,33 @@ func EnsureRentalComps(app core.App) error {
 		// Relation to photos collection — supports multiple photos
 		&core.RelationField{
 			Name:         "photos",
-			CollectionId: photosCol.Id,
+			CollectionId: "photos",
 			MaxSelect:    20,
 		},
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_rental_comps_created", Columns: "created"},
+	},
+	Rules: patch.Rules{
+		List:   rules.Ptr(rules.Public),
+		View:   rules.Ptr(rules.Public),
+		Create: rules.Ptr(rules.AuthOnly),
+		Update: rules.Ptr(rules.AuthOnly),
+		Delete: rules.Ptr(rules.AuthOnly),
+	},
+}
 
-	collection.AddIndex("idx_rental_comps_created", false, "created", "")
+func init() {
+	patch.RegisterSpec(rentalCompsSpec)
+	registry.Register(registry.Collection{
+		Name:      "rental_comps",
+		DependsOn: rentalCompsSpec.Dependencies(),
+		Setup:     EnsureRentalComps,
+	})
+}
 
-	return app.Save(collection)
+func EnsureRentalComps(app core.App) error {
+	return patch.Ensure(app, rentalCompsSpec)
 }
@@ -6,56 +6,23 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
 	"pocketbase-server/pb/rules"
 )
 
-func EnsureSavedPropertiesOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureSavedProperties(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-func EnsureSavedProperties(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("saved_properties")
-	if existing != nil {
-		return patch.Collection(app, "saved_properties",
-			patch.AutodateFields(),
-		)
-	}
-
-	usersCol, err := app.FindCollectionByNameOrId("users")
-	if err != nil {
-		return err
-	}
-
-	propertiesCol, err := app.FindCollectionByNameOrId("properties")
-	if err != nil {
-		return err
-	}
-
-	collection := core.NewBaseCollection("saved_properties")
-
-	ownerRule := rules.Ptr(rules.OwnRecord("user"))
-	collection.ListRule = ownerRule
-	collection.ViewRule = ownerRule
-	collection.CreateRule = ownerRule
-	collection.UpdateRule = ownerRule
-	collection.DeleteRule = ownerRule
-
-	collection.Fields.Add(
+var savedPropertiesSpec = patch.Spec{
+	Name: "saved_properties",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "user",
-			CollectionId:  usersCol.Id,
+			CollectionId:  "users",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
 		},
 		&core.RelationField{
 			Name:          "property",
-			CollectionId:  propertiesCol.Id,
+			CollectionId:  "properties",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -63,62 +30,33 @@ func EnsureSavedProperties(app core.App) error {
 		&core.TextField{Name: "notes"},
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-
-	// Prevent duplicate saves of the same property by the same user
-	collection.AddIndex("idx_saved_properties_user_property", true, "user, property", "")
-	collection.AddIndex("idx_saved_properties_user", false, "user", "")
-
-	return app.Save(collection)
-}
-
-func EnsureSavedPropertyHistoryOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureSavedPropertyHistory(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
+	},
+	Indexes: []patch.IndexSpec{
+		// Prevent duplicate saves of the same property by the same user
+		{Name: "idx_saved_properties_user_property", Unique: true, Columns: "user, property"},
+		{Name: "idx_saved_properties_user", Columns: "user"},
+	},
+	Rules: patch.Rules{
+		List:   rules.Ptr(rules.OwnRecord("user")),
+		View:   rules.Ptr(rules.OwnRecord("user")),
+		Create: rules.Ptr(rules.OwnRecord("user")),
+		Update: rules.Ptr(rules.OwnRecord("user")),
+		Delete: rules.Ptr(rules.OwnRecord("user")),
+	},
 }
 
-func EnsureSavedPropertyHistory(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("saved_property_history")
-	if existing != nil {
-		return patch.Collection(app, "saved_property_history",
-			patch.AutodateFields(),
-		)
-	}
-
-	usersCol, err := app.FindCollectionByNameOrId("users")
-	if err != nil {
-		return err
-	}
-
-	propertiesCol, err := app.FindCollectionByNameOrId("properties")
-	if err != nil {
-		return err
-	}
-
-	collection := core.NewBaseCollection("saved_property_history")
-
-	ownerRule := rules.Ptr(rules.OwnRecord("user"))
-	collection.ListRule = ownerRule
-	collection.ViewRule = ownerRule
-	// history is written by hooks only — no direct create/update/delete from clients
-	collection.CreateRule = nil
-	collection.UpdateRule = nil
-	collection.DeleteRule = nil
-
-	collection.Fields.Add(
+var savedPropertyHistorySpec = patch.Spec{
+	Name: "saved_property_history",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:         "user",
-			CollectionId: usersCol.Id,
+			CollectionId: "users",
 			Required:     true,
 			MaxSelect:    1,
 		},
 		&core.RelationField{
 			Name:         "property",
-			CollectionId: propertiesCol.Id,
+			CollectionId: "properties",
 			Required:     true,
 			MaxSelect:    1,
 		},
@@ -130,12 +68,39 @@ func EnsureSavedPropertyHistory(app core.App) error {
 		},
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_saved_property_history_user", Columns: "user"},
+		{Name: "idx_saved_property_history_property", Columns: "property"},
+	},
+	// history is written by hooks only — no direct create/update/delete from clients
+	Rules: patch.Rules{
+		List: rules.Ptr(rules.OwnRecord("user")),
+		View: rules.Ptr(rules.OwnRecord("user")),
+	},
+}
 
-	collection.AddIndex("idx_saved_property_history_user", false, "user", "")
-	collection.AddIndex("idx_saved_property_history_property", false, "property", "")
+func init() {
+	patch.RegisterSpec(savedPropertiesSpec)
+	patch.RegisterSpec(savedPropertyHistorySpec)
+	registry.Register(registry.Collection{
+		Name:      "saved_properties",
+		DependsOn: savedPropertiesSpec.Dependencies(),
+		Setup:     EnsureSavedProperties,
+	})
+	registry.Register(registry.Collection{
+		Name:      "saved_property_history",
+		DependsOn: savedPropertyHistorySpec.Dependencies(),
+		Setup:     EnsureSavedPropertyHistory,
+	})
+}
 
-	return app.Save(collection)
+func EnsureSavedProperties(app core.App) error {
+	return patch.Ensure(app, savedPropertiesSpec)
+}
+
+func EnsureSavedPropertyHistory(app core.App) error {
+	return patch.Ensure(app, savedPropertyHistorySpec)
 }
 
 // RegisterSavedPropertyHooks writes a history record whenever a property is saved or unsaved.
@@ -144,7 +109,7 @@ func RegisterSavedPropertyHooks(app core.App) {
 		if err := e.Next(); err != nil {
 			return err
 		}
-		writeHistory(app, e.Record.GetString("user"), e.Record.GetString("property"), "saved")
+		writeHistory(e.App, e.Record.GetString("user"), e.Record.GetString("property"), "saved")
 		return nil
 	})
 
@@ -157,7 +122,7 @@ func RegisterSavedPropertyHooks(app core.App) {
 			return err
 		}
 
-		writeHistory(app, userId, propertyId, "unsaved")
+		writeHistory(e.App, userId, propertyId, "unsaved")
 		return nil
 	})
 }
@@ -0,0 +1,134 @@
+// Package registry holds the collections bootstrap sets up. Each
+// collection package registers its collections from init, with their
+// dependencies, and bootstrap runs them in dependency order: every Setup
+// first, then every Rules.
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Collection is a collection's bootstrap entry.
+type Collection struct {
+	Name string
+	// DependsOn lists the collections whose Setup must run first, such as
+	// the targets of relation fields.
+	DependsOn []string
+	// Setup creates or patches the collection.
+	Setup func(app core.App) error
+	// Rules applies access rules that can refer to other collections. It
+	// runs after every Setup; optional.
+	Rules func(app core.App) error
+}
+
+// registered holds the registered collections by name.
+var registered = map[string]Collection{}
+
+// Register adds c to the registry. Call it from init; it panics on a
+// duplicate name or a missing Setup.
+func Register(c Collection) {
+	if c.Name == "" || c.Setup == nil {
+		panic(fmt.Sprintf("registry: collection %q needs a name and a setup step", c.Name))
+	}
+	if _, ok := registered[c.Name]; ok {
+		panic(fmt.Sprintf("registry: duplicate collection %q", c.Name))
+	}
+	registered[c.Name] = c
+}
+
+// Collections returns the registered collections in dependency order.
+func Collections() ([]Collection, error) {
+	cols := make([]Collection, 0, len(registered))
+	for _, c := range registered {
+		cols = append(cols, c)
+	}
+	return Sort(cols)
+}
+
+// Sort orders cols so each collection comes after its dependencies, and
+// alphabetically otherwise. A dependency missing from cols or a cycle is
+// an error.
+func Sort(cols []Collection) ([]Collection, error) {
+	byName := make(map[string]Collection, len(cols))
+	names := make([]string, 0, len(cols))
+	for _, c := range cols {
+		byName[c.Name] = c
+		names = append(names, c.Name)
+	}
+	slices.Sort(names)
+
+	deps := make(map[string][]string, len(cols))
+	for _, name := range names {
+		deps[name] = slices.Clone(byName[name].DependsOn)
+		slices.Sort(deps[name])
+		for _, dep := range deps[name] {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("collection %q depends on %q, which is not registered", name, dep)
+			}
+		}
+	}
+
+	ordered, err := Order(names, func(name string) []string { return deps[name] })
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]Collection, len(ordered))
+	for i, name := range ordered {
+		sorted[i] = byName[name]
+	}
+	return sorted, nil
+}
+
+// Order returns names so each comes after the dependencies deps returns
+// for it, and in the given order otherwise. Dependencies outside names
+// are ignored; a cycle is an error.
+func Order(names []string, deps func(name string) []string) ([]string, error) {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(names))
+	sorted := make([]string, 0, len(names))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, name):]), name)
+			return fmt.Errorf("collection dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dep := range deps(name) {
+			if !known[dep] {
+				continue
+			}
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+
+		state[name] = done
+		sorted = append(sorted, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
@@ -1,11 +1,11 @@
 package tenancy
 
 import (
-	"log"
+	"fmt"
 
-	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 
+	"pocketbase-server/pb/collections/patch"
 	"pocketbase-server/pb/rules"
 )
 
@@ -20,66 +20,71 @@ type OrgScoped struct {
 	PublicRead bool
 }
 
+// RulesVersion is the version of the org-scoped rules. Bump it when
+// changing rules() so existing databases pick up the change.
+const RulesVersion = 3
+
 // registered holds all org-scoped collections
 var registered []OrgScoped
 
 // Register adds a collection to the org-scoped tenancy system.
-// Call this from your Ensure* functions before EnforceTenancy runs.
+// Call it from init, next to the collection's registry entry.
 func Register(collection, orgField string) {
-	registered = append(registered, OrgScoped{Collection: collection, OrgField: orgField})
+	register(OrgScoped{Collection: collection, OrgField: orgField})
 }
 
 // RegisterPublicRead adds a collection with public list/view but org-gated writes.
 func RegisterPublicRead(collection, orgField string) {
-	registered = append(registered, OrgScoped{Collection: collection, OrgField: orgField, PublicRead: true})
+	register(OrgScoped{Collection: collection, OrgField: orgField, PublicRead: true})
 }
 
-// EnforceTenancy auto-applies org-scoped access rules to all registered collections.
-// Must run in Phase 2 (after all collections are created).
-//
-// Rules applied:
-//   - List/View: user must be a member of the record's org
-//   - Create/Update/Delete: user must be an owner or admin of the record's org
-//   - Platform admins (role="admin") bypass via @request.auth.role = "admin"
-func EnforceTenancy(app *pocketbase.PocketBase) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		for _, scope := range registered {
-			applyOrgScopedRules(app, scope)
-		}
-		return e.Next()
-	})
+func register(scope OrgScoped) {
+	registered = append(registered, scope)
+	patch.DeclareRules(scope.Collection, RulesVersion, scope.rules())
+	// Version 1, also what releases before the rules ledger applied
+	patch.DeclareEarlierRules(scope.Collection, 1, scope.rulesWith(rules.LegacyWithPlatformAdmin, rules.LegacyOrgAdmin))
 }
 
-func applyOrgScopedRules(app *pocketbase.PocketBase, scope OrgScoped) {
-	collection, err := app.FindCollectionByNameOrId(scope.Collection)
-	if err != nil {
-		log.Printf("tenancy: collection %q not found, skipping rules", scope.Collection)
-		return
-	}
-
-	// Skip if rules are already set (e.g. configured via admin UI)
-	if collection.ListRule != nil {
-		return
-	}
-
-	writeRule := rules.WithPlatformAdmin(rules.OrgAdmin(scope.OrgField))
+// rules returns the org-scoped rules of the collection.
+func (scope OrgScoped) rules() patch.Rules {
+	return scope.rulesWith(rules.WithPlatformAdmin, rules.OrgAdmin)
+}
 
+// rulesWith builds the rules from the given platform admin and org admin
+// rule builders, so earlier versions can be reproduced.
+func (scope OrgScoped) rulesWith(withPlatformAdmin func(string) string, orgAdmin func(string) string) patch.Rules {
+	writeRule := rules.Ptr(withPlatformAdmin(orgAdmin(scope.OrgField)))
+	r := patch.Rules{Create: writeRule, Update: writeRule, Delete: writeRule}
 	if scope.PublicRead {
-		collection.ListRule = rules.Ptr(rules.Public)
-		collection.ViewRule = rules.Ptr(rules.Public)
+		r.List = rules.Ptr(rules.Public)
+		r.View = rules.Ptr(rules.Public)
 	} else {
-		readRule := rules.WithPlatformAdmin(rules.OrgMember(scope.OrgField))
-		collection.ListRule = rules.Ptr(readRule)
-		collection.ViewRule = rules.Ptr(readRule)
+		readRule := withPlatformAdmin(rules.OrgMember(scope.OrgField))
+		r.List = rules.Ptr(readRule)
+		r.View = rules.Ptr(readRule)
 	}
+	return r
+}
 
-	collection.CreateRule = rules.Ptr(writeRule)
-	collection.UpdateRule = rules.Ptr(writeRule)
-	collection.DeleteRule = rules.Ptr(writeRule)
+// EnforceCollection applies the org-scoped rules of one registered
+// collection. Run it after every collection exists.
+//
+// Rules applied:
+//   - List/View: user must be a member of the record's org
+//   - Create/Update/Delete: user must be an owner or admin of the record's org
+//   - Platform admins (role="admin") bypass via @request.auth.role = "admin"
+func EnforceCollection(app core.App, collection string) error {
+	for _, scope := range registered {
+		if scope.Collection == collection {
+			return applyOrgScopedRules(app, scope)
+		}
+	}
+	return fmt.Errorf("tenancy: collection %q is not registered", collection)
+}
 
-	if err := app.Save(collection); err != nil {
-		log.Printf("tenancy: failed to apply rules to %q: %v", scope.Collection, err)
-	} else {
-		log.Printf("tenancy: applied org-scoped rules to %q", scope.Collection)
+func applyOrgScopedRules(app core.App, scope OrgScoped) error {
+	if err := patch.EnsureRules(app, scope.Collection); err != nil {
+		return fmt.Errorf("tenancy: failed to apply rules to %q: %w", scope.Collection, err)
 	}
+	return nil
 }
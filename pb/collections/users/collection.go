@@ -1,77 +1,84 @@
 package users
 
 import (
-	"log"
-
 	"github.com/pocketbase/pocketbase/core"
 
+	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
 	"pocketbase-server/pb/collections/roles"
+	"pocketbase-server/pb/collections/visibility"
 	"pocketbase-server/pb/rules"
 )
 
-// EnsureCollectionOnBeforeServe registers the users collection setup on server start.
-func EnsureCollectionOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureCollection(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-// EnsureCollection adds custom fields to the built-in users collection if they don't exist.
-func EnsureCollection(app core.App) error {
-	users, err := app.FindCollectionByNameOrId("users")
-	if err != nil {
-		return err
-	}
-
-	changed := false
+// usersSpec adds the custom fields to PocketBase's built-in users collection.
+var usersSpec = patch.Spec{
+	Name: "users",
+	Auth: true,
+	Fields: []core.Field{
+		// PocketBase's defaults
+		&core.TextField{Name: "name", Max: 255},
+		&core.FileField{
+			Name:      "avatar",
+			MaxSelect: 1,
+			MimeTypes: []string{"image/jpeg", "image/png", "image/svg+xml", "image/gif", "image/webp"},
+		},
+		&core.AutodateField{Name: "created", OnCreate: true},
+		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
 
-	if f := users.Fields.GetByName("phone"); f == nil {
-		users.Fields.Add(&core.TextField{
+		&core.TextField{
 			Name:    "phone",
 			Pattern: `^\+?[1-9]\d{1,14}$`,
-		})
-		changed = true
-	} else if tf, ok := f.(*core.TextField); ok && tf.Required {
-		tf.Required = false
-		changed = true
-	}
-
-	if users.Fields.GetByName("deactivated") == nil {
-		users.Fields.Add(&core.BoolField{
-			Name: "deactivated",
-		})
-		changed = true
-	}
-
-	if users.Fields.GetByName("role") == nil {
-		users.Fields.Add(&core.SelectField{
+		},
+		&core.BoolField{Name: "deactivated"},
+		&core.SelectField{
 			Name:      "role",
 			Required:  true,
 			MaxSelect: 1,
 			Values:    roles.AllPlatform,
-		})
-		changed = true
-	}
+		},
+	},
+	Rules: usersRules,
+	Patches: []patch.Func{
+		// The built-in collection comes with rules that leave out
+		// platform admins.
+		patch.ReplaceRules(pocketbaseUsersRules, usersRules),
+	},
+}
 
-	// Apply access rules if not already set
-	if users.ListRule == nil {
-		users.ListRule = rules.Ptr(rules.OwnUser)
-		users.ViewRule = rules.Ptr(rules.OwnUser)
-		users.UpdateRule = rules.Ptr(rules.OwnUser)
-		users.DeleteRule = rules.Ptr(rules.OwnUser)
-		changed = true
-	}
+var usersRules = patch.Rules{
+	List:   rules.Ptr(rules.OwnUser),
+	View:   rules.Ptr(rules.OwnUser),
+	Create: rules.Ptr(rules.Public), // sign-ups
+	Update: rules.Ptr(rules.OwnUser),
+	Delete: rules.Ptr(rules.OwnUser),
+}
 
-	if changed {
-		if err := app.Save(users); err != nil {
-			log.Printf("Failed to update users collection: %v", err)
-		} else {
-			log.Println("Updated users collection fields")
-		}
-	}
+// pocketbaseUsersRules are the rules PocketBase creates users with.
+var pocketbaseUsersRules = patch.Rules{
+	List:   rules.Ptr("id = @request.auth.id"),
+	View:   rules.Ptr("id = @request.auth.id"),
+	Create: rules.Ptr(rules.Public),
+	Update: rules.Ptr("id = @request.auth.id"),
+	Delete: rules.Ptr("id = @request.auth.id"),
+}
 
-	return nil
+func init() {
+	patch.RegisterSpec(usersSpec)
+	visibility.Register(visibility.Policy{
+		Collection: "users",
+		OwnerField: "id",
+		Fields: map[string][]visibility.Audience{
+			"phone": {visibility.Owner, visibility.PlatformAdmin},
+		},
+	})
+	registry.Register(registry.Collection{
+		Name:      "users",
+		DependsOn: usersSpec.Dependencies(),
+		Setup:     EnsureCollection,
+	})
+}
+
+// EnsureCollection adds custom fields to the built-in users collection if they don't exist.
+func EnsureCollection(app core.App) error {
+	return patch.Ensure(app, usersSpec)
 }
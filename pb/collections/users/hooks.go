@@ -37,11 +37,11 @@ func RegisterHooks(app core.App) {
 		}
 
 		// Auto-create settings record (skip if one already exists)
-		settingsCol, err := app.FindCollectionByNameOrId("settings")
+		settingsCol, err := e.App.FindCollectionByNameOrId("settings")
 		if err != nil {
 			log.Printf("settings collection not found: %v", err)
 		} else {
-			exists, _ := app.FindFirstRecordByFilter("settings", "user = {:userId}", map[string]any{"userId": e.Record.Id})
+			exists, _ := e.App.FindFirstRecordByFilter("settings", "user = {:userId}", map[string]any{"userId": e.Record.Id})
 			if exists == nil {
 				settings := core.NewRecord(settingsCol)
 				settings.Set("user", e.Record.Id)
@@ -49,14 +49,14 @@ func RegisterHooks(app core.App) {
 				settings.Set("sms_notifications", false)
 				settings.Set("theme", "system")
 
-				if err := app.Save(settings); err != nil {
+				if err := e.App.Save(settings); err != nil {
 					log.Printf("Failed to create settings for user %s: %v", e.Record.Id, err)
 				}
 			}
 		}
 
 		// Auto-create a personal organization and add user as owner
-		orgsCol, err := app.FindCollectionByNameOrId("organizations")
+		orgsCol, err := e.App.FindCollectionByNameOrId("organizations")
 		if err != nil {
 			log.Printf("organizations collection not found: %v", err)
 			return nil
@@ -71,14 +71,14 @@ func RegisterHooks(app core.App) {
 		org.Set("name", username+"'s Organization")
 		org.Set("slug", e.Record.Id)
 
-		if err := app.Save(org); err != nil {
+		if err := e.App.Save(org); err != nil {
 			log.Printf("Failed to create personal org for user %s: %v", e.Record.Id, err)
 			return nil
 		}
 		log.Printf("Created personal org %s for user %s", org.Id, e.Record.Id)
 
 		// Add user as owner of the new org
-		membersCol, err := app.FindCollectionByNameOrId("org_members")
+		membersCol, err := e.App.FindCollectionByNameOrId("org_members")
 		if err != nil {
 			log.Printf("org_members collection not found: %v", err)
 			return nil
@@ -89,12 +89,12 @@ func RegisterHooks(app core.App) {
 		member.Set("organization", org.Id)
 		member.Set("role", roles.OrgOwner)
 
-		if err := app.Save(member); err != nil {
+		if err := e.App.Save(member); err != nil {
 			log.Printf("Failed to create org owner membership for user %s: %v", e.Record.Id, err)
 		}
 
 		// Send welcome notification
-		notifClient := notifications.NewClient(app)
+		notifClient := notifications.NewClient(e.App)
 		if _, err := notifClient.Send(notifications.NotificationOpts{
 			Recipient:    e.Record.Id,
 			Owner:        e.Record.Id,
@@ -0,0 +1,53 @@
+package users
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/pb/collections/patch"
+)
+
+func init() {
+	// The settings spec declares these values now; the migration stays
+	// registered for databases that already applied it.
+	patch.Register(patch.Migration{
+		Id:          "20260101_settings_theme_values",
+		Description: "add the light, dark and system values to settings.theme",
+		Up: func(app core.App) error {
+			return patch.Collection(app, "settings", func(col *core.Collection) bool {
+				sel, ok := col.Fields.GetByName("theme").(*core.SelectField)
+				if !ok {
+					return false
+				}
+
+				have := map[string]bool{}
+				for _, v := range sel.Values {
+					have[v] = true
+				}
+				changed := false
+				for _, v := range []string{"light", "dark", "system"} {
+					if !have[v] {
+						sel.Values = append(sel.Values, v)
+						changed = true
+					}
+				}
+				return changed
+			})
+		},
+	})
+
+	patch.Register(patch.Migration{
+		Id:          "20260103_users_optional_phone",
+		Description: "make users.phone optional",
+		Up: func(app core.App) error {
+			return patch.Collection(app, "users",
+				patch.TextField("phone", func(f *core.TextField) bool {
+					if !f.Required {
+						return false
+					}
+					f.Required = false
+					return true
+				}),
+			)
+		},
+	})
+}
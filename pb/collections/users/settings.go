@@ -1,44 +1,19 @@
 package users
 
 import (
-	"log"
-
 	"github.com/pocketbase/pocketbase/core"
 
 	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
 	"pocketbase-server/pb/rules"
 )
 
-// EnsureSettingsOnBeforeServe registers the settings collection setup on server start.
-func EnsureSettingsOnBeforeServe(app core.App) {
-	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		if err := EnsureSettings(e.App); err != nil {
-			return err
-		}
-		return e.Next()
-	})
-}
-
-// EnsureSettings creates the settings collection if it doesn't exist.
-func EnsureSettings(app core.App) error {
-	existing, _ := app.FindCollectionByNameOrId("settings")
-	if existing != nil {
-		ensureSettingsFields(app, existing)
-		return patch.Collection(app, "settings",
-			patch.AutodateFields(),
-		)
-	}
-
-	usersCol, err := app.FindCollectionByNameOrId("users")
-	if err != nil {
-		return err
-	}
-
-	collection := core.NewBaseCollection("settings")
-	collection.Fields.Add(
+var settingsSpec = patch.Spec{
+	Name: "settings",
+	Fields: []core.Field{
 		&core.RelationField{
 			Name:          "user",
-			CollectionId:  usersCol.Id,
+			CollectionId:  "users",
 			Required:      true,
 			MaxSelect:     1,
 			CascadeDelete: true,
@@ -52,55 +27,31 @@ func EnsureSettings(app core.App) error {
 		},
 		&core.TextField{Name: "timezone"},
 		&core.JSONField{Name: "preferences"},
-	)
-
-	ownerRule := rules.Ptr(rules.OwnRecord("user"))
-	collection.ListRule = ownerRule
-	collection.ViewRule = ownerRule
-	collection.UpdateRule = ownerRule
-	collection.CreateRule = ownerRule
-	collection.DeleteRule = ownerRule
-
-	collection.Fields.Add(
 		&core.AutodateField{Name: "created", OnCreate: true},
 		&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
-	)
-	collection.AddIndex("idx_user_settings_user", true, "user", "")
-
-	return app.Save(collection)
+	},
+	Indexes: []patch.IndexSpec{
+		{Name: "idx_user_settings_user", Unique: true, Columns: "user"},
+	},
+	Rules: patch.Rules{
+		List:   rules.Ptr(rules.OwnRecord("user")),
+		View:   rules.Ptr(rules.OwnRecord("user")),
+		Create: rules.Ptr(rules.OwnRecord("user")),
+		Update: rules.Ptr(rules.OwnRecord("user")),
+		Delete: rules.Ptr(rules.OwnRecord("user")),
+	},
 }
 
-// ensureSettingsFields updates the theme SelectField to include all valid values
-// if the collection was created by an older version that was missing some.
-func ensureSettingsFields(app core.App, col *core.Collection) {
-	themeField := col.Fields.GetByName("theme")
-	if themeField == nil {
-		return
-	}
-
-	sel, ok := themeField.(*core.SelectField)
-	if !ok {
-		return
-	}
-
-	expected := []string{"light", "dark", "system"}
-	missing := false
-	have := map[string]bool{}
-	for _, v := range sel.Values {
-		have[v] = true
-	}
-	for _, v := range expected {
-		if !have[v] {
-			missing = true
-			sel.Values = append(sel.Values, v)
-		}
-	}
+func init() {
+	patch.RegisterSpec(settingsSpec)
+	registry.Register(registry.Collection{
+		Name:      "settings",
+		DependsOn: settingsSpec.Dependencies(),
+		Setup:     EnsureSettings,
+	})
+}
 
-	if missing {
-		if err := app.Save(col); err != nil {
-			log.Printf("Failed to update settings theme field: %v", err)
-		} else {
-			log.Println("Updated settings theme field with missing values")
-		}
-	}
+// EnsureSettings creates the settings collection if it doesn't exist.
+func EnsureSettings(app core.App) error {
+	return patch.Ensure(app, settingsSpec)
 }
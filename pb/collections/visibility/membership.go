@@ -0,0 +1,130 @@
+package visibility
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// lookups caches the queries behind org membership: the viewer's
+// organizations and the records relation paths pass through.
+type lookups struct {
+	mu      sync.Mutex
+	viewer  string
+	orgs    map[string]bool
+	records map[string]*core.Record
+}
+
+func newLookups() *lookups {
+	return &lookups{records: map[string]*core.Record{}}
+}
+
+// active holds the lookups of the requests whose records are being
+// enriched.
+var (
+	activeMu sync.Mutex
+	active   = map[*core.RequestInfo]*lookups{}
+)
+
+// requestLookups returns the lookups shared by the records enriched for
+// info, and a release func to call when the caller's enrichment is done.
+// Only the first caller of a request owns them; the release of nested
+// callers does nothing.
+func requestLookups(info *core.RequestInfo) (*lookups, func()) {
+	if info == nil {
+		return newLookups(), func() {}
+	}
+
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	if l, ok := active[info]; ok {
+		return l, func() {}
+	}
+	l := newLookups()
+	active[info] = l
+	return l, func() {
+		activeMu.Lock()
+		delete(active, info)
+		activeMu.Unlock()
+	}
+}
+
+// isOrgMember reports whether auth belongs to the organization OrgField
+// leads to from record.
+func (p Policy) isOrgMember(app core.App, record, auth *core.Record, l *lookups) (bool, error) {
+	if !isUser(auth) {
+		return false, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	orgs, err := l.viewerOrgs(app, auth)
+	if err != nil || len(orgs) == 0 {
+		return false, err
+	}
+	orgId, err := l.resolve(app, record, p.OrgField)
+	if err != nil || orgId == "" {
+		return false, err
+	}
+	return orgs[orgId], nil
+}
+
+// viewerOrgs returns the ids of the organizations auth is a member of,
+// loaded once.
+func (l *lookups) viewerOrgs(app core.App, auth *core.Record) (map[string]bool, error) {
+	if l.orgs != nil && l.viewer == auth.Id {
+		return l.orgs, nil
+	}
+
+	var ids []string
+	err := app.DB().Select("organization").
+		From("org_members").
+		Where(dbx.HashExp{"user": auth.Id}).
+		Column(&ids)
+	if err != nil {
+		return nil, err
+	}
+
+	l.viewer = auth.Id
+	l.orgs = make(map[string]bool, len(ids))
+	for _, id := range ids {
+		l.orgs[id] = true
+	}
+	return l.orgs, nil
+}
+
+// resolve returns the id path leads to from record, loading the records
+// of the relations along the way.
+func (l *lookups) resolve(app core.App, record *core.Record, path string) (string, error) {
+	segments := strings.Split(path, ".")
+	for _, name := range segments[:len(segments)-1] {
+		rel, ok := record.Collection().Fields.GetByName(name).(*core.RelationField)
+		if !ok {
+			return "", fmt.Errorf("visibility: %s.%s is not a relation", record.Collection().Name, name)
+		}
+		id := record.GetString(name)
+		if id == "" {
+			return "", nil
+		}
+
+		key := rel.CollectionId + "/" + id
+		next, ok := l.records[key]
+		if !ok {
+			var err error
+			next, err = app.FindRecordById(rel.CollectionId, id)
+			if err != nil {
+				next = nil // dangling relation; nothing to be a member of
+			}
+			l.records[key] = next
+		}
+		if next == nil {
+			return "", nil
+		}
+		record = next
+	}
+	return record.GetString(segments[len(segments)-1]), nil
+}
@@ -0,0 +1,319 @@
+// Package visibility hides fields by audience. Access rules allow or deny
+// whole records; a Policy narrows who sees some of their fields. The
+// record enrich hook removes the other fields from API responses,
+// including realtime events and expanded relations, and list filters,
+// sorts and realtime subscriptions may not use them.
+package visibility
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/pb/collections/roles"
+	"pocketbase-server/pb/rules"
+)
+
+// Audience is a group of viewers a field can be shown to.
+type Audience string
+
+const (
+	// Public is everyone who can see the record.
+	Public Audience = "public"
+	// Owner is the user the record belongs to (see Policy.OwnerField).
+	Owner Audience = "owner"
+	// OrgMember is any member of the record's organization (see Policy.OrgField).
+	OrgMember Audience = "org_member"
+	// PlatformAdmin is users with the platform-level admin role.
+	PlatformAdmin Audience = "platform_admin"
+)
+
+// Policy declares who sees the restricted fields of a collection.
+// Superusers see every field; fields not listed are left to the rules.
+type Policy struct {
+	// Collection name in PocketBase
+	Collection string
+	// OwnerField is the relation to users naming the record's owner, or
+	// "id" for the users collection itself. Required by Owner.
+	OwnerField string
+	// OrgField is the path to the record's organization, following
+	// relations with dots (e.g. "property.organization"). Required by
+	// OrgMember.
+	OrgField string
+	// Fields maps each restricted field to the audiences that see it.
+	Fields map[string][]Audience
+}
+
+// registered holds the policies by collection.
+var registered = map[string]Policy{}
+
+// Register adds p. Call it from init; it panics on a duplicate collection
+// or an audience the policy can't resolve.
+func Register(p Policy) {
+	if _, ok := registered[p.Collection]; ok {
+		panic(fmt.Sprintf("visibility: duplicate policy for %q", p.Collection))
+	}
+	for field, audiences := range p.Fields {
+		if slices.Contains(audiences, Owner) && p.OwnerField == "" {
+			panic(fmt.Sprintf("visibility: %s.%s is shown to the owner but the policy has no OwnerField", p.Collection, field))
+		}
+		if slices.Contains(audiences, OrgMember) && p.OrgField == "" {
+			panic(fmt.Sprintf("visibility: %s.%s is shown to org members but the policy has no OrgField", p.Collection, field))
+		}
+	}
+	registered[p.Collection] = p
+}
+
+// Lookup returns the policy of collection.
+func Lookup(collection string) (Policy, bool) {
+	p, ok := registered[collection]
+	return p, ok
+}
+
+// RegisterHooks hides the restricted fields of every registered
+// collection from viewers outside their audiences, and rejects list and
+// realtime filters and sorts that could probe them.
+func RegisterHooks(app core.App) {
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	app.OnRecordEnrich(names...).BindFunc(func(e *core.RecordEnrichEvent) error {
+		var auth *core.Record
+		if e.RequestInfo != nil {
+			auth = e.RequestInfo.Auth
+		}
+		// The records of one response, and their expansions, are enriched
+		// inside this handler's e.Next, so they share the lookups.
+		lookups, release := requestLookups(e.RequestInfo)
+		defer release()
+
+		hidden, err := registered[e.Record.Collection().Name].hidden(e.App, e.Record, auth, lookups)
+		if err != nil {
+			return err
+		}
+		e.Record.Hide(hidden...)
+		return e.Next()
+	})
+
+	// Filters reach restricted fields through relations too, so every
+	// collection is checked.
+	app.OnRecordsListRequest().BindFunc(func(e *core.RecordsListRequestEvent) error {
+		query := e.Request.URL.Query()
+		if err := CheckQuery(e.App, e.Collection, e.Auth, query.Get("filter"), query.Get("sort")); err != nil {
+			return e.BadRequestError(err.Error(), nil)
+		}
+		return e.Next()
+	})
+
+	app.OnRealtimeSubscribeRequest().BindFunc(func(e *core.RealtimeSubscribeRequestEvent) error {
+		for _, sub := range e.Subscriptions {
+			collection, filter, sort := subscriptionQuery(sub)
+			col, err := e.App.FindCachedCollectionByNameOrId(collection)
+			if err != nil {
+				continue // not a record subscription
+			}
+			if err := CheckQuery(e.App, col, e.Auth, filter, sort); err != nil {
+				return e.BadRequestError(err.Error(), nil)
+			}
+		}
+		return e.Next()
+	})
+}
+
+// subscriptionQuery splits a realtime subscription such as
+// `properties/*?options={"query":{"filter":"..."}}` into its collection
+// and query filter and sort.
+func subscriptionQuery(sub string) (collection, filter, sort string) {
+	u, err := url.Parse(sub)
+	if err != nil {
+		return "", "", ""
+	}
+	collection, _, _ = strings.Cut(u.Path, "/")
+
+	var options struct {
+		Query map[string]any `json:"query"`
+	}
+	if raw := u.Query().Get("options"); raw != "" {
+		json.Unmarshal([]byte(raw), &options)
+	}
+	str := func(key string) string {
+		if v, ok := options.Query[key]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	return collection, str("filter"), str("sort")
+}
+
+// CheckQuery returns an error when filter or sort, run on col by auth,
+// reach a restricted field auth can't filter on. Matching a value reveals
+// it as well as returning it, so only audiences that don't depend on the
+// record (Public, PlatformAdmin) may filter; owners and org members can't,
+// since the query spans records of other owners and orgs.
+func CheckQuery(app core.App, col *core.Collection, auth *core.Record, filter, sort string) error {
+	if auth != nil && auth.IsSuperuser() {
+		return nil
+	}
+
+	exprs := []string{}
+	if filter != "" {
+		exprs = append(exprs, filter)
+	}
+	for _, item := range strings.Split(sort, ",") {
+		if name := strings.TrimLeft(strings.TrimSpace(item), "+-"); name != "" {
+			exprs = append(exprs, name+" = null")
+		}
+	}
+
+	var blocked []string
+	for _, expr := range exprs {
+		// Unknown fields and syntax errors are left to PocketBase.
+		rules.Fields(app, col, expr, func(c *core.Collection, field string) {
+			p, ok := registered[c.Name]
+			if !ok || p.Fields[field] == nil || p.filterable(field, auth) {
+				return
+			}
+			if name := c.Name + "." + field; !slices.Contains(blocked, name) {
+				blocked = append(blocked, name)
+			}
+		})
+	}
+	if len(blocked) > 0 {
+		return fmt.Errorf("restricted fields can't be used in filters or sorts: %s", strings.Join(blocked, ", "))
+	}
+	return nil
+}
+
+// filterable reports whether auth sees field on every record.
+func (p Policy) filterable(field string, auth *core.Record) bool {
+	for _, a := range p.Fields[field] {
+		switch a {
+		case Public:
+			return true
+		case PlatformAdmin:
+			if isUser(auth) && auth.GetString("role") == roles.Admin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Hidden returns the fields of record that auth, nil when anonymous, may
+// not see.
+func (p Policy) Hidden(app core.App, record, auth *core.Record) ([]string, error) {
+	return p.hidden(app, record, auth, newLookups())
+}
+
+func (p Policy) hidden(app core.App, record, auth *core.Record, lookups *lookups) ([]string, error) {
+	if auth != nil && auth.IsSuperuser() {
+		return nil, nil
+	}
+
+	// Resolved on first use, since org membership may take queries.
+	var member *bool
+	sees := func(a Audience) (bool, error) {
+		switch a {
+		case Public:
+			return true, nil
+		case PlatformAdmin:
+			return isUser(auth) && auth.GetString("role") == roles.Admin, nil
+		case Owner:
+			return isUser(auth) && auth.Id == record.GetString(p.OwnerField), nil
+		case OrgMember:
+			if member == nil {
+				m, err := p.isOrgMember(app, record, auth, lookups)
+				if err != nil {
+					return false, err
+				}
+				member = &m
+			}
+			return *member, nil
+		}
+		return false, nil
+	}
+
+	var hidden []string
+	for field, audiences := range p.Fields {
+		visible := false
+		for _, a := range audiences {
+			ok, err := sees(a)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				visible = true
+				break
+			}
+		}
+		if !visible {
+			hidden = append(hidden, field)
+		}
+	}
+	slices.Sort(hidden)
+	return hidden, nil
+}
+
+func isUser(auth *core.Record) bool {
+	return auth != nil && auth.Collection().Name == "users"
+}
+
+// Validate checks that the collections, fields and relation paths of the
+// registered policies exist.
+func Validate(app core.App) error {
+	var errs []error
+	for _, p := range registered {
+		col, err := app.FindCollectionByNameOrId(p.Collection)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("visibility: collection %q not found", p.Collection))
+			continue
+		}
+		for field := range p.Fields {
+			if col.Fields.GetByName(field) == nil {
+				errs = append(errs, fmt.Errorf("visibility: %s has no field %q", p.Collection, field))
+			}
+		}
+		if p.OwnerField != "" && col.Fields.GetByName(p.OwnerField) == nil {
+			errs = append(errs, fmt.Errorf("visibility: %s has no owner field %q", p.Collection, p.OwnerField))
+		}
+		if p.OrgField != "" {
+			if err := validatePath(app, col, p.OrgField); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validatePath(app core.App, col *core.Collection, path string) error {
+	segments := strings.Split(path, ".")
+	for i, name := range segments {
+		f := col.Fields.GetByName(name)
+		if f == nil {
+			return fmt.Errorf("visibility: %s has no field %q", col.Name, name)
+		}
+		if i == len(segments)-1 {
+			return nil
+		}
+		rel, ok := f.(*core.RelationField)
+		if !ok {
+			return fmt.Errorf("visibility: %s.%s is not a relation", col.Name, name)
+		}
+		next, err := app.FindCollectionByNameOrId(rel.CollectionId)
+		if err != nil {
+			return fmt.Errorf("visibility: %s.%s targets a missing collection", col.Name, name)
+		}
+		col = next
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package pb
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/pocketbase/pocketbase/ui"
+)
+
+// Handler bootstraps app and returns its fully configured router as an
+// http.Handler for mounting inside another server under prefix (e.g. "/pb").
+//
+// It triggers app.OnServe() the same way `serve` does, so collections,
+// hooks and custom routes are set up, but the listener stays with the
+// caller. Requests have prefix stripped before routing and redirects
+// issued by the app get it added back, so the admin UI is served at
+// <prefix>/_/ and the API at <prefix>/api/.
+//
+// Like `serve`, it allows CORS requests from allowedOrigins (default "*")
+// and sends caching and Content-Security-Policy headers with the admin UI.
+//
+// Call Shutdown when the host server stops.
+func Handler(app core.App, prefix string, allowedOrigins ...string) (http.Handler, error) {
+	prefix = normalizePrefix(prefix)
+
+	if !app.IsBootstrapped() {
+		if err := app.Bootstrap(); err != nil {
+			return nil, fmt.Errorf("failed to bootstrap app: %w", err)
+		}
+	}
+	if err := app.RunAllMigrations(); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{"*"}
+	}
+
+	pbRouter, err := apis.NewRouter(app)
+	if err != nil {
+		return nil, err
+	}
+
+	pbRouter.Bind(apis.CORS(apis.CORSConfig{
+		AllowOrigins: allowedOrigins,
+		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+	}))
+
+	// The admin UI only uses relative URLs, so it works under any prefix.
+	pbRouter.GET("/_/{path...}", apis.Static(ui.DistDirFS, false)).
+		BindFunc(uiHeaders).
+		Bind(apis.Gzip())
+
+	var mux http.Handler
+	event := &core.ServeEvent{
+		App:    app,
+		Router: pbRouter,
+		Server: &http.Server{},
+	}
+	err = app.OnServe().Trigger(event, func(e *core.ServeEvent) error {
+		mux, err = e.Router.BuildMux()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix == "" {
+		return mux, nil
+	}
+	return withPrefix(prefix, mux), nil
+}
+
+// Mount registers the handler for app on mux under prefix.
+func Mount(mux *http.ServeMux, app core.App, prefix string, allowedOrigins ...string) error {
+	h, err := Handler(app, prefix, allowedOrigins...)
+	if err != nil {
+		return err
+	}
+
+	prefix = normalizePrefix(prefix)
+	mux.Handle(prefix+"/", h)
+	if prefix != "" {
+		mux.Handle(prefix, http.RedirectHandler(prefix+"/", http.StatusMovedPermanently))
+	}
+	return nil
+}
+
+// Shutdown runs the app's OnTerminate hooks (final sync, closing
+// connections) for an app served through Handler.
+func Shutdown(app core.App) error {
+	return app.OnTerminate().Trigger(&core.TerminateEvent{App: app}, func(e *core.TerminateEvent) error {
+		return e.App.ResetBootstrapState()
+	})
+}
+
+// uiCSP is the Content-Security-Policy `serve` sends with the admin UI.
+const uiCSP = "default-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' http://127.0.0.1:* https://tile.openstreetmap.org data: blob:; connect-src 'self' http://127.0.0.1:* https://nominatim.openstreetmap.org; script-src 'self' 'sha256-GRUzBA7PzKYug7pqxv5rJaec5bwDCw1Vo6/IXwvD3Tc='"
+
+// uiHeaders sets the admin UI headers of `serve`: long caching for its
+// assets and a default CSP.
+func uiHeaders(e *core.RequestEvent) error {
+	if e.Request.PathValue(apis.StaticWildcardParam) != "" {
+		e.Response.Header().Set("Cache-Control", "max-age=1209600, stale-while-revalidate=86400")
+	}
+	if e.Response.Header().Get("Content-Security-Policy") == "" {
+		e.Response.Header().Set("Content-Security-Policy", uiCSP)
+	}
+	return e.Next()
+}
+
+func normalizePrefix(prefix string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
+
+// withPrefix strips prefix from incoming requests and adds it back to
+// absolute Location headers on the way out.
+func withPrefix(prefix string, h http.Handler) http.Handler {
+	stripped := http.StripPrefix(prefix, h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stripped.ServeHTTP(&prefixWriter{ResponseWriter: w, prefix: prefix}, r)
+	})
+}
+
+type prefixWriter struct {
+	http.ResponseWriter
+	prefix string
+}
+
+var _ router.RWUnwrapper = (*prefixWriter)(nil)
+
+func (w *prefixWriter) WriteHeader(status int) {
+	if loc := w.Header().Get("Location"); strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+		w.Header().Set("Location", w.prefix+loc)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+// (needed for flushing realtime responses).
+func (w *prefixWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
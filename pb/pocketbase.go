@@ -6,35 +6,42 @@ import (
 	"net/http"
 	"os"
 
-	"github.com/caarlos0/env/v11"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/config"
 )
 
+// PocketBaseConfig holds PocketBase configuration
 type PocketBaseConfig struct {
-	Dev        bool   `env:"PB_DEV" envDefault:"false"`
-	Enabled    bool   `env:"PB_ENABLED" envDefault:"true"`
-	AdminEmail string `env:"PB_ADMIN_EMAIL" envDefault:""`
-	AdminPass  string `env:"PB_ADMIN_PASS" envDefault:""`
-	Addr       string `env:"PB_ADDR" envDefault:"0.0.0.0:8080"`
-	DataDir    string `env:"PB_DATA_DIR" envDefault:"./db/pb_data"`
+	Dev        bool
+	Enabled    bool
+	AdminEmail string
+	AdminPass  string
+	Addr       string
+	DataDir    string
+
+	// Prefix mounts PocketBase on the host mux (e.g. "/pb") instead of
+	// running it on its own port. See RegisterRoutes.
+	Prefix string
 }
 
 func (cfg *PocketBaseConfig) HttpFlag() string {
-	fmt.Println(cfg.Addr)
 	return fmt.Sprintf("--http=%s", cfg.Addr)
 }
 
-func NewConfig() *PocketBaseConfig {
-	cfg := &PocketBaseConfig{}
-	if err := env.Parse(cfg); err != nil {
-		panic(err)
+// NewConfig derives the PocketBase group settings from the service config.
+func NewConfig(cfg *config.Config) *PocketBaseConfig {
+	return &PocketBaseConfig{
+		Dev:        cfg.Server.Dev,
+		Enabled:    true,
+		AdminEmail: cfg.Admin.Email,
+		AdminPass:  cfg.Admin.Pass,
+		Addr:       cfg.Server.Addr,
+		DataDir:    cfg.Server.DataDir,
 	}
-	return cfg
 }
 
-// PocketBaseConfig holds PocketBase configuration
-
 // PocketBaseGroup handles PocketBase integration
 type PocketBaseGroup struct {
 	app     *pocketbase.PocketBase
@@ -78,14 +85,14 @@ func NewPocketBaseGroup(cfg *PocketBaseConfig) *PocketBaseGroup {
 		return e.Next()
 	})
 
+	g.bindDefaultAdmin()
+
 	return g
 }
 
-func (g *PocketBaseGroup) Start() error {
-	if !g.enabled || g.app == nil {
-		return nil
-	}
-
+// bindDefaultAdmin creates the configured superuser on serve when
+// no real superuser exists yet.
+func (g *PocketBaseGroup) bindDefaultAdmin() {
 	if g.cfg.AdminEmail != "" && g.cfg.AdminPass != "" {
 		g.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
 			superusers, err := g.app.FindAllRecords("_superusers")
@@ -124,6 +131,17 @@ func (g *PocketBaseGroup) Start() error {
 			return e.Next()
 		})
 	}
+}
+
+func (g *PocketBaseGroup) Start() error {
+	if !g.enabled || g.app == nil {
+		return nil
+	}
+
+	// Embedded mode: the host server owns the listener (see RegisterRoutes)
+	if g.cfg.Prefix != "" {
+		return nil
+	}
 
 	// Set args to simulate "serve" command
 	args := []string{"pocketbase", "serve", g.cfg.HttpFlag()}
@@ -139,8 +157,17 @@ func (g *PocketBaseGroup) App() *pocketbase.PocketBase {
 	return g.app
 }
 
+// RegisterRoutes mounts PocketBase on mux under cfg.Prefix.
+// Without a prefix PocketBase runs on its own port (see Start) and
+// nothing is registered.
 func (g *PocketBaseGroup) RegisterRoutes(mux *http.ServeMux) {
-	// PocketBase runs on :8090, not on the main mux
+	if !g.enabled || g.app == nil || g.cfg.Prefix == "" {
+		return
+	}
+
+	if err := Mount(mux, g.app, g.cfg.Prefix); err != nil {
+		log.Printf("Failed to mount PocketBase at %s: %v", g.cfg.Prefix, err)
+	}
 }
 
 func (g *PocketBaseGroup) IsEnabled() bool {
@@ -0,0 +1,216 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ---- Expressions ----
+//
+// Expr builds a rule from typed parts instead of string concatenation:
+//
+//	And(
+//		Neq(Auth("id"), Value("")),
+//		Or(Eq(Field("owner"), Auth("id")), Eq(Auth("role"), Value("admin"))),
+//	).String()
+//	→  "@request.auth.id != '' && (owner = @request.auth.id || @request.auth.role = 'admin')"
+//
+// Every || group is parenthesized, so an expression keeps its meaning when
+// it is nested in another one.
+
+// Expr is a rule expression; String renders it in PocketBase filter syntax.
+type Expr interface {
+	String() string
+	// negate returns the expression with the opposite result.
+	negate() Expr
+}
+
+// Operand is one side of a comparison: a field path, a @request or
+// @collection reference, or a literal value.
+type Operand struct {
+	s string
+}
+
+func (o Operand) String() string {
+	return o.s
+}
+
+// Field references a field of the record, following relations with dots:
+//
+//	Field("organization.owner")
+func Field(path string) Operand {
+	return Operand{path}
+}
+
+// Request references the request, e.g. Request("body.role").
+func Request(path string) Operand {
+	return Operand{"@request." + path}
+}
+
+// Auth references a field of the authenticated user, e.g. Auth("id").
+func Auth(path string) Operand {
+	return Request("auth." + path)
+}
+
+// Collection references a field of another collection, joined on the
+// comparisons that use it:
+//
+//	Collection("org_members", "user")  →  "@collection.org_members.user"
+func Collection(name, path string) Operand {
+	return Operand{"@collection." + name + "." + path}
+}
+
+// Value is a literal: a string, number, bool or nil.
+func Value(v any) Operand {
+	switch v := v.(type) {
+	case nil:
+		return Operand{"null"}
+	case string:
+		return Operand{"'" + strings.ReplaceAll(v, "'", `\'`) + "'"}
+	case bool:
+		return Operand{strconv.FormatBool(v)}
+	case int, int32, int64, float32, float64:
+		return Operand{fmt.Sprint(v)}
+	}
+	panic(fmt.Sprintf("rules: unsupported value %T", v))
+}
+
+// compare is a single comparison.
+type compare struct {
+	left  Operand
+	op    string
+	right Operand
+}
+
+func (c compare) String() string {
+	return c.left.s + " " + c.op + " " + c.right.s
+}
+
+// negated maps each operator to its opposite. "Any item equals" is
+// negated to "no item equals", which is != on a multi-valued operand.
+var negated = map[string]string{
+	"=":   "!=",
+	"!=":  "=",
+	"?=":  "!=",
+	"?!=": "=",
+}
+
+func (c compare) negate() Expr {
+	return compare{c.left, negated[c.op], c.right}
+}
+
+// Eq matches when left equals right.
+func Eq(left, right Operand) Expr {
+	return compare{left, "=", right}
+}
+
+// Neq matches when left differs from right.
+func Neq(left, right Operand) Expr {
+	return compare{left, "!=", right}
+}
+
+// AnyEq matches when any item of a multi-valued operand, such as a
+// @collection reference, equals the other one.
+func AnyEq(left, right Operand) Expr {
+	return compare{left, "?=", right}
+}
+
+// In matches when left equals one of values.
+func In(left Operand, values ...any) Expr {
+	exprs := make([]Expr, len(values))
+	for i, v := range values {
+		exprs[i] = Eq(left, Value(v))
+	}
+	return Or(exprs...)
+}
+
+// group joins expressions with && or ||.
+type group struct {
+	join  string
+	exprs []Expr
+}
+
+func (g group) String() string {
+	parts := make([]string, len(g.exprs))
+	for i, e := range g.exprs {
+		parts[i] = e.String()
+		switch e := e.(type) {
+		case group:
+			// An || group parenthesizes itself.
+			if e.join == "&&" && g.join == "||" {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		case raw:
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	s := strings.Join(parts, " "+g.join+" ")
+	if g.join == "||" {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// negate applies De Morgan's laws.
+func (g group) negate() Expr {
+	exprs := make([]Expr, len(g.exprs))
+	for i, e := range g.exprs {
+		exprs[i] = e.negate()
+	}
+	if g.join == "&&" {
+		return Or(exprs...)
+	}
+	return And(exprs...)
+}
+
+func join(op string, exprs []Expr) Expr {
+	if len(exprs) == 0 {
+		panic("rules: " + op + " needs at least one expression")
+	}
+	var flat []Expr
+	for _, e := range exprs {
+		if g, ok := e.(group); ok && g.join == op {
+			flat = append(flat, g.exprs...)
+		} else {
+			flat = append(flat, e)
+		}
+	}
+	if len(flat) == 1 {
+		return flat[0]
+	}
+	return group{op, flat}
+}
+
+// And matches when every expression matches.
+func And(exprs ...Expr) Expr {
+	return join("&&", exprs)
+}
+
+// Or matches when any expression matches.
+func Or(exprs ...Expr) Expr {
+	return join("||", exprs)
+}
+
+// Not matches when e doesn't. PocketBase filters have no negation, so the
+// operators are inverted instead; Raw expressions can't be negated.
+func Not(e Expr) Expr {
+	return e.negate()
+}
+
+// raw is a rule string used as an expression.
+type raw string
+
+func (r raw) String() string {
+	return string(r)
+}
+
+func (r raw) negate() Expr {
+	panic(fmt.Sprintf("rules: can't negate raw rule %q", string(r)))
+}
+
+// Raw wraps an existing rule string, such as AuthOnly, so it can be
+// combined with expressions. It is parenthesized when nested.
+func Raw(rule string) Expr {
+	return raw(rule)
+}
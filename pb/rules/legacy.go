@@ -0,0 +1,32 @@
+package rules
+
+import "fmt"
+
+// ---- Earlier renderings ----
+//
+// Releases before the typed expressions wrote these strings to
+// collections. They are frozen so those databases are recognized and
+// upgraded (see patch.DeclareEarlierRules); don't change them.
+
+// LegacyOrgAdmin is OrgAdmin before its role checks used ?=.
+func LegacyOrgAdmin(orgField string) string {
+	return OrgMember(orgField) + " && " + legacyIsOrgAdmin
+}
+
+// LegacyWithPlatformAdmin is WithPlatformAdmin before it became an Or.
+func LegacyWithPlatformAdmin(rule string) string {
+	return fmt.Sprintf("(%s) || (%s)", PlatformAdmin, rule)
+}
+
+const (
+	legacyIsOrgAdmin = "(@collection.org_members.role = 'owner' || @collection.org_members.role = 'admin')"
+	legacyIsOrgOwner = "@collection.org_members.role = 'owner'"
+)
+
+var (
+	// LegacyDirectOrgAdmin is DirectOrgAdmin before its role checks used ?=.
+	LegacyDirectOrgAdmin = DirectOrgMember + " && " + legacyIsOrgAdmin
+
+	// LegacyDirectOrgOwner is DirectOrgOwner before its role check used ?=.
+	LegacyDirectOrgOwner = DirectOrgMember + " && " + legacyIsOrgOwner
+)
@@ -1,9 +1,9 @@
 // Package rules provides reusable PocketBase access rule strings and builders.
 // Rules are plain strings — use Ptr() when assigning to collection rule fields.
+// The builders are composed from typed expressions (see Expr), and Validate
+// checks a rule against the schema.
 package rules
 
-import "fmt"
-
 // ---- Primitives ----
 
 // Public allows anyone to access a resource with no authentication.
@@ -20,25 +20,30 @@ const SystemOnly = "" // use nil pointer on the collection field
 // PlatformAdmin matches users with the platform-level admin role.
 const PlatformAdmin = "@request.auth.role = 'admin'"
 
+var (
+	isAuth          = Neq(Auth("id"), Value(""))
+	isPlatformAdmin = Eq(Auth("role"), Value("admin"))
+)
+
 // ---- User / record ownership ----
 
 // OwnRecord returns a rule matching users who own a record via a given field.
 //
 //	OwnRecord("user")  →  "@request.auth.id = user || @request.auth.role = 'admin'"
 func OwnRecord(field string) string {
-	return fmt.Sprintf("(@request.auth.id = %s || @request.auth.role = 'admin')", field)
+	return Or(Eq(Auth("id"), Field(field)), isPlatformAdmin).String()
 }
 
 // OwnUser is a shorthand for records where the PK is the user's own ID.
 //
-//	"@request.auth.id = id || @request.auth.role = 'admin'"
-const OwnUser = "(@request.auth.id = id || @request.auth.role = 'admin')"
+//	"(@request.auth.id = id || @request.auth.role = 'admin')"
+var OwnUser = OwnRecord("id")
 
 // RecipientOnly restricts list/view to records where recipient = the caller.
 //
 //	Used for notifications and similar per-user inboxes.
 func RecipientOnly(recipientField string) string {
-	return fmt.Sprintf("@request.auth.id != '' && %s = @request.auth.id", recipientField)
+	return And(isAuth, Eq(Field(recipientField), Auth("id"))).String()
 }
 
 // ---- Org membership ----
@@ -48,41 +53,65 @@ func RecipientOnly(recipientField string) string {
 //
 //	OrgMember("organization")
 func OrgMember(orgField string) string {
-	return fmt.Sprintf(
-		"@request.auth.id != '' && %s.id ?= @collection.org_members.organization && @request.auth.id ?= @collection.org_members.user",
-		orgField,
-	)
+	return orgMember(orgField).String()
 }
 
 // OrgAdmin returns a rule allowing only org owners and admins.
 func OrgAdmin(orgField string) string {
-	return OrgMember(orgField) + " && (@collection.org_members.role = 'owner' || @collection.org_members.role = 'admin')"
+	return And(orgMember(orgField), isOrgAdmin).String()
 }
 
 // OrgOwner returns a rule allowing only the org owner.
 func OrgOwner(orgField string) string {
-	return OrgMember(orgField) + " && @collection.org_members.role = 'owner'"
+	return And(orgMember(orgField), isOrgOwner).String()
 }
 
+func orgMember(orgField string) Expr {
+	return And(
+		isAuth,
+		AnyEq(Field(orgField+".id"), Collection("org_members", "organization")),
+		AnyEq(Auth("id"), Collection("org_members", "user")),
+	)
+}
+
+// The role must be matched with ?= so it is checked on the same
+// org_members row as the org and user; = would require every joined row
+// to have the role.
+var (
+	isOrgAdmin = Or(
+		AnyEq(Collection("org_members", "role"), Value("owner")),
+		AnyEq(Collection("org_members", "role"), Value("admin")),
+	)
+	isOrgOwner = AnyEq(Collection("org_members", "role"), Value("owner"))
+)
+
 // WithPlatformAdmin wraps any rule so platform admins always bypass it.
 //
 //	WithPlatformAdmin(OrgMember("organization"))
-//	→  "(@request.auth.role = 'admin') || (<orgMemberRule>)"
+//	→  "(@request.auth.role = 'admin' || (<orgMemberRule>))"
 func WithPlatformAdmin(rule string) string {
-	return fmt.Sprintf("(%s) || (%s)", PlatformAdmin, rule)
+	return Or(isPlatformAdmin, Raw(rule)).String()
 }
 
 // ---- Direct org collection rules (for the organizations table itself) ----
 
-// DirectOrgMember matches users who are members of the org record being accessed
-// (used on the organizations collection where the PK is the org ID).
-const DirectOrgMember = "@request.auth.id != '' && @request.auth.id ?= @collection.org_members.user && id ?= @collection.org_members.organization"
+var directOrgMember = And(
+	isAuth,
+	AnyEq(Auth("id"), Collection("org_members", "user")),
+	AnyEq(Field("id"), Collection("org_members", "organization")),
+)
+
+var (
+	// DirectOrgMember matches users who are members of the org record being accessed
+	// (used on the organizations collection where the PK is the org ID).
+	DirectOrgMember = directOrgMember.String()
 
-// DirectOrgAdmin matches org owners/admins on the organizations collection.
-const DirectOrgAdmin = DirectOrgMember + " && (@collection.org_members.role = 'owner' || @collection.org_members.role = 'admin')"
+	// DirectOrgAdmin matches org owners/admins on the organizations collection.
+	DirectOrgAdmin = And(directOrgMember, isOrgAdmin).String()
 
-// DirectOrgOwner matches only the org owner on the organizations collection.
-const DirectOrgOwner = DirectOrgMember + " && @collection.org_members.role = 'owner'"
+	// DirectOrgOwner matches only the org owner on the organizations collection.
+	DirectOrgOwner = And(directOrgMember, isOrgOwner).String()
+)
 
 // ---- Helpers ----
 
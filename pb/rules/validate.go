@@ -0,0 +1,162 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ganigeorgiev/fexpr"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Validate parses rule and checks that every field it references exists:
+// record fields and @request.body on collection, @request.auth on users,
+// and @collection references on the named collection. Relations are
+// followed through dotted paths, including back-relations (x_via_field),
+// and modifiers such as :isset are ignored.
+func Validate(app core.App, collection, rule string) error {
+	if rule == "" {
+		return nil
+	}
+	col, err := app.FindCollectionByNameOrId(collection)
+	if err != nil {
+		return fmt.Errorf("collection %q not found", collection)
+	}
+	return walk(app, col, rule, nil)
+}
+
+// Fields parses filter and calls visit with every field of a record it
+// reaches from col, including the relations along dotted paths and
+// @collection references. Fields of @request data are not visited. It
+// returns the errors Validate would.
+func Fields(app core.App, col *core.Collection, filter string, visit func(col *core.Collection, field string)) error {
+	return walk(app, col, filter, visit)
+}
+
+func walk(app core.App, col *core.Collection, expr string, visit func(*core.Collection, string)) error {
+	groups, err := fexpr.Parse(expr)
+	if err != nil {
+		return fmt.Errorf("invalid rule: %w", err)
+	}
+	v := validator{app: app, col: col, visit: visit}
+	v.groups(groups)
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	app   core.App
+	col   *core.Collection
+	visit func(col *core.Collection, field string)
+	errs  []error
+}
+
+func (v *validator) groups(groups []fexpr.ExprGroup) {
+	for _, g := range groups {
+		switch item := g.Item.(type) {
+		case fexpr.Expr:
+			v.token(item.Left)
+			v.token(item.Right)
+		case fexpr.ExprGroup:
+			v.groups([]fexpr.ExprGroup{item})
+		case []fexpr.ExprGroup:
+			v.groups(item)
+		}
+	}
+}
+
+func (v *validator) token(t fexpr.Token) {
+	switch t.Type {
+	case fexpr.TokenIdentifier:
+		if err := v.identifier(t.Literal); err != nil {
+			v.errs = append(v.errs, fmt.Errorf("%s: %w", t.Literal, err))
+		}
+	case fexpr.TokenFunction:
+		args, _ := t.Meta.([]fexpr.Token)
+		for _, arg := range args {
+			v.token(arg)
+		}
+	}
+}
+
+func (v *validator) identifier(id string) error {
+	switch id {
+	case "null", "true", "false":
+		return nil
+	}
+	path := strings.Split(id, ".")
+	switch {
+	case path[0] == "@request" && len(path) > 2 && path[1] == "auth":
+		switch path[2] {
+		case "collectionId", "collectionName":
+			return nil
+		}
+		users, err := v.app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+		return v.resolve(users, path[2:], false)
+	case path[0] == "@request" && len(path) > 2 && path[1] == "body":
+		return v.resolve(v.col, path[2:], false)
+	case path[0] == "@collection" && len(path) > 2:
+		name, _, _ := strings.Cut(path[1], ":") // drop the alias
+		col, err := v.app.FindCollectionByNameOrId(name)
+		if err != nil {
+			return fmt.Errorf("collection %q not found", name)
+		}
+		return v.resolve(col, path[2:], true)
+	case strings.HasPrefix(path[0], "@"):
+		// Other request data (query, headers, method, context) and
+		// datetime macros such as @now.
+		return nil
+	}
+	return v.resolve(v.col, path, true)
+}
+
+// resolve follows path from col: every segment but the last must be a
+// relation, back-relation or JSON field. With visit set, the fields along
+// the way are passed to v.visit.
+func (v *validator) resolve(col *core.Collection, path []string, visit bool) error {
+	visit = visit && v.visit != nil
+	for i, segment := range path {
+		name, _, _ := strings.Cut(segment, ":")
+		if source, field, ok := strings.Cut(name, "_via_"); ok {
+			back, err := v.app.FindCollectionByNameOrId(source)
+			if err != nil {
+				return fmt.Errorf("collection %q not found", source)
+			}
+			rel, ok := back.Fields.GetByName(field).(*core.RelationField)
+			if !ok || rel.CollectionId != col.Id {
+				return fmt.Errorf("%s.%s is not a relation to %s", source, field, col.Name)
+			}
+			if visit {
+				v.visit(back, field)
+			}
+			col = back
+			continue
+		}
+
+		f := col.Fields.GetByName(name)
+		if f == nil {
+			return fmt.Errorf("%s has no field %q", col.Name, name)
+		}
+		if visit {
+			v.visit(col, name)
+		}
+		if i == len(path)-1 {
+			return nil
+		}
+		switch f := f.(type) {
+		case *core.RelationField:
+			target, err := v.app.FindCollectionByNameOrId(f.CollectionId)
+			if err != nil {
+				return fmt.Errorf("%s.%s targets a missing collection", col.Name, name)
+			}
+			col = target
+		case *core.JSONField:
+			return nil
+		default:
+			return fmt.Errorf("%s.%s is not a relation", col.Name, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/server/middleware"
+)
+
+// BindDrift registers the schema drift report endpoints.
+func BindDrift(app core.App) {
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// GET /api/admin/drift - requires superuser auth
+		e.Router.GET("/api/admin/drift", func(re *core.RequestEvent) error {
+			drift, err := patch.DetectDrift(re.App, patch.Specs())
+			if err != nil {
+				return re.JSON(500, map[string]any{
+					"error":   "drift report failed",
+					"message": err.Error(),
+				})
+			}
+			return re.JSON(200, map[string]any{
+				"drift": nonNil(drift),
+			})
+		}).Bind(middleware.RequireSuperuser())
+
+		// POST /api/admin/drift/fix - requires superuser auth
+		e.Router.POST("/api/admin/drift/fix", func(re *core.RequestEvent) error {
+			fixed, err := patch.FixDrift(re.App, patch.Specs())
+			if err != nil {
+				return re.JSON(400, map[string]any{
+					"error":   "drift fix failed",
+					"message": err.Error(),
+				})
+			}
+			drift, err := patch.DetectDrift(re.App, patch.Specs())
+			if err != nil {
+				return re.JSON(500, map[string]any{
+					"error":   "drift report failed",
+					"message": err.Error(),
+				})
+			}
+			return re.JSON(200, map[string]any{
+				"fixed": nonNil(fixed),
+				"drift": nonNil(drift),
+			})
+		}).Bind(middleware.RequireSuperuser())
+
+		return e.Next()
+	})
+}
+
+// nonNil makes an empty report encode as [] rather than null.
+func nonNil(drift []patch.Drift) []patch.Drift {
+	if drift == nil {
+		return []patch.Drift{}
+	}
+	return drift
+}
@@ -3,14 +3,22 @@ package admin
 import (
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/database"
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/server/middleware"
 )
 
+// Sync runs and reports libSQL replica syncs.
 type Sync interface {
 	Sync() error
+	SyncAsync() bool
+	SyncStatus() database.SyncStatus
 }
 
 func RedirectAdminUI(app *pocketbase.PocketBase) {
@@ -28,38 +36,89 @@ func RedirectAdminUI(app *pocketbase.PocketBase) {
 	)
 }
 
-func BindSyncFunc(app *pocketbase.PocketBase, s Sync) {
+func BindSyncFunc(app core.App, s Sync) {
 	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		// POST /api/admin/sync - requires superuser auth
+		// POST /api/admin/sync[?async=true] - requires superuser auth
 		e.Router.POST("/api/admin/sync", func(re *core.RequestEvent) error {
-			// Check if user is authenticated as superuser
-			if re.Auth == nil || re.Auth.Collection().Name != "_superusers" {
-				return re.JSON(401, map[string]any{
-					"error": "unauthorized",
+			if async, _ := strconv.ParseBool(re.Request.URL.Query().Get("async")); async {
+				if !s.SyncAsync() {
+					return re.JSON(409, map[string]any{
+						"error": "a sync is already running or there is nothing to sync",
+					})
+				}
+				logging.FromContext(re.Request.Context()).Info().Msg("manual sync started")
+				return re.JSON(202, map[string]any{
+					"success": true,
+					"message": "sync started, see GET /api/admin/sync/status",
 				})
 			}
 
 			start := time.Now()
 			if err := s.Sync(); err != nil {
-				log.Printf("Manual sync failed: %v", err)
+				logging.FromContext(re.Request.Context()).Error().Err(err).Msg("manual sync failed")
 				return re.JSON(500, map[string]any{
 					"error":   "sync failed",
 					"message": err.Error(),
 				})
 			}
 			duration := time.Since(start)
-			log.Printf("Manual sync completed in %v", duration)
+			status := s.SyncStatus()
+			logging.FromContext(re.Request.Context()).Info().Dur("duration", duration).Msg("manual sync completed")
 			return re.JSON(200, map[string]any{
-				"success":  true,
-				"message":  "sync completed",
-				"duration": duration.String(),
+				"success":       true,
+				"message":       "sync completed",
+				"duration":      duration.String(),
+				"frames_synced": status.FramesSynced,
+				"frame_no":      status.FrameNo,
 			})
-		})
+		}).Bind(middleware.RequireSuperuser())
+
+		// GET /api/admin/sync/status - recent syncs, requires superuser auth
+		e.Router.GET("/api/admin/sync/status", func(re *core.RequestEvent) error {
+			return re.JSON(200, syncStatusJSON(s.SyncStatus()))
+		}).Bind(middleware.RequireSuperuser())
 
 		return e.Next()
 	})
 }
 
+func syncStatusJSON(status database.SyncStatus) map[string]any {
+	lastError := ""
+	if status.LastError != nil {
+		lastError = status.LastError.Error()
+	}
+
+	history := make([]map[string]any, len(status.History))
+	for i, h := range status.History {
+		history[i] = map[string]any{
+			"started":       h.Started,
+			"duration":      h.Duration.String(),
+			"frames_synced": h.FramesSynced,
+			"frame_no":      h.FrameNo,
+			"error":         h.Error,
+		}
+	}
+
+	return map[string]any{
+		"running":              status.Running,
+		"last_attempt":         formatTime(status.LastAttempt),
+		"last_success":         formatTime(status.LastSuccess),
+		"last_error":           lastError,
+		"last_duration":        status.LastDuration.String(),
+		"frames_synced":        status.FramesSynced,
+		"frame_no":             status.FrameNo,
+		"consecutive_failures": status.ConsecutiveFailures,
+		"history":              history,
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
 // EnsureAdmin upserts a superuser with the given email and password.
 // If a superuser with that email already exists, its password is updated.
 // Uses OnServe because DB must be initialized before we can query records.
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/internal/transfer"
+	"pocketbase-server/server/middleware"
+)
+
+// importBodyLimit caps uploaded imports; use the import command for
+// larger ones.
+const importBodyLimit = 256 << 20
+
+// BindTransfer registers the JSONL export and import endpoints.
+func BindTransfer(app core.App) {
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		// GET /api/admin/export[?organization=ID] - requires superuser auth
+		e.Router.GET("/api/admin/export", func(re *core.RequestEvent) error {
+			opts := transfer.Options{Organization: re.Request.URL.Query().Get("organization")}
+
+			// Export reads everything before writing, so failures still
+			// get a JSON error response.
+			var buf bytes.Buffer
+			if _, err := transfer.Export(re.Request.Context(), re.App, &buf, opts); err != nil {
+				return re.JSON(400, map[string]any{
+					"error":   "export failed",
+					"message": err.Error(),
+				})
+			}
+
+			name := fmt.Sprintf("export-%s.jsonl", time.Now().UTC().Format("20060102T150405Z"))
+			re.Response.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+			return re.Blob(200, "application/x-ndjson", buf.Bytes())
+		}).Bind(middleware.RequireSuperuser())
+
+		// POST /api/admin/import[?organization=ID&remap=true&dry_run=true]
+		// with an export as the body - requires superuser auth
+		e.Router.POST("/api/admin/import", func(re *core.RequestEvent) error {
+			q := re.Request.URL.Query()
+			opts := transfer.Options{Organization: q.Get("organization")}
+			opts.RemapIds, _ = strconv.ParseBool(q.Get("remap"))
+			opts.DryRun, _ = strconv.ParseBool(q.Get("dry_run"))
+
+			// PocketBase's rereadable body starts over after EOF, which the
+			// streaming decoder would read as a second export.
+			body, err := io.ReadAll(re.Request.Body)
+			if err != nil {
+				return re.JSON(400, map[string]any{
+					"error":   "import failed",
+					"message": err.Error(),
+				})
+			}
+
+			summary, err := transfer.Import(re.Request.Context(), re.App, bytes.NewReader(body), opts)
+			if err != nil {
+				logging.FromContext(re.Request.Context()).Warn().Err(err).Msg("import failed")
+				return re.JSON(400, map[string]any{
+					"error":   "import failed",
+					"message": err.Error(),
+				})
+			}
+			return re.JSON(200, map[string]any{
+				"success":     true,
+				"dry_run":     opts.DryRun,
+				"collections": summary.Collections,
+			})
+		}).Bind(middleware.RequireSuperuser(), apis.BodyLimit(importBodyLimit))
+
+		return e.Next()
+	})
+}
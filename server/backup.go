@@ -0,0 +1,93 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/hook"
+
+	"pocketbase-server/internal/backup"
+	"pocketbase-server/internal/cronjobs"
+	"pocketbase-server/internal/database"
+)
+
+// instanceLockHookId identifies the data directory lock handlers.
+const instanceLockHookId = "serverInstanceLock"
+
+// Backups returns the backup manager for the configured target.
+func (s *Server) Backups() *backup.Manager {
+	return s.backups
+}
+
+func (s *Server) newBackupManager() *backup.Manager {
+	cfg := backup.Config{
+		App:             s.app,
+		Store:           s.backupStore,
+		SnapshotDataDB:  s.snapshotDataDB,
+		DataDBEncrypted: s.conn.Encrypted(),
+		Retention: backup.Retention{
+			KeepLast: s.cfg.Backup.KeepLast,
+			MaxAge:   s.cfg.Backup.MaxAge,
+		},
+	}
+	if s.cfg.Backup.Target == "s3" {
+		cfg.Prefix = "backups/"
+	}
+	return backup.New(cfg)
+}
+
+func (s *Server) backupStore() (*filesystem.System, error) {
+	if s.cfg.Backup.Target == "s3" {
+		c := s.cfg.S3
+		return filesystem.NewS3(c.Bucket, c.Region, c.Endpoint, c.AccessKey, c.Secret, c.ForcePathStyle)
+	}
+	dir, err := filepath.Abs(s.cfg.Backup.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.NewLocal(dir)
+}
+
+// snapshotDataDB copies data.db through the libSQL connection; PocketBase
+// only sees it as an opaque *sql.DB.
+func (s *Server) snapshotDataDB(dst string) error {
+	err := s.conn.Snapshot(dst)
+	if errors.Is(err, database.ErrNoLocalCopy) {
+		return backup.Skip("remote mode, back up the primary instead")
+	}
+	return err
+}
+
+// bindBackups holds the data directory lock while serving, so restores
+// refuse to run against a live instance, and schedules backups.
+func (s *Server) bindBackups() {
+	var unlock func()
+
+	s.app.OnServe().Bind(&hook.Handler[*core.ServeEvent]{
+		Id: instanceLockHookId,
+		Func: func(e *core.ServeEvent) error {
+			var err error
+			if unlock, err = backup.LockInstance(s.cfg.Server.DataDir); err != nil {
+				return fmt.Errorf("failed to lock %s: %w", s.cfg.Server.DataDir, err)
+			}
+			return e.Next()
+		},
+	})
+	s.app.OnTerminate().Bind(&hook.Handler[*core.TerminateEvent]{
+		Id: instanceLockHookId,
+		Func: func(e *core.TerminateEvent) error {
+			err := e.Next()
+			if unlock != nil {
+				unlock()
+			}
+			return err
+		},
+	})
+
+	if s.cfg.Backup.Schedule != "" {
+		cronjobs.RegisterBackups(s.app, s.backups, s.cfg.Backup.Schedule)
+	}
+}
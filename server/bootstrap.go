@@ -0,0 +1,155 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/pb/collections/auth"
+	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/collections/registry"
+	"pocketbase-server/pb/collections/visibility"
+
+	// Collection packages register themselves with the registry.
+	_ "pocketbase-server/pb/collections/notifications"
+	_ "pocketbase-server/pb/collections/organizations"
+	_ "pocketbase-server/pb/collections/photos"
+	_ "pocketbase-server/pb/collections/realestate"
+	_ "pocketbase-server/pb/collections/users"
+)
+
+// setupStep is a single collection or settings bootstrap step.
+type setupStep struct {
+	name string
+	run  func(app core.App) error
+	// critical steps abort serve on failure; the others are logged
+	// (rules may legitimately fail on a half-migrated schema).
+	critical bool
+}
+
+// collectionSteps returns the collection bootstrap steps: the setup of
+// every registered collection in dependency order, pending migrations,
+// rule and field visibility validation, then the collections' access rules.
+func collectionSteps() ([]setupStep, error) {
+	collections, err := registry.Collections()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []setupStep
+	for _, c := range collections {
+		steps = append(steps, setupStep{c.Name, c.Setup, true})
+	}
+
+	// Pending schema migrations (all collections now exist)
+	steps = append(steps, setupStep{"migrations", migrate, true})
+
+	// The fields the code's rules reference must exist before they apply
+	steps = append(steps, setupStep{"rule validation", func(app core.App) error {
+		return patch.ValidateRules(app, patch.Specs())
+	}, true})
+	steps = append(steps, setupStep{"field visibility validation", visibility.Validate, true})
+
+	// Access rules can refer to any collection
+	for _, c := range collections {
+		if c.Rules != nil {
+			steps = append(steps, setupStep{c.Name + " rules", c.Rules, false})
+		}
+	}
+	return steps, nil
+}
+
+// setupSteps returns the collection steps followed by the settings steps.
+func (s *Server) setupSteps() ([]setupStep, error) {
+	steps, err := collectionSteps()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(steps,
+		setupStep{"oauth2 providers", func(app core.App) error {
+			return auth.EnsureOAuth2Providers(app, s.cfg.OAuth2)
+		}, false},
+
+		// S3 file storage (only applied if S3_BUCKET is set)
+		setupStep{"s3 storage", s.applyS3Settings, true},
+	), nil
+}
+
+// Bootstrap runs every setup step against app. Critical failures abort
+// immediately; with strict set, non-critical failures are also returned
+// (after the remaining steps have run).
+func (s *Server) Bootstrap(app core.App, strict bool) error {
+	steps, err := s.setupSteps()
+	if err != nil {
+		return fmt.Errorf("bootstrap: %w", err)
+	}
+	return runSteps(app, steps, strict)
+}
+
+// BootstrapCollections runs only the collection steps of Bootstrap, for
+// apps without a Server such as tests. strict is as for Bootstrap.
+func BootstrapCollections(app core.App, strict bool) error {
+	steps, err := collectionSteps()
+	if err != nil {
+		return fmt.Errorf("bootstrap: %w", err)
+	}
+	return runSteps(app, steps, strict)
+}
+
+func runSteps(app core.App, steps []setupStep, strict bool) error {
+	var errs []error
+	for _, step := range steps {
+		err := step.run(app)
+		if err == nil {
+			continue
+		}
+		if step.critical {
+			return fmt.Errorf("bootstrap %s: %w", step.name, err)
+		}
+		logging.Errorf(err, "bootstrap %s failed", step.name)
+		if strict {
+			errs = append(errs, fmt.Errorf("bootstrap %s: %w", step.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// migrate applies the pending migrations of the patch ledger.
+func migrate(app core.App) error {
+	results, err := patch.Migrations().Up(app, false)
+	for _, r := range results {
+		logging.Infof("migration %s applied: %s", r.Id, describeChanges(r.Changes))
+	}
+	return err
+}
+
+func describeChanges(changes []string) string {
+	if len(changes) == 0 {
+		return "no schema changes"
+	}
+	return strings.Join(changes, "; ")
+}
+
+func (s *Server) applyS3Settings(app core.App) error {
+	cfg := s.cfg.S3
+	if cfg.Bucket == "" {
+		return nil
+	}
+
+	settings := app.Settings()
+	settings.S3.Enabled = true
+	settings.S3.Bucket = cfg.Bucket
+	settings.S3.Region = cfg.Region
+	settings.S3.Endpoint = cfg.Endpoint
+	settings.S3.AccessKey = cfg.AccessKey
+	settings.S3.Secret = cfg.Secret
+	settings.S3.ForcePathStyle = cfg.ForcePathStyle
+	if err := app.Save(settings); err != nil {
+		return fmt.Errorf("failed to configure S3: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,681 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/cmd"
+	"github.com/spf13/cobra"
+
+	"pocketbase-server/internal/backup"
+	"pocketbase-server/internal/config"
+	"pocketbase-server/internal/database"
+	"pocketbase-server/internal/transfer"
+	"pocketbase-server/pb"
+	"pocketbase-server/pb/collections/patch"
+)
+
+// Exit codes reported by ExitCode for use in deploy scripts.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+// ExitCode returns the process exit code for the command run by Start.
+func (s *Server) ExitCode() int {
+	return s.exitCode
+}
+
+// fail records err as the command result. The first failure wins.
+func (s *Server) fail(code int, err error) error {
+	if s.exitCode == ExitOK {
+		s.exitCode = code
+	}
+	return err
+}
+
+// run wraps a command action so its error is reflected in ExitCode;
+// PocketBase's Execute discards the cobra error.
+func (s *Server) run(fn func(c *cobra.Command, args []string) error) func(c *cobra.Command, args []string) error {
+	return func(c *cobra.Command, args []string) error {
+		if err := fn(c, args); err != nil {
+			return s.fail(ExitFailure, err)
+		}
+		return nil
+	}
+}
+
+// registerCommands adds the PocketBase system commands and the service
+// operational commands to the root command.
+func (s *Server) registerCommands() {
+	root := s.app.RootCmd
+	root.SilenceUsage = true
+	root.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return s.fail(ExitUsage, err)
+	})
+
+	serve := cmd.NewServeCommand(s.app, true)
+	if f := serve.PersistentFlags().Lookup("http"); f != nil {
+		f.DefValue = s.cfg.Server.Addr
+		_ = f.Value.Set(s.cfg.Server.Addr)
+	}
+	serve.RunE = s.run(serve.RunE)
+
+	root.AddCommand(
+		serve,
+		cmd.NewSuperuserCommand(s.app),
+		s.newBootstrapCommand(),
+		s.newSyncCommand(),
+		s.newCreateAdminCommand(),
+		s.newSeedCommand(),
+		s.newRotateKeyCommand(),
+		s.newBackupCommand(),
+		s.newExportCommand(),
+		s.newImportCommand(),
+		s.newMigrationsCommand(),
+		s.newDriftCommand(),
+	)
+}
+
+func (s *Server) newBootstrapCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Create/patch all collections, apply access rules and exit",
+		Args:  cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			if err := s.app.RunAllMigrations(); err != nil {
+				return fmt.Errorf("failed to run migrations: %w", err)
+			}
+			if err := s.Bootstrap(s.app, true); err != nil {
+				return err
+			}
+			fmt.Fprintln(c.OutOrStdout(), "bootstrap completed")
+			return nil
+		}),
+	}
+}
+
+func (s *Server) newSyncCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Force a libSQL embedded-replica sync with the primary",
+		Args:  cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			if !s.conn.Syncs() {
+				fmt.Fprintf(c.OutOrStdout(), "nothing to sync in %s mode\n", s.conn.Mode())
+				return nil
+			}
+			start := time.Now()
+			if err := s.Sync(); err != nil {
+				return fmt.Errorf("sync failed: %w", err)
+			}
+			fmt.Fprintf(c.OutOrStdout(), "sync completed in %v\n", time.Since(start))
+			return nil
+		}),
+	}
+}
+
+func (s *Server) newCreateAdminCommand() *cobra.Command {
+	var email, password string
+	var ifMissing bool
+
+	command := &cobra.Command{
+		Use:   "create-admin",
+		Short: "Create a superuser (defaults to PB_ADMIN_EMAIL/PB_ADMIN_PASS)",
+		Args:  cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			if email == "" || password == "" {
+				return s.fail(ExitUsage, errors.New("--email and --password are required"))
+			}
+
+			if ifMissing {
+				record, err := pb.EnsureAdmin(s.app, email, password)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(c.OutOrStdout(), "superuser %s ready (id %s)\n", email, record.Id)
+				return nil
+			}
+
+			if exists, _ := pb.AdminExists(s.app, email); exists {
+				return fmt.Errorf("superuser %s already exists", email)
+			}
+			record, err := pb.CreateAdmin(s.app, email, password)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(c.OutOrStdout(), "created superuser %s (id %s)\n", email, record.Id)
+			return nil
+		}),
+	}
+
+	command.Flags().StringVar(&email, "email", s.cfg.Admin.Email, "superuser email")
+	command.Flags().StringVar(&password, "password", s.cfg.Admin.Pass, "superuser password")
+	command.Flags().BoolVar(&ifMissing, "if-missing", false, "succeed without changes when the superuser already exists")
+
+	return command
+}
+
+func (s *Server) newSeedCommand() *cobra.Command {
+	var file string
+
+	command := &cobra.Command{
+		Use:   "seed",
+		Short: "Load records from a JSON seed file",
+		Long: `Load records from a JSON seed file in a single transaction.
+
+The file is a list of collection batches applied in order:
+
+  [
+    {"collection": "users", "records": [{"id": "...", "email": "...", "password": "..."}]},
+    {"collection": "properties", "records": [{"organization": "...", "property_name": "..."}]}
+  ]
+
+Records with an id that already exists are skipped.`,
+		Args: cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			if file == "" {
+				return s.fail(ExitUsage, errors.New("--file is required"))
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			created, skipped, err := Seed(s.app, data)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(c.OutOrStdout(), "seed completed: %d created, %d skipped\n", created, skipped)
+			return nil
+		}),
+	}
+
+	command.Flags().StringVarP(&file, "file", "f", "", "path to the JSON seed file")
+
+	return command
+}
+
+func (s *Server) newRotateKeyCommand() *cobra.Command {
+	var newKey, newKeyFile string
+
+	command := &cobra.Command{
+		Use:   "rotate-key",
+		Short: "Re-encrypt the local libSQL replica with a new key",
+		Long: `Re-encrypt the local libSQL replica with a new key.
+
+The current key comes from LIBSQL_ENCRYPTION_KEY or LIBSQL_ENCRYPTION_KEY_FILE
+(leave both unset to encrypt a plaintext replica). The replica is synced,
+moved aside and pulled again from the primary encrypted with the new key; the
+old files are restored on failure. Stop the server first, and update the key
+configuration before starting it again.`,
+		Args: cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			if (newKey == "") == (newKeyFile == "") {
+				return s.fail(ExitUsage, errors.New("exactly one of --new-key and --new-key-file is required"))
+			}
+			if newKeyFile != "" {
+				var err error
+				if newKey, err = (config.LibSQLConfig{EncryptionKeyFile: newKeyFile}).ResolveEncryptionKey(); err != nil {
+					return s.fail(ExitUsage, err)
+				}
+			}
+
+			dbcfg, err := libSQLConfig(s.cfg)
+			if err != nil {
+				return err
+			}
+			if dbcfg.Mode != database.ModeReplica {
+				return s.fail(ExitUsage, fmt.Errorf("rotate-key needs replica mode, not %s", dbcfg.Mode))
+			}
+
+			// Release the replica opened at startup before replacing its files.
+			if err := s.app.ResetBootstrapState(); err != nil {
+				return err
+			}
+			if err := s.conn.Close(); err != nil {
+				return err
+			}
+
+			if err := database.RotateKey(*dbcfg, newKey); err != nil {
+				return err
+			}
+			fmt.Fprintln(c.OutOrStdout(), "replica re-encrypted; set the new key in LIBSQL_ENCRYPTION_KEY or LIBSQL_ENCRYPTION_KEY_FILE before restarting")
+			return nil
+		}),
+	}
+
+	command.Flags().StringVar(&newKey, "new-key", "", "new encryption key")
+	command.Flags().StringVar(&newKeyFile, "new-key-file", "", "file holding the new encryption key")
+
+	return command
+}
+
+func (s *Server) newBackupCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "backup",
+		Short: "Create, list, verify and restore backups",
+		Args:  cobra.NoArgs,
+	}
+
+	command.AddCommand(
+		&cobra.Command{
+			Use:   "create",
+			Short: "Back up data.db, auxiliary.db and uploaded files now",
+			Args:  cobra.NoArgs,
+			RunE: s.run(func(c *cobra.Command, args []string) error {
+				info, err := s.backups.Create(c.Context())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(c.OutOrStdout(), "backup %s created (%d bytes)\n", info.Id, info.Size)
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "list",
+			Short: "List backups, newest first",
+			Args:  cobra.NoArgs,
+			RunE: s.run(func(c *cobra.Command, args []string) error {
+				backups, err := s.backups.List(c.Context())
+				if err != nil {
+					return err
+				}
+				for _, b := range backups {
+					fmt.Fprintf(c.OutOrStdout(), "%s\t%s\t%d\n", b.Id, b.Created.Format(time.RFC3339), b.Size)
+				}
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "verify <id>",
+			Short: "Check a backup's checksums and database integrity",
+			Args:  cobra.ExactArgs(1),
+			RunE: s.run(func(c *cobra.Command, args []string) error {
+				manifest, err := s.backups.Verify(c.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(c.OutOrStdout(), "backup %s is valid (%d files)\n", manifest.Id, len(manifest.Files))
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "prune",
+			Short: "Delete the backups the retention rules don't keep",
+			Args:  cobra.NoArgs,
+			RunE: s.run(func(c *cobra.Command, args []string) error {
+				deleted, err := s.backups.Prune(c.Context())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(c.OutOrStdout(), "%d backups deleted\n", len(deleted))
+				return nil
+			}),
+		},
+		s.newRestoreCommand(),
+	)
+
+	return command
+}
+
+func (s *Server) newRestoreCommand() *cobra.Command {
+	var at string
+
+	command := &cobra.Command{
+		Use:   "restore [<id>]",
+		Short: "Restore a backup over the data directory",
+		Long: `Restore a backup over the data directory.
+
+Pass a backup id, or --at to pick the latest backup taken at or before an
+RFC 3339 time. The backup is verified before anything is replaced. data.db is
+only restored in local mode; in replica and remote modes it lives on the
+libSQL primary, so only auxiliary.db and the uploaded files are restored.
+The server must be stopped: restore refuses to run while an instance holds
+the data directory.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			if (len(args) == 1) == (at != "") {
+				return s.fail(ExitUsage, errors.New("pass either a backup id or --at"))
+			}
+
+			id := ""
+			if len(args) == 1 {
+				id = args[0]
+			} else {
+				t, err := time.Parse(time.RFC3339, at)
+				if err != nil {
+					return s.fail(ExitUsage, fmt.Errorf("invalid --at: %w", err))
+				}
+				info, err := s.backups.Find(c.Context(), t)
+				if err != nil {
+					return err
+				}
+				id = info.Id
+			}
+
+			unlock, err := backup.LockInstance(s.cfg.Server.DataDir)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			dir, err := os.MkdirTemp("", "restore-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+
+			if _, err := s.backups.Extract(c.Context(), id, dir); err != nil {
+				return err
+			}
+
+			// Release the databases opened at startup before replacing them.
+			if err := s.app.ResetBootstrapState(); err != nil {
+				return err
+			}
+			if err := s.conn.Close(); err != nil {
+				return err
+			}
+
+			restored, err := backup.RestoreDatabases(dir, s.cfg.Server.DataDir, s.conn.Mode() == database.ModeLocal)
+			if err != nil {
+				return fmt.Errorf("restore failed after %v: %w", restored, err)
+			}
+
+			if err := s.conn.Reopen(); err != nil {
+				return err
+			}
+			if err := s.app.Bootstrap(); err != nil {
+				return err
+			}
+
+			files, err := backup.RestoreFiles(c.Context(), s.app, dir)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(c.OutOrStdout(), "backup %s restored: %s and %d files\n", id, strings.Join(restored, ", "), files)
+			if s.conn.Mode() != database.ModeLocal {
+				fmt.Fprintf(c.OutOrStdout(), "data.db was not restored in %s mode; restore it on the libSQL primary\n", s.conn.Mode())
+			}
+			return nil
+		}),
+	}
+
+	command.Flags().StringVar(&at, "at", "", "restore the latest backup at or before this RFC 3339 time")
+
+	return command
+}
+
+func (s *Server) newExportCommand() *cobra.Command {
+	var file string
+	var opts transfer.Options
+
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export every collection as JSONL",
+		Long: `Export every collection as JSONL, with schema metadata, in dependency order.
+
+Writes to stdout unless --file is given. With --organization only that
+organization's data is exported, along with the users and other records it
+references. Uploaded files are not included.`,
+		Args: cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			out := c.OutOrStdout()
+			if file != "" && file != "-" {
+				f, err := os.Create(file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			summary, err := transfer.Export(c.Context(), s.app, out, opts)
+			if err != nil {
+				return err
+			}
+			if file != "" && file != "-" {
+				printTransferSummary(c, summary)
+			}
+			return nil
+		}),
+	}
+
+	command.Flags().StringVarP(&file, "file", "f", "", "output file (default stdout)")
+	command.Flags().StringVar(&opts.Organization, "organization", "", "only export this organization id")
+
+	return command
+}
+
+func (s *Server) newImportCommand() *cobra.Command {
+	var file string
+	var opts transfer.Options
+
+	command := &cobra.Command{
+		Use:   "import",
+		Short: "Import a JSONL export in one transaction",
+		Long: `Import a JSONL export in one transaction.
+
+Run bootstrap first: the collections must exist. Records keep their ids and
+overwrite existing ones unless --remap-ids gives them new ids, with relations
+rewritten to match. Records are written as is, without record hooks, and the
+import fails when a relation would point at a missing record.`,
+		Args: cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			if file == "" {
+				return s.fail(ExitUsage, errors.New("--file is required"))
+			}
+
+			in := c.InOrStdin()
+			if file != "-" {
+				f, err := os.Open(file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+
+			summary, err := transfer.Import(c.Context(), s.app, in, opts)
+			if err != nil {
+				return err
+			}
+			printTransferSummary(c, summary)
+			if opts.DryRun {
+				fmt.Fprintln(c.OutOrStdout(), "dry run: nothing was written")
+			}
+			return nil
+		}),
+	}
+
+	command.Flags().StringVarP(&file, "file", "f", "", "export to import (- for stdin)")
+	command.Flags().StringVar(&opts.Organization, "organization", "", "only import this organization id")
+	command.Flags().BoolVar(&opts.RemapIds, "remap-ids", false, "give imported records new ids")
+	command.Flags().BoolVar(&opts.DryRun, "dry-run", false, "roll back instead of committing")
+
+	return command
+}
+
+func printTransferSummary(c *cobra.Command, summary *transfer.Summary) {
+	for _, col := range summary.Collections {
+		line := fmt.Sprintf("%s\t%d records", col.Name, col.Records)
+		if col.Created+col.Updated > 0 {
+			line += fmt.Sprintf(", %d created, %d updated", col.Created, col.Updated)
+		}
+		if len(col.SkippedFields) > 0 {
+			line += ", skipped fields: " + strings.Join(col.SkippedFields, ", ")
+		}
+		fmt.Fprintln(c.OutOrStdout(), line)
+	}
+}
+
+func (s *Server) newMigrationsCommand() *cobra.Command {
+	var dryRun bool
+	var steps int
+
+	command := &cobra.Command{
+		Use:   "migrations",
+		Short: "List, apply and revert schema migrations",
+		Long: `List, apply and revert the schema migrations recorded in the
+` + patch.LedgerTable + ` table. Pending migrations are also applied by bootstrap
+and on serve, after the collections are created.`,
+		Args: cobra.NoArgs,
+	}
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "List applied and pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			statuses, err := patch.Migrations().Status(s.app)
+			if err != nil {
+				return err
+			}
+			for _, m := range statuses {
+				state := "pending"
+				switch {
+				case m.Unknown:
+					state = "applied (not registered)"
+				case m.Applied:
+					state = "applied " + m.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(c.OutOrStdout(), "%s\t%s\t%s\n", m.Id, state, m.Description)
+			}
+			return nil
+		}),
+	}
+
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "Apply the pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			results, err := patch.Migrations().Up(s.app, dryRun)
+			if err == nil || len(results) > 0 {
+				printMigrations(c, results, dryRun, "apply", "applied")
+			}
+			return err
+		}),
+	}
+	up.Flags().BoolVar(&dryRun, "dry-run", false, "print the planned changes and roll back")
+
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the most recently applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			if steps < 1 {
+				return s.fail(ExitUsage, errors.New("--steps must be at least 1"))
+			}
+			results, err := patch.Migrations().Down(s.app, steps, dryRun)
+			if err == nil || len(results) > 0 {
+				printMigrations(c, results, dryRun, "revert", "reverted")
+			}
+			return err
+		}),
+	}
+	down.Flags().BoolVar(&dryRun, "dry-run", false, "print the planned changes and roll back")
+	down.Flags().IntVar(&steps, "steps", 1, "number of migrations to revert")
+
+	command.AddCommand(status, up, down)
+	return command
+}
+
+func printMigrations(c *cobra.Command, results []patch.Result, dryRun bool, verb, past string) {
+	out := c.OutOrStdout()
+	if len(results) == 0 {
+		fmt.Fprintf(out, "nothing to %s\n", verb)
+		return
+	}
+	for _, r := range results {
+		if dryRun {
+			fmt.Fprintf(out, "would %s %s\n", verb, r.Id)
+		} else {
+			fmt.Fprintf(out, "%s %s\n", past, r.Id)
+		}
+		for _, change := range r.Changes {
+			fmt.Fprintf(out, "  %s\n", change)
+		}
+	}
+}
+
+func (s *Server) newDriftCommand() *cobra.Command {
+	var fix bool
+
+	command := &cobra.Command{
+		Use:   "drift",
+		Short: "Compare the live collections with the code",
+		Long: `Compare each live collection's fields, indexes and rules with the collection
+specs and the rules the code applies. Differences are missing (declared but not
+in the database), extra (in the database only) or changed. Exits with 1 while
+any remain.
+
+--fix makes the database match the code, except extra fields, indexes and
+collections, and fields whose type changed.`,
+		Args: cobra.NoArgs,
+		RunE: s.run(func(c *cobra.Command, args []string) error {
+			out := c.OutOrStdout()
+			if fix {
+				fixed, err := patch.FixDrift(s.app, patch.Specs())
+				if err != nil {
+					return err
+				}
+				for _, d := range fixed {
+					fmt.Fprintf(out, "fixed %s\n", d)
+				}
+			}
+
+			drift, err := patch.DetectDrift(s.app, patch.Specs())
+			if err != nil {
+				return err
+			}
+			if len(drift) == 0 {
+				fmt.Fprintln(out, "no drift")
+				return nil
+			}
+			for _, d := range drift {
+				fmt.Fprintln(out, d)
+				if d.Status == patch.DriftChanged {
+					fmt.Fprintf(out, "  code: %s\n  live: %s\n", d.Expected, d.Actual)
+				}
+			}
+			return fmt.Errorf("schema drift: %d differences", len(drift))
+		}),
+	}
+	command.Flags().BoolVar(&fix, "fix", false, "make the database match the code where possible")
+	return command
+}
+
+// defaultToServe inserts the serve command when args name no subcommand,
+// so `./server` and `./server --dev` keep starting the web server.
+func (s *Server) defaultToServe() error {
+	root := s.app.RootCmd
+	args := os.Args[1:]
+
+	found, _, err := root.Find(args)
+	if err != nil {
+		return s.fail(ExitUsage, err)
+	}
+	if found == root && !containsAny(args, "-h", "--help", "-v", "--version") {
+		os.Args = append([]string{os.Args[0], "serve"}, args...)
+	}
+	return nil
+}
+
+func containsAny(args []string, values ...string) bool {
+	for _, a := range args {
+		for _, v := range values {
+			if strings.EqualFold(a, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	"pocketbase-server/pb"
+)
+
+// Handler returns the fully configured app (collections, hooks, custom
+// routes and services) as an http.Handler for embedding in another Go
+// HTTP server under prefix, e.g.:
+//
+//	h, err := srv.Handler("/pb")
+//	mux.Handle("/pb/", h)
+//	defer srv.Shutdown()
+//
+// The host keeps the listener and os.Args; Start must not be called.
+// allowedOrigins are the CORS origins, "*" when empty.
+func (s *Server) Handler(prefix string, allowedOrigins ...string) (http.Handler, error) {
+	return pb.Handler(s.app, prefix, allowedOrigins...)
+}
+
+// Shutdown stops an embedded server: cron is stopped, a final libSQL
+// sync runs and the connector is closed. Call it after the host server
+// has stopped serving the Handler.
+func (s *Server) Shutdown() error {
+	return pb.Shutdown(s.app)
+}
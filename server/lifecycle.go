@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+
+	"pocketbase-server/internal/database"
+	"pocketbase-server/internal/logging"
+)
+
+// lifecycleHookId identifies the shutdown handler on app.OnTerminate().
+const lifecycleHookId = "serverGracefulShutdown"
+
+// Lifecycle coordinates an orderly shutdown of the server:
+//  1. stop accepting new requests and drain in-flight handlers (up to Timeout)
+//  2. stop the cron scheduler and the scheduled libSQL syncs
+//  3. run a final libSQL sync so embedded-replica writes reach the primary
+//  4. close the libSQL connector once PocketBase has released its DB handles
+//
+// PocketBase already traps SIGINT/SIGTERM and triggers OnTerminate, so the
+// lifecycle hooks in there instead of installing its own signal handler.
+type Lifecycle struct {
+	app     *pocketbase.PocketBase
+	conn    *database.LibSQLConnection
+	syncer  *database.Syncer
+	timeout time.Duration
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+// NewLifecycle creates a Lifecycle for app, conn and its syncer (nil when
+// syncs aren't scheduled). A zero timeout falls back to 15 seconds.
+func NewLifecycle(app *pocketbase.PocketBase, conn *database.LibSQLConnection, syncer *database.Syncer, timeout time.Duration) *Lifecycle {
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	return &Lifecycle{
+		app:     app,
+		conn:    conn,
+		syncer:  syncer,
+		timeout: timeout,
+	}
+}
+
+// Bind registers the lifecycle hooks on the app.
+func (l *Lifecycle) Bind() {
+	l.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		l.mu.Lock()
+		l.server = e.Server
+		l.mu.Unlock()
+		return e.Next()
+	})
+
+	// Runs before PocketBase's own "pbGracefulShutdown" handler (priority -9999),
+	// which cancels every request context and only waits one second.
+	l.app.OnTerminate().Bind(&hook.Handler[*core.TerminateEvent]{
+		Id:       lifecycleHookId,
+		Priority: -10000,
+		Func: func(e *core.TerminateEvent) error {
+			l.drain()
+			l.stopCron()
+			l.stopSyncer()
+			l.sync()
+
+			err := e.Next()
+
+			l.close()
+			logging.Info("shutdown: complete")
+			return err
+		},
+	})
+}
+
+// drain stops the HTTP listener and waits for in-flight handlers to finish.
+func (l *Lifecycle) drain() {
+	l.mu.Lock()
+	server := l.server
+	l.mu.Unlock()
+
+	if server == nil {
+		logging.Info("shutdown: http server not started, nothing to drain")
+		return
+	}
+
+	// Realtime (SSE) handlers never return on their own; discard the
+	// clients once the listener is closed so they don't hold up the drain.
+	server.RegisterOnShutdown(func() {
+		broker := l.app.SubscriptionsBroker()
+		for id := range broker.Clients() {
+			broker.Unregister(id)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := server.Shutdown(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		logging.Warnf("shutdown: http drain timed out after %v, remaining requests will be cancelled", l.timeout)
+	case err != nil:
+		logging.Errorf(err, "shutdown: http drain failed")
+	default:
+		logging.Infof("shutdown: http server drained in %v", time.Since(start))
+	}
+}
+
+func (l *Lifecycle) stopCron() {
+	l.app.Cron().Stop()
+	logging.Info("shutdown: cron scheduler stopped")
+}
+
+func (l *Lifecycle) stopSyncer() {
+	if l.syncer == nil {
+		return
+	}
+	l.syncer.Stop()
+	logging.Info("shutdown: scheduled libSQL syncs stopped")
+}
+
+func (l *Lifecycle) sync() {
+	if l.conn == nil || !l.conn.Syncs() {
+		return
+	}
+
+	start := time.Now()
+	if err := l.conn.Sync(); err != nil {
+		logging.Errorf(err, "shutdown: final libSQL sync failed")
+		return
+	}
+	logging.Infof("shutdown: final libSQL sync completed in %v", time.Since(start))
+}
+
+func (l *Lifecycle) close() {
+	if l.conn == nil {
+		return
+	}
+
+	if err := l.conn.Close(); err != nil {
+		logging.Errorf(err, "shutdown: failed to close libSQL connector")
+		return
+	}
+	logging.Info("shutdown: libSQL connector closed")
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/internal/metrics"
+	"pocketbase-server/server/middleware"
+)
+
+// bindMetrics exposes the Prometheus metrics. Without cfg.Metrics.Addr
+// GET /metrics is served on the main router for superusers only;
+// otherwise it gets its own listener (meant for a private bind address
+// such as 127.0.0.1:9090) that is started on serve and stopped on shutdown.
+func (s *Server) bindMetrics() {
+	addr := s.cfg.Metrics.Addr
+
+	if addr == "" {
+		s.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+			e.Router.GET("/metrics", apis.WrapStdHandler(metrics.Handler())).
+				Bind(middleware.RequireSuperuser())
+			return e.Next()
+		})
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	s.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logging.Errorf(err, "metrics: listener on %s failed", addr)
+			}
+		}()
+		logging.Infof("metrics: serving /metrics on %s", addr)
+		return e.Next()
+	})
+
+	s.app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+		return e.Next()
+	})
+}
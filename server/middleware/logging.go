@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"pocketbase-server/internal/logging"
+)
+
+// RequestIDHeader carries the request id in both directions.
+const RequestIDHeader = "X-Request-Id"
+
+const (
+	AccessLogId = "serverAccessLog"
+
+	// Runs ahead of PocketBase's own middlewares so every request,
+	// including rejected ones, gets a request id and an access log entry.
+	AccessLogPriority = apis.DefaultActivityLoggerMiddlewarePriority - 2
+
+	requestIdKey = "requestId"
+	maxRequestId = 128
+)
+
+// AccessLog writes one structured log entry per request with the request
+// id, status, latency, response size and auth context.
+//
+// A request-scoped logger tagged with request_id is stored on the request
+// context, so handlers and hooks can use
+//
+//	logging.FromContext(re.Request.Context())
+//
+// The incoming X-Request-Id is reused when present, otherwise one is
+// generated; either way it is echoed in the response headers.
+func AccessLog() *hook.Handler[*core.RequestEvent] {
+	return &hook.Handler[*core.RequestEvent]{
+		Id:       AccessLogId,
+		Priority: AccessLogPriority,
+		Func: func(re *core.RequestEvent) error {
+			start := time.Now()
+
+			id := re.Request.Header.Get(RequestIDHeader)
+			if !validRequestId(id) {
+				id = uuid.NewString()
+			}
+			re.Set(requestIdKey, id)
+			re.Response.Header().Set(RequestIDHeader, id)
+
+			logger := log.Logger.With().Str("request_id", id).Logger()
+			re.Request = re.Request.WithContext(logging.WithContext(re.Request.Context(), logger))
+
+			counter := &countingWriter{ResponseWriter: re.Response}
+			re.Response = counter
+
+			err := re.Next()
+
+			status := responseStatus(re, err)
+
+			var event *zerolog.Event
+			switch {
+			case status >= http.StatusInternalServerError:
+				event = logger.Error().Err(err)
+			case status >= http.StatusBadRequest:
+				event = logger.Warn()
+			default:
+				event = logger.Info()
+			}
+
+			event = event.
+				Str("method", re.Request.Method).
+				Str("path", re.Request.URL.Path).
+				Int("status", status).
+				Dur("latency", time.Since(start)).
+				Int64("bytes", counter.bytes).
+				Str("remote_ip", re.RealIP())
+
+			if re.Auth != nil {
+				event = event.
+					Str("auth_collection", re.Auth.Collection().Name).
+					Str("user_id", re.Auth.Id)
+			}
+			if orgId := re.Request.PathValue("orgId"); orgId != "" {
+				event = event.Str("org_id", orgId)
+			}
+
+			event.Msg("request")
+
+			return err
+		},
+	}
+}
+
+// responseStatus is the status the client receives: errors returned by
+// the handler chain are written by the router after the middlewares run.
+func responseStatus(re *core.RequestEvent, err error) int {
+	if err != nil {
+		return router.ToApiError(err).Status
+	}
+	return re.Status()
+}
+
+// RequestID returns the id assigned by AccessLog, or "" outside of it.
+func RequestID(re *core.RequestEvent) string {
+	id, _ := re.Get(requestIdKey).(string)
+	return id
+}
+
+// validRequestId accepts short, printable ASCII ids so a client-supplied
+// header can't inject arbitrary content into the logs.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestId {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// countingWriter counts response body bytes. Unwrap keeps PocketBase's
+// status tracking and flushing working through the wrapper.
+type countingWriter struct {
+	http.ResponseWriter
+	bytes int64
+}
+
+var _ router.RWUnwrapper = (*countingWriter)(nil)
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
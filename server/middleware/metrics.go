@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+
+	"pocketbase-server/internal/metrics"
+)
+
+const (
+	MetricsId       = "serverMetrics"
+	MetricsPriority = AccessLogPriority + 1
+)
+
+// Metrics records request counts and latency per route pattern and
+// status in the http_requests_total and http_request_duration_seconds
+// metrics.
+func Metrics() *hook.Handler[*core.RequestEvent] {
+	return &hook.Handler[*core.RequestEvent]{
+		Id:       MetricsId,
+		Priority: MetricsPriority,
+		Func: func(re *core.RequestEvent) error {
+			start := time.Now()
+
+			err := re.Next()
+
+			metrics.ObserveHTTPRequest(re.Request.Method, routePattern(re), responseStatus(re, err), time.Since(start))
+			return err
+		},
+	}
+}
+
+// routePattern returns the matched route pattern without its method,
+// e.g. "/api/collections/{collection}/records".
+func routePattern(re *core.RequestEvent) string {
+	pattern := re.Request.Pattern
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = pattern[i+1:]
+	}
+	if pattern == "" {
+		return "unmatched"
+	}
+	return pattern
+}
@@ -2,26 +2,18 @@ package router
 
 import (
 	"encoding/json"
-	"log"
 
 	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/pb/collections/roles"
+	"pocketbase-server/server/middleware"
 )
 
 // bindAdminRoutes registers admin-only API endpoints.
 func (r *Router) bindAdminRoutes(e *core.ServeEvent) {
 	// POST /api/admin/users — platform admin creates a new user
 	e.Router.POST("/api/admin/users", func(re *core.RequestEvent) error {
-		if re.Auth == nil {
-			return re.JSON(401, map[string]any{"error": "authentication required"})
-		}
-
-		// Must be a platform admin or superuser
-		isSuperuser := re.Auth.Collection().Name == "_superusers"
-		isAdmin := re.Auth.GetString("role") == "admin"
-		if !isSuperuser && !isAdmin {
-			return re.JSON(403, map[string]any{"error": "platform admin access required"})
-		}
-
 		var body struct {
 			Email          string `json:"email"`
 			Password       string `json:"password"`
@@ -60,7 +52,7 @@ func (r *Router) bindAdminRoutes(e *core.ServeEvent) {
 
 		// Save triggers OnRecordCreate hooks (auto-create settings + personal org)
 		if err := r.app.Save(user); err != nil {
-			log.Printf("Admin user creation failed: %v", err)
+			logging.FromContext(re.Request.Context()).Error().Err(err).Msg("admin user creation failed")
 			return re.JSON(400, map[string]any{"error": err.Error()})
 		}
 
@@ -85,7 +77,7 @@ func (r *Router) bindAdminRoutes(e *core.ServeEvent) {
 				member.Set("role", orgRole)
 
 				if err := r.app.Save(member); err != nil {
-					log.Printf("Failed to add admin-created user to org: %v", err)
+					logging.FromContext(re.Request.Context()).Error().Err(err).Str("org_id", body.OrganizationId).Msg("failed to add admin-created user to org")
 					response["org_warning"] = "user created but failed to add to organization: " + err.Error()
 				} else {
 					response["organization_id"] = body.OrganizationId
@@ -95,5 +87,5 @@ func (r *Router) bindAdminRoutes(e *core.ServeEvent) {
 		}
 
 		return re.JSON(201, response)
-	})
+	}).Bind(middleware.RequirePlatformRole(roles.Admin))
 }
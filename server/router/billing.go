@@ -0,0 +1,77 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/internal/webhooks"
+	"pocketbase-server/pb/collections/billing"
+	"pocketbase-server/pb/collections/entitlements"
+	"pocketbase-server/pb/collections/roles"
+	"pocketbase-server/server/middleware"
+)
+
+// WithBilling enables the plan-change and billing webhook routes for
+// provider. Call before RegisterBind.
+func (r *Router) WithBilling(provider billing.Provider) *Router {
+	r.billing = provider
+	return r
+}
+
+// bindBillingRoutes registers the plan catalog, plan-change and webhook
+// endpoints.
+func (r *Router) bindBillingRoutes(e *core.ServeEvent) {
+	// GET /api/billing/plans — public plan catalog
+	e.Router.GET("/api/billing/plans", func(re *core.RequestEvent) error {
+		return re.JSON(200, map[string]any{"plans": entitlements.Catalog})
+	})
+
+	// POST /api/orgs/:orgId/plan — org owners request an upgrade/downgrade
+	e.Router.POST("/api/orgs/{orgId}/plan", func(re *core.RequestEvent) error {
+		if r.billing == nil {
+			return re.JSON(503, map[string]any{"error": "billing is not configured"})
+		}
+
+		var body struct {
+			Plan string `json:"plan"`
+		}
+		if err := re.BindBody(&body); err != nil || body.Plan == "" {
+			return re.JSON(400, map[string]any{"error": "plan is required"})
+		}
+
+		orgId := re.Request.PathValue("orgId")
+		result, err := billing.RequestPlanChange(re.Request.Context(), r.app, r.billing, orgId, body.Plan, re.Auth.Id)
+		if err != nil {
+			var downgrade *billing.DowngradeError
+			switch {
+			case errors.Is(err, billing.ErrUnknownPlan), errors.Is(err, billing.ErrSamePlan):
+				return re.JSON(400, map[string]any{"error": err.Error()})
+			case errors.As(err, &downgrade):
+				return re.JSON(409, map[string]any{
+					"error":   err.Error(),
+					"feature": downgrade.Feature,
+					"limit":   downgrade.Limit,
+					"used":    downgrade.Used,
+				})
+			}
+			logging.FromContext(re.Request.Context()).Error().Err(err).Str("org_id", orgId).Msg("plan change failed")
+			return re.JSON(502, map[string]any{"error": "billing provider error"})
+		}
+
+		return re.JSON(202, map[string]any{
+			"org_id":       orgId,
+			"pending_plan": body.Plan,
+			"status":       result.Status,
+			"checkout_url": result.CheckoutURL,
+		})
+	}).Bind(middleware.RequireOrgRole("orgId", roles.OrgOwner))
+
+	// POST /api/billing/webhook/:provider — subscription events
+	if r.billing != nil {
+		name := r.billing.Name()
+		e.Router.POST("/api/billing/webhook/"+name, apis.WrapStdHandler(webhooks.Handle(name, billing.HandleEvent(r.app, r.billing))))
+	}
+}
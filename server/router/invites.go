@@ -2,17 +2,29 @@ package router
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/pb/collections/organizations"
+	"pocketbase-server/pb/collections/roles"
+	"pocketbase-server/server/middleware"
 )
 
+// maxBulkInvites caps the emails of one bulk invite request; the rate
+// limit counts requests, not emails.
+const maxBulkInvites = 50
+
 // bindInviteRoutes registers custom invite endpoints.
 func (r *Router) bindInviteRoutes(e *core.ServeEvent) {
 	// GET /api/invites/verify?token=... — public, returns invite details
-	e.Router.GET("/api/invites/verify", func(re *core.RequestEvent) error {
+	verify := e.Router.GET("/api/invites/verify", func(re *core.RequestEvent) error {
 		token := re.Request.URL.Query().Get("token")
 		if token == "" {
 			return re.JSON(400, map[string]any{"error": "token is required"})
@@ -52,12 +64,11 @@ func (r *Router) bindInviteRoutes(e *core.ServeEvent) {
 		})
 	})
 
+	// Tokens are looked up by value, so limit guessing per client
+	r.rateLimit(verify, "invite_verify", r.limits.InviteVerify)
+
 	// POST /api/invites/accept — authenticated, accepts {"token": "..."}
 	e.Router.POST("/api/invites/accept", func(re *core.RequestEvent) error {
-		if re.Auth == nil {
-			return re.JSON(401, map[string]any{"error": "authentication required"})
-		}
-
 		var body struct {
 			Token string `json:"token"`
 		}
@@ -95,7 +106,13 @@ func (r *Router) bindInviteRoutes(e *core.ServeEvent) {
 		// in invites.go will create the org_member record
 		invite.Set("status", "accepted")
 		if err := r.app.Save(invite); err != nil {
-			log.Printf("Failed to accept invite: %v", err)
+			// The org is at its member limit
+			var apiErr *router.ApiError
+			if errors.As(err, &apiErr) && apiErr.Status == http.StatusPaymentRequired {
+				return re.JSON(apiErr.Status, map[string]any{"error": apiErr.Message, "limit": apiErr.Data})
+			}
+
+			logging.FromContext(re.Request.Context()).Error().Err(err).Str("invite_id", invite.Id).Msg("failed to accept invite")
 			return re.JSON(500, map[string]any{"error": "failed to accept invite"})
 		}
 
@@ -103,26 +120,16 @@ func (r *Router) bindInviteRoutes(e *core.ServeEvent) {
 			"success": true,
 			"org_id":  invite.GetString("organization"),
 		})
-	})
+	}).Bind(middleware.RequireAuth())
 
-	// POST /api/orgs/:orgId/invites/bulk — authenticated org admin/owner
-	e.Router.POST("/api/orgs/{orgId}/invites/bulk", func(re *core.RequestEvent) error {
-		if re.Auth == nil {
-			return re.JSON(401, map[string]any{"error": "authentication required"})
-		}
+	// Org-scoped endpoints for org owners/admins
+	orgs := e.Router.Group("/api/orgs/{orgId}")
+	orgs.Bind(middleware.RequireOrgRole("orgId", roles.OrgOwner, roles.OrgAdmin))
 
+	// POST /api/orgs/:orgId/invites/bulk — authenticated org admin/owner
+	bulk := orgs.POST("/invites/bulk", func(re *core.RequestEvent) error {
 		orgId := re.Request.PathValue("orgId")
 
-		// Verify caller is an owner/admin of this org
-		_, err := r.app.FindFirstRecordByFilter(
-			"org_members",
-			"user = {:userId} && organization = {:orgId} && (role = 'owner' || role = 'admin')",
-			dbx.Params{"userId": re.Auth.Id, "orgId": orgId},
-		)
-		if err != nil {
-			return re.JSON(403, map[string]any{"error": "you must be an org owner or admin"})
-		}
-
 		var body struct {
 			Emails []string `json:"emails"`
 			Role   string   `json:"role"`
@@ -133,6 +140,9 @@ func (r *Router) bindInviteRoutes(e *core.ServeEvent) {
 		if len(body.Emails) == 0 {
 			return re.JSON(400, map[string]any{"error": "emails array is required"})
 		}
+		if len(body.Emails) > maxBulkInvites {
+			return re.JSON(400, map[string]any{"error": fmt.Sprintf("at most %d emails per request", maxBulkInvites)})
+		}
 		if body.Role == "" {
 			body.Role = "member"
 		}
@@ -156,9 +166,8 @@ func (r *Router) bindInviteRoutes(e *core.ServeEvent) {
 			invite.Set("organization", orgId)
 			invite.Set("email", email)
 			invite.Set("role", body.Role)
-			// token, status, expires_at, invited_by are set by RegisterInviteHooks
+			organizations.PrepareInvite(invite, re.Auth)
 
-			// Use the request-aware save to trigger OnRecordCreateRequest hooks
 			if err := r.app.Save(invite); err != nil {
 				results = append(results, result{Email: email, Status: "failed", Error: err.Error()})
 			} else {
@@ -173,4 +182,7 @@ func (r *Router) bindInviteRoutes(e *core.ServeEvent) {
 			"results": results,
 		})
 	})
+
+	// Each invite sends an email
+	r.rateLimit(bulk, "invite_bulk", r.limits.InviteBulk)
 }
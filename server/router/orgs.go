@@ -0,0 +1,38 @@
+package router
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/pb/collections/entitlements"
+	"pocketbase-server/server/middleware"
+)
+
+// bindOrgRoutes registers org-scoped endpoints for any org member.
+func (r *Router) bindOrgRoutes(e *core.ServeEvent) {
+	orgs := e.Router.Group("/api/orgs/{orgId}")
+	orgs.Bind(middleware.RequireOrgRole("orgId"))
+
+	// GET /api/orgs/:orgId/usage — plan limits and current usage
+	orgs.GET("/usage", func(re *core.RequestEvent) error {
+		orgId := re.Request.PathValue("orgId")
+
+		ent, err := entitlements.For(r.app, orgId)
+		if err != nil {
+			logging.FromContext(re.Request.Context()).Error().Err(err).Str("org_id", orgId).Msg("failed to resolve entitlements")
+			return re.JSON(500, map[string]any{"error": "failed to resolve plan"})
+		}
+
+		usage, err := ent.Usage(r.app)
+		if err != nil {
+			logging.FromContext(re.Request.Context()).Error().Err(err).Str("org_id", orgId).Msg("failed to count usage")
+			return re.JSON(500, map[string]any{"error": "failed to count usage"})
+		}
+
+		return re.JSON(200, map[string]any{
+			"org_id": orgId,
+			"plan":   ent.Plan,
+			"usage":  usage,
+		})
+	})
+}
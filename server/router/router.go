@@ -1,17 +1,28 @@
 package router
 
 import (
-	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+
+	"pocketbase-server/internal/config"
+	"pocketbase-server/internal/ratelimit"
+	"pocketbase-server/pb/collections/billing"
+	"pocketbase-server/server/middleware"
 )
 
 type Router struct {
-	app *pocketbase.PocketBase
+	app    core.App
+	limits config.RateLimitConfig
+	store  ratelimit.Store
+
+	billing billing.Provider
 }
 
-func NewRouter(app *pocketbase.PocketBase) *Router {
+func NewRouter(app core.App, limits config.RateLimitConfig, store ratelimit.Store) *Router {
 	return &Router{
-		app: app,
+		app:    app,
+		limits: limits,
+		store:  store,
 	}
 }
 
@@ -19,6 +30,32 @@ func (r *Router) RegisterBind() {
 	r.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
 		r.bindInviteRoutes(e)
 		r.bindAdminRoutes(e)
+		r.bindOrgRoutes(e)
+		r.bindBillingRoutes(e)
 		return e.Next()
 	})
 }
+
+// rateLimit binds the named rate limit policy to route, unless rate
+// limiting is disabled.
+func (r *Router) rateLimit(route *router.Route[*core.RequestEvent], name string, policy config.RateLimitPolicy) {
+	if !r.limits.Enabled || r.store == nil {
+		return
+	}
+
+	var key middleware.KeyFunc
+	switch policy.Key {
+	case "user":
+		key = middleware.KeyByUser
+	case "org":
+		key = middleware.KeyByOrg("orgId")
+	default:
+		key = middleware.KeyByIP
+	}
+
+	route.Bind(middleware.RateLimit(r.store, name, ratelimit.Limit{
+		Requests: policy.Requests,
+		Per:      policy.Per,
+		Burst:    policy.Burst,
+	}, key))
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// seedBatch is a group of records for a single collection.
+type seedBatch struct {
+	Collection string           `json:"collection"`
+	Records    []map[string]any `json:"records"`
+}
+
+// Seed loads the JSON seed document into app inside a transaction and
+// returns the number of created and skipped records. Record hooks fire as
+// usual, so e.g. seeded users get their settings and personal org.
+func Seed(app core.App, data []byte) (created, skipped int, err error) {
+	var batches []seedBatch
+	if err := json.Unmarshal(data, &batches); err != nil {
+		return 0, 0, fmt.Errorf("invalid seed file: %w", err)
+	}
+
+	err = app.RunInTransaction(func(txApp core.App) error {
+		for _, batch := range batches {
+			col, err := txApp.FindCollectionByNameOrId(batch.Collection)
+			if err != nil {
+				return fmt.Errorf("seed: unknown collection %q", batch.Collection)
+			}
+
+			for i, fields := range batch.Records {
+				if id, _ := fields["id"].(string); id != "" {
+					if existing, _ := txApp.FindRecordById(col, id); existing != nil {
+						skipped++
+						continue
+					}
+				}
+
+				record := core.NewRecord(col)
+				for k, v := range fields {
+					if k == "password" && col.IsAuth() {
+						record.SetPassword(fmt.Sprint(v))
+						continue
+					}
+					record.Set(k, v)
+				}
+
+				if err := txApp.Save(record); err != nil {
+					return fmt.Errorf("seed: %s record #%d: %w", batch.Collection, i, err)
+				}
+				created++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return created, skipped, nil
+}
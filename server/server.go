@@ -3,92 +3,62 @@ package server
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
-	"time"
 
-	"github.com/caarlos0/env/v11"
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+	pbrouter "github.com/pocketbase/pocketbase/tools/router"
 
+	"pocketbase-server/internal/backup"
+	"pocketbase-server/internal/config"
 	"pocketbase-server/internal/cronjobs"
 	"pocketbase-server/internal/database"
-	"pocketbase-server/internal/logging"
-	"pocketbase-server/pb/collections/auth"
+	"pocketbase-server/internal/ratelimit"
+	"pocketbase-server/internal/webhooks"
+	"pocketbase-server/pb/collections/billing"
+	"pocketbase-server/pb/collections/entitlements"
 	"pocketbase-server/pb/collections/notifications"
 	"pocketbase-server/pb/collections/organizations"
-	"pocketbase-server/pb/collections/photos"
 	"pocketbase-server/pb/collections/realestate"
-	"pocketbase-server/pb/collections/tenancy"
 	"pocketbase-server/pb/collections/users"
+	"pocketbase-server/pb/collections/visibility"
 	"pocketbase-server/server/admin"
+	"pocketbase-server/server/middleware"
 	"pocketbase-server/server/router"
 	"pocketbase-server/service"
 )
 
-type Config struct {
-	Addr       string `env:"PB_ADDR" envDefault:"0.0.0.0:8090"`
-	DataDir    string `env:"PB_DATA_DIR" envDefault:"./db/pb_data"`
-	Dev        bool   `env:"PB_DEV" envDefault:"false"`
-	AdminEmail string `env:"PB_ADMIN_EMAIL" envDefault:""`
-	AdminPass  string `env:"PB_ADMIN_PASS" envDefault:""`
-
-	// e.g., "http://localhost:8080" for local, "libsql://xxx.turso.io" for cloud
-	LibSQLURL      string        `env:"LIBSQL_URL" envDefault:"http://localhost:8080"`
-	LibSQLToken    string        `env:"LIBSQL_AUTH_TOKEN" envDefault:""`
-	LibSQLInterval time.Duration `env:"LIBSQL_SYNC_INTERVAL" envDefault:"30s"`
-
-	// S3 file storage (optional — leave blank to use local disk)
-	S3Bucket         string `env:"S3_BUCKET" envDefault:""`
-	S3Region         string `env:"S3_REGION" envDefault:""`
-	S3Endpoint       string `env:"S3_ENDPOINT" envDefault:""`
-	S3AccessKey      string `env:"S3_ACCESS_KEY" envDefault:""`
-	S3Secret         string `env:"S3_SECRET" envDefault:""`
-	S3ForcePathStyle bool   `env:"S3_FORCE_PATH_STYLE" envDefault:"false"`
-}
-
-func (cfg *Config) httpFlag() string {
-	return fmt.Sprintf("--http=%s", cfg.Addr)
-}
-
-func NewConfig() *Config {
-	var cfg Config
-	if err := env.Parse(&cfg); err != nil {
-		panic(err)
-	}
-	return &cfg
-}
-
 type Server struct {
-	app  *pocketbase.PocketBase
-	cfg  *Config
-	conn *database.LibSQLConnection
+	app      *pocketbase.PocketBase
+	cfg      *config.Config
+	conn     *database.LibSQLConnection
+	syncer   *database.Syncer
+	backups  *backup.Manager
+	services *service.Registry
+	exitCode int
 }
 
 type Option func(*pocketbase.Config)
 
-func New() (*Server, error) {
-	cfg := NewConfig()
-	conn, err := database.NewLibSQLConnection(
-		&database.LibSQLConfig{
-			DataDir:  cfg.DataDir,
-			URL:      cfg.LibSQLURL,
-			Token:    cfg.LibSQLToken,
-			Interval: cfg.LibSQLInterval,
-		},
-	)
+func New(cfg *config.Config) (*Server, error) {
+	dbcfg, err := libSQLConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := database.NewLibSQLConnection(dbcfg)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	var pbcfg = pocketbase.Config{
-		DefaultDataDir: cfg.DataDir,
-		DefaultDev:     cfg.Dev,
+		DefaultDataDir: cfg.Server.DataDir,
+		DefaultDev:     cfg.Server.Dev,
 		DBConnect: func(dbPath string) (*dbx.DB, error) {
 			// Use libSQL connector for the main data.db
 			if strings.HasSuffix(dbPath, "data.db") {
-				return dbx.NewFromDB(conn.DB, "sqlite"), nil
+				return dbx.NewFromDB(conn.OpenDB(), "sqlite"), nil
 			}
 			// Use default SQLite for auxiliary databases (logs, etc.)
 			return core.DefaultDBConnect(dbPath)
@@ -96,132 +66,184 @@ func New() (*Server, error) {
 	}
 
 	app := pocketbase.NewWithConfig(pbcfg)
+	config.RegisterFlags(app.RootCmd.PersistentFlags())
 
 	s := &Server{
-		app:  app,
-		cfg:  cfg,
-		conn: conn,
+		app:      app,
+		cfg:      cfg,
+		conn:     conn,
+		services: service.NewRegistry(app),
 	}
 
-	// Request logging middleware
+	s.bindSyncer()
+	NewLifecycle(app, conn, s.syncer, cfg.Server.ShutdownTimeout).Bind()
+
+	// Structured access log (also puts a request-scoped logger on the
+	// context) and request metrics
 	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		e.Router.BindFunc(func(re *core.RequestEvent) error {
-			logging.Infof("%s %s", re.Request.Method, re.Request.URL.Path)
-			return re.Next()
-		})
+		e.Router.Bind(middleware.AccessLog(), middleware.Metrics())
 		return e.Next()
 	})
+	s.bindMetrics()
 
-	router := router.NewRouter(s.App())
+	router := router.NewRouter(s.App(), cfg.RateLimit, s.rateLimitStore())
+	if provider := billingProvider(cfg.Billing); provider != nil {
+		webhooks.Register(provider)
+		router.WithBilling(provider)
+	}
 	router.RegisterBind()
 	admin.BindSyncFunc(s.App(), s)
+	admin.BindTransfer(s.App())
+	admin.BindDrift(s.App())
 	admin.RedirectAdminUI(s.App())
-	admin.EnsureAdmin(s.App(), cfg.AdminEmail, cfg.AdminPass)
-
-	// S3 file storage (only applied if S3_BUCKET is set)
-	if cfg.S3Bucket != "" {
-		app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-			settings := app.Settings()
-			settings.S3.Enabled = true
-			settings.S3.Bucket = cfg.S3Bucket
-			settings.S3.Region = cfg.S3Region
-			settings.S3.Endpoint = cfg.S3Endpoint
-			settings.S3.AccessKey = cfg.S3AccessKey
-			settings.S3.Secret = cfg.S3Secret
-			settings.S3.ForcePathStyle = cfg.S3ForcePathStyle
-			if err := app.Save(settings); err != nil {
-				return fmt.Errorf("failed to configure S3: %w", err)
-			}
-			return e.Next()
-		})
-	}
+	admin.EnsureAdmin(s.App(), cfg.Admin.Email, cfg.Admin.Pass)
+
+	// Collection setup runs on every serve; see setupSteps for the order.
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		if err := s.Bootstrap(e.App, false); err != nil {
+			return err
+		}
+		return e.Next()
+	})
 
-	// Phase 1: Create collections (no cross-collection rules)
-	users.EnsureCollectionOnBeforeServe(s.App())
-	users.EnsureSettingsOnBeforeServe(s.App())
 	users.RegisterHooks(s.App())
-	organizations.EnsureCollectionOnBeforeServe(s.App())
-	organizations.EnsureMembersOnBeforeServe(s.App())
-	organizations.EnsureOrgSettingsOnBeforeServe(s.App())
-	organizations.EnsureInvitesOnBeforeServe(s.App())
 	organizations.RegisterHooks(s.App())
 	organizations.RegisterInviteHooks(s.App())
-	notifications.EnsureCollectionOnBeforeServe(s.App())
+	entitlements.RegisterHooks(s.App())
+	billing.RegisterHooks(s.App())
 	notifications.RegisterHooks(s.App())
-	photos.EnsureCollectionOnBeforeServe(s.App())
-	realestate.EnsurePropertiesOnBeforeServe(s.App())
-	realestate.EnsurePropertyDetailsOnBeforeServe(s.App())
-	realestate.EnsurePropertySaleHistoryOnBeforeServe(s.App())
-	realestate.EnsurePropertyTaxHistoryOnBeforeServe(s.App())
-	realestate.EnsurePropertyContactsOnBeforeServe(s.App())
-	realestate.EnsureRentalCompsOnBeforeServe(s.App())
-	realestate.EnsureSavedPropertiesOnBeforeServe(s.App())
-	realestate.EnsureSavedPropertyHistoryOnBeforeServe(s.App())
 	realestate.RegisterSavedPropertyHooks(s.App())
-
-	// Phase 2: Apply access rules (all collections now exist)
-	organizations.ApplyRules(s.App())
-	organizations.ApplyInviteRules(s.App())
-	organizations.ApplyOrgSettingsRules(s.App())
-	tenancy.EnforceTenancy(s.App())
-	auth.EnsureOAuth2Providers(s.App())
+	visibility.RegisterHooks(s.App())
 
 	// Cron jobs
 	cronjobs.RegisterExpireInvites(s.App())
 
-	service.NewService(s.App())
+	s.backups = s.newBackupManager()
+	s.bindBackups()
+
+	s.services.Bind()
+	healthChecks := []service.Check{
+		service.DBCheck(conn),
+		service.SyncCheck(conn),
+		service.MailCheck(),
+		service.CronCheck(s.App()),
+	}
+	if cfg.S3.Bucket != "" {
+		healthChecks = append(healthChecks, service.S3Check(cfg.S3.Bucket))
+	}
+	if err := s.RegisterService(service.NewHealthService(healthChecks...)); err != nil {
+		return nil, err
+	}
 
 	return s, nil
 }
 
-func (s *Server) Start() error {
-	args := []string{"pocketbase", "serve", s.cfg.httpFlag()}
-	if s.cfg.Dev {
-		args = append(args, "--dev")
+// libSQLConfig maps the config file/env settings to the connection config.
+func libSQLConfig(cfg *config.Config) (*database.LibSQLConfig, error) {
+	key, err := cfg.LibSQL.ResolveEncryptionKey()
+	if err != nil {
+		return nil, err
 	}
-	os.Args = args
+	return &database.LibSQLConfig{
+		Mode:          database.Mode(cfg.LibSQL.EffectiveMode()),
+		DataDir:       cfg.Server.DataDir,
+		URL:           cfg.LibSQL.URL,
+		Token:         cfg.LibSQL.Token,
+		EncryptionKey: key,
+	}, nil
+}
+
+// rateLimitStore returns the store for the custom route rate limits.
+// The db store keeps buckets in the main database so instances share them.
+func (s *Server) rateLimitStore() ratelimit.Store {
+	if s.cfg.RateLimit.Store != "db" {
+		return ratelimit.NewMemoryStore()
+	}
+
+	store := ratelimit.NewDBStore(s.app)
+	s.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		if err := store.Ensure(); err != nil {
+			return fmt.Errorf("failed to create rate limit table: %w", err)
+		}
+		return e.Next()
+	})
+	cronjobs.RegisterRateLimitCleanup(s.app, store)
+	return store
+}
+
+// billingProvider returns the configured payment provider, or nil when
+// billing is disabled.
+func billingProvider(cfg config.BillingConfig) billing.Provider {
+	switch cfg.Provider {
+	case "fake":
+		return billing.NewFakeProvider(cfg.WebhookSecret)
+	default:
+		return nil
+	}
+}
 
-	return s.app.Start()
+// Start runs the command named in os.Args (serve when none is given)
+// and blocks until it finishes. Check ExitCode for the command result.
+func (s *Server) Start() error {
+	s.registerCommands()
+	if err := s.defaultToServe(); err != nil {
+		return err
+	}
+	return s.app.Execute()
 }
 
 func (s *Server) App() *pocketbase.PocketBase {
 	return s.app
 }
-func (s *Server) Config() *Config {
+func (s *Server) Config() *config.Config {
 	return s.cfg
 }
 
+// RegisterService mounts services under /api/custom/<name>.
+// Must be called before Start.
+func (s *Server) RegisterService(services ...service.Service) error {
+	return s.services.Register(services...)
+}
+
 type RouteHandler func(e *core.RequestEvent) error
 
-func (s *Server) AddRoute(method, path string, handler RouteHandler) {
+// Middleware is a request handler bound in front of a route or group,
+// e.g. the constructors in server/middleware.
+type Middleware = *hook.Handler[*core.RequestEvent]
+
+// AddRoute registers a route for any HTTP method. Middlewares run in
+// the order given, before the handler.
+func (s *Server) AddRoute(method, path string, handler RouteHandler, middlewares ...Middleware) {
 	s.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
-		switch method {
-		case http.MethodGet:
-			e.Router.GET(path, handler)
-		case http.MethodPost:
-			e.Router.POST(path, handler)
-		case http.MethodPut:
-			e.Router.PUT(path, handler)
-		case http.MethodPatch:
-			e.Router.PATCH(path, handler)
-		case http.MethodDelete:
-			e.Router.DELETE(path, handler)
-		}
+		e.Router.Route(strings.ToUpper(method), path, handler).Bind(middlewares...)
+		return e.Next()
+	})
+}
+
+// Group registers a route group under prefix. The middlewares apply to
+// every route register adds to the group, including nested groups:
+//
+//	s.Group("/api/orgs/{orgId}", func(g *pbrouter.RouterGroup[*core.RequestEvent]) {
+//		g.GET("/reports", reportsHandler)
+//		g.DELETE("/reports/{id}", deleteHandler).Bind(middleware.RequireOrgRole("orgId", roles.OrgOwner))
+//	}, middleware.RequireOrgRole("orgId"))
+func (s *Server) Group(prefix string, register func(g *pbrouter.RouterGroup[*core.RequestEvent]), middlewares ...Middleware) {
+	s.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		g := e.Router.Group(prefix)
+		g.Bind(middlewares...)
+		register(g)
 		return e.Next()
 	})
 }
 
 // AddHTTPHandler wraps a standard http.HandlerFunc for use with PocketBase
-func (s *Server) AddHTTPHandler(method, path string, handler http.HandlerFunc) {
+func (s *Server) AddHTTPHandler(method, path string, handler http.HandlerFunc, middlewares ...Middleware) {
 	s.AddRoute(method, path, func(e *core.RequestEvent) error {
 		handler(e.Response, e.Request)
 		return nil
-	})
+	}, middlewares...)
 }
 
-func (s *Server) Sync() error {
-	return s.conn.Sync()
-}
 func (s *Server) Close() error {
 	return s.conn.Close()
 }
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/database"
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/internal/notifications"
+	"pocketbase-server/pb/collections/roles"
+)
+
+// bindSyncer schedules replica syncs while serving. Other modes have
+// nothing to sync, and one-off commands rely on the final sync on exit.
+func (s *Server) bindSyncer() {
+	if !s.conn.Syncs() {
+		return
+	}
+
+	s.syncer = database.NewSyncer(s.conn, database.SyncerConfig{
+		Interval:   s.cfg.LibSQL.SyncInterval,
+		MaxBackoff: s.cfg.LibSQL.SyncMaxBackoff,
+		AlertAfter: s.cfg.LibSQL.SyncAlertAfter,
+		OnAlert:    s.alertSyncFailing,
+		OnRecover: func(status database.SyncStatus) {
+			logging.Infof("libSQL sync recovered at frame %d", status.FrameNo)
+		},
+	})
+
+	s.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		s.syncer.Start()
+		return e.Next()
+	})
+}
+
+// alertSyncFailing logs the failure streak and notifies platform admins.
+func (s *Server) alertSyncFailing(status database.SyncStatus) {
+	logging.Err(status.LastError).
+		Int("consecutive_failures", status.ConsecutiveFailures).
+		Time("last_success", status.LastSuccess).
+		Msg("libSQL sync failing")
+
+	admins, err := s.app.FindAllRecords("users", dbx.HashExp{"role": roles.Admin})
+	if err != nil {
+		logging.Errorf(err, "failed to find platform admins for sync alert")
+		return
+	}
+
+	client := notifications.NewClient(s.app)
+	for _, admin := range admins {
+		_, err := client.Send(notifications.NotificationOpts{
+			Recipient: admin.Id,
+			Type:      notifications.TypeSystem,
+			Title:     "Database sync is failing",
+			Message:   fmt.Sprintf("The last %d libSQL syncs failed: %v", status.ConsecutiveFailures, status.LastError),
+			Data: map[string]any{
+				"consecutive_failures": status.ConsecutiveFailures,
+				"last_success":         status.LastSuccess,
+			},
+		})
+		if err != nil {
+			logging.Errorf(err, "failed to notify %s about failing sync", admin.Id)
+		}
+	}
+}
+
+// Sync runs a replica sync and waits for it.
+func (s *Server) Sync() error {
+	return s.conn.Sync()
+}
+
+// SyncAsync starts a replica sync in the background; false when one is
+// already running or there is nothing to sync.
+func (s *Server) SyncAsync() bool {
+	return s.conn.SyncAsync()
+}
+
+// SyncStatus reports the recent replica syncs.
+func (s *Server) SyncStatus() database.SyncStatus {
+	return s.conn.SyncStatus()
+}
@@ -1,27 +1,125 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
 )
 
-type HealthService struct{}
+// checkTimeout bounds how long a single readiness check may run.
+const checkTimeout = 3 * time.Second
+
+// Check is a single readiness probe. Details are included in the
+// response alongside the check status; a non-nil error marks it failed.
+type Check struct {
+	Name string
+	// Critical checks make /ready return 503 when they fail.
+	Critical bool
+	Run      func(ctx context.Context, app core.App) (details map[string]any, err error)
+}
+
+// CheckResult is the reported outcome of a Check.
+type CheckResult struct {
+	Status   string         `json:"status"`
+	Critical bool           `json:"critical"`
+	Latency  string         `json:"latency"`
+	Error    string         `json:"error,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+}
 
-func NewHealthService() *HealthService {
-	return &HealthService{}
+// HealthService exposes liveness and readiness probes:
+//
+//	GET /api/custom/health/live   process is up and serving requests
+//	GET /api/custom/health/ready  dependencies are usable (503 otherwise)
+type HealthService struct {
+	app    core.App
+	checks []Check
+}
+
+func NewHealthService(checks ...Check) *HealthService {
+	return &HealthService{checks: checks}
 }
 
 func (s *HealthService) Name() string {
 	return "health"
 }
 
-func (s *HealthService) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/api/custom/health", s.handleHealth)
+func (s *HealthService) Start(app core.App) error {
+	s.app = app
+	return nil
+}
+
+func (s *HealthService) RegisterRoutes(e *core.ServeEvent) {
+	e.Router.GET("", s.handleLive)
+	e.Router.GET("/live", s.handleLive)
+	e.Router.GET("/ready", s.handleReady)
 }
 
-func (s *HealthService) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+func (s *HealthService) handleLive(re *core.RequestEvent) error {
+	return re.JSON(http.StatusOK, map[string]string{
 		"status": "ok",
 	})
 }
+
+func (s *HealthService) handleReady(re *core.RequestEvent) error {
+	results := s.Run(re.Request.Context())
+
+	status, code := "ok", http.StatusOK
+	for _, r := range results {
+		if r.Critical && r.Status != "ok" {
+			status, code = "fail", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	return re.JSON(code, map[string]any{
+		"status": status,
+		"checks": results,
+	})
+}
+
+// Run executes all checks concurrently and returns their results by name.
+func (s *HealthService) Run(ctx context.Context) map[string]CheckResult {
+	results := make(map[string]CheckResult, len(s.checks))
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range s.checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			r := s.run(ctx, c)
+			mu.Lock()
+			results[c.Name] = r
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (s *HealthService) run(ctx context.Context, c Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := c.Run(ctx, s.app)
+
+	r := CheckResult{
+		Status:   "ok",
+		Critical: c.Critical,
+		Latency:  time.Since(start).String(),
+		Details:  details,
+	}
+	if err != nil {
+		r.Status = "fail"
+		r.Error = err.Error()
+	}
+	return r
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+
+	"pocketbase-server/internal/database"
+)
+
+// Pinger is implemented by database connections that can be probed.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// SyncStatuser reports the outcome of the most recent replica syncs.
+type SyncStatuser interface {
+	SyncStatus() database.SyncStatus
+}
+
+// DBCheck pings the main database through the libSQL connector.
+func DBCheck(db Pinger) Check {
+	return Check{
+		Name:     "db",
+		Critical: true,
+		Run: func(ctx context.Context, _ core.App) (map[string]any, error) {
+			return nil, db.Ping(ctx)
+		},
+	}
+}
+
+// SyncCheck reports the last replica sync. It only fails when the most
+// recent sync attempt returned an error.
+func SyncCheck(s SyncStatuser) Check {
+	return Check{
+		Name:     "sync",
+		Critical: true,
+		Run: func(_ context.Context, _ core.App) (map[string]any, error) {
+			status := s.SyncStatus()
+			details := map[string]any{
+				"last_attempt":         formatTime(status.LastAttempt),
+				"last_success":         formatTime(status.LastSuccess),
+				"frame_no":             status.FrameNo,
+				"consecutive_failures": status.ConsecutiveFailures,
+			}
+			if status.LastError != nil {
+				return details, fmt.Errorf("last sync failed: %w", status.LastError)
+			}
+			return details, nil
+		},
+	}
+}
+
+// MailCheck verifies that outgoing mail is configured, either through
+// SMTP settings or a local sendmail binary.
+func MailCheck() Check {
+	return Check{
+		Name: "mail",
+		Run: func(_ context.Context, app core.App) (map[string]any, error) {
+			settings := app.Settings()
+			details := map[string]any{
+				"sender": settings.Meta.SenderAddress,
+			}
+
+			if settings.SMTP.Enabled {
+				details["transport"] = "smtp"
+				details["host"] = settings.SMTP.Host
+				if settings.SMTP.Host == "" || settings.SMTP.Port == 0 {
+					return details, errors.New("smtp enabled without host/port")
+				}
+				return details, nil
+			}
+
+			details["transport"] = "sendmail"
+			for _, p := range []string{"/usr/sbin/sendmail", "/usr/bin/sendmail", "sendmail"} {
+				if _, err := exec.LookPath(p); err == nil {
+					return details, nil
+				}
+			}
+			return details, errors.New("smtp is disabled and no sendmail executable was found")
+		},
+	}
+}
+
+// S3Check verifies the configured S3 bucket is reachable.
+func S3Check(bucket string) Check {
+	return Check{
+		Name:     "s3",
+		Critical: true,
+		Run: func(ctx context.Context, app core.App) (map[string]any, error) {
+			details := map[string]any{"bucket": bucket}
+
+			if !app.Settings().S3.Enabled {
+				return details, errors.New("S3_BUCKET is set but S3 storage is not enabled")
+			}
+
+			fs, err := app.NewFilesystem()
+			if err != nil {
+				return details, err
+			}
+			defer fs.Close()
+
+			fs.SetContext(ctx)
+			if _, err := fs.Exists(".healthcheck"); err != nil {
+				return details, err
+			}
+			return details, nil
+		},
+	}
+}
+
+// CronCheck verifies the app cron scheduler is running.
+func CronCheck(app core.App) Check {
+	// PocketBase only starts the cron ticker at the next whole minute
+	// after serving begins, so give it that long before failing.
+	var (
+		mu       sync.Mutex
+		servedAt time.Time
+	)
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		mu.Lock()
+		servedAt = time.Now()
+		mu.Unlock()
+		return e.Next()
+	})
+
+	return Check{
+		Name:     "cron",
+		Critical: true,
+		Run: func(_ context.Context, app core.App) (map[string]any, error) {
+			details := map[string]any{"jobs": app.Cron().Total()}
+			if app.Cron().HasStarted() {
+				return details, nil
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if servedAt.IsZero() || time.Since(servedAt) <= time.Minute {
+				details["starting"] = true
+				return details, nil
+			}
+			return details, errors.New("cron scheduler is not running")
+		},
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
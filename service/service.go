@@ -1,15 +1,146 @@
+// Package service provides a small registry for pluggable HTTP services
+// mounted on the PocketBase router.
 package service
 
 import (
-	"github.com/pocketbase/pocketbase"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+
+	"pocketbase-server/internal/logging"
 )
 
-type Service struct {
-	app *pocketbase.PocketBase
+// DefaultPrefix is the base path every service is mounted under.
+// A service named "health" is reachable at /api/custom/health.
+const DefaultPrefix = "/api/custom"
+
+// Service is a self-contained group of custom routes.
+//
+// RegisterRoutes receives a ServeEvent whose Router is scoped to the
+// service prefix, so routes are registered relative to it:
+//
+//	func (s *MyService) RegisterRoutes(e *core.ServeEvent) {
+//		e.Router.GET("/items", s.listItems) // → GET /api/custom/<name>/items
+//	}
+//
+// The scoped event must not be used to call e.Next().
+type Service interface {
+	Name() string
+	RegisterRoutes(e *core.ServeEvent)
+}
+
+// Starter is implemented by services that need to run setup once the
+// app is bootstrapped and the server is about to start listening.
+type Starter interface {
+	Start(app core.App) error
+}
+
+// Stopper is implemented by services that hold resources which must be
+// released when the app terminates.
+type Stopper interface {
+	Stop() error
+}
+
+// Registry holds the registered services and mounts them on serve.
+type Registry struct {
+	app    core.App
+	prefix string
+
+	mu       sync.Mutex
+	services []Service
+	names    map[string]bool
+}
+
+// NewRegistry creates a registry that mounts services under DefaultPrefix.
+func NewRegistry(app core.App) *Registry {
+	return &Registry{
+		app:    app,
+		prefix: DefaultPrefix,
+		names:  map[string]bool{},
+	}
+}
+
+// Register adds services to the registry. Names must be unique and
+// usable as a single path segment.
+func (r *Registry) Register(services ...Service) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range services {
+		name := s.Name()
+		if name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("service: invalid name %q", name)
+		}
+		if r.names[name] {
+			return fmt.Errorf("service: %q is already registered", name)
+		}
+		r.names[name] = true
+		r.services = append(r.services, s)
+	}
+	return nil
+}
+
+// Services returns the registered services in registration order.
+func (r *Registry) Services() []Service {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Service, len(r.services))
+	copy(out, r.services)
+	return out
+}
+
+// Path returns the mount path for the named service.
+func (r *Registry) Path(name string) string {
+	return r.prefix + "/" + name
+}
+
+// Bind registers the serve and terminate hooks that mount, start and
+// stop the services. Services registered after the server has started
+// are not mounted.
+func (r *Registry) Bind() {
+	r.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		for _, s := range r.Services() {
+			if starter, ok := s.(Starter); ok {
+				if err := starter.Start(e.App); err != nil {
+					return fmt.Errorf("service %q failed to start: %w", s.Name(), err)
+				}
+			}
+
+			s.RegisterRoutes(r.scoped(e, s.Name()))
+			logging.Infof("service: mounted %s at %s", s.Name(), r.Path(s.Name()))
+		}
+		return e.Next()
+	})
+
+	r.app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		services := r.Services()
+		for i := len(services) - 1; i >= 0; i-- {
+			stopper, ok := services[i].(Stopper)
+			if !ok {
+				continue
+			}
+			if err := stopper.Stop(); err != nil {
+				logging.Errorf(err, "service: %s failed to stop", services[i].Name())
+			} else {
+				logging.Infof("service: stopped %s", services[i].Name())
+			}
+		}
+		return e.Next()
+	})
 }
 
-func NewService(app *pocketbase.PocketBase) *Service {
-	return &Service{
-		app: app,
+// scoped returns a copy of e whose Router is a group rooted at the
+// service's mount path.
+func (r *Registry) scoped(e *core.ServeEvent, name string) *core.ServeEvent {
+	group := e.Router.Group(r.Path(name))
+	return &core.ServeEvent{
+		App:         e.App,
+		Router:      &router.Router[*core.RequestEvent]{RouterGroup: group},
+		Server:      e.Server,
+		CertManager: e.CertManager,
 	}
 }
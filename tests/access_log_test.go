@@ -0,0 +1,82 @@
+package tests_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/logging"
+	"pocketbase-server/server/middleware"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = logging.NewLogger(logging.WithWriter(&buf))
+	defer func() { log.Logger = previous }()
+
+	appFactory := func(t testing.TB) *pbtests.TestApp {
+		app, err := pbtests.NewTestApp()
+		require.NoError(t, err)
+
+		app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+			e.Router.Bind(middleware.AccessLog())
+			e.Router.GET("/api/orgs/{orgId}/ping", func(re *core.RequestEvent) error {
+				logging.FromContext(re.Request.Context()).Info().Msg("from handler")
+				return re.JSON(http.StatusOK, map[string]any{"ok": true})
+			})
+			return e.Next()
+		})
+		return app
+	}
+
+	scenarios := []pbtests.ApiScenario{
+		{
+			Name:            "propagates the incoming request id",
+			Method:          http.MethodGet,
+			URL:             "/api/orgs/org123/ping",
+			Headers:         map[string]string{middleware.RequestIDHeader: "req-abc"},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"ok":true`},
+			AfterTestFunc: func(t testing.TB, app *pbtests.TestApp, res *http.Response) {
+				assert.Equal(t, "req-abc", res.Header.Get(middleware.RequestIDHeader))
+
+				lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+				require.Len(t, lines, 2)
+				assert.Contains(t, lines[0], `"message":"from handler"`)
+				assert.Contains(t, lines[0], `"request_id":"req-abc"`)
+				for _, field := range []string{`"request_id":"req-abc"`, `"status":200`, `"org_id":"org123"`, `"bytes":12`, `"latency":`} {
+					assert.Contains(t, lines[1], field)
+				}
+			},
+		},
+		{
+			Name:            "generates a request id and logs the error status",
+			Method:          http.MethodGet,
+			URL:             "/api/collections/missing/records",
+			ExpectedStatus:  http.StatusNotFound,
+			ExpectedContent: []string{`"data":{}`},
+			BeforeTestFunc: func(t testing.TB, app *pbtests.TestApp, e *core.ServeEvent) {
+				buf.Reset()
+			},
+			AfterTestFunc: func(t testing.TB, app *pbtests.TestApp, res *http.Response) {
+				id := res.Header.Get(middleware.RequestIDHeader)
+				assert.Len(t, id, 36)
+				assert.Contains(t, buf.String(), `"request_id":"`+id+`"`)
+				assert.Contains(t, buf.String(), `"status":404`)
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.TestAppFactory = appFactory
+		scenario.Test(t)
+	}
+}
@@ -0,0 +1,474 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/billing"
+	"pocketbase-server/pb/collections/entitlements"
+	"pocketbase-server/pb/collections/notifications"
+	"pocketbase-server/pb/collections/organizations"
+	"pocketbase-server/pb/collections/realestate"
+	"pocketbase-server/pb/collections/roles"
+	"pocketbase-server/pb/collections/users"
+	"pocketbase-server/pb/collections/visibility"
+)
+
+var updateAccess = flag.Bool("update-access", false, "regenerate the access tables in pb/collections/README.md")
+
+// accessReadme holds the tables generated from accessMatrix, between the
+// two markers.
+const (
+	accessReadme      = "../pb/collections/README.md"
+	accessMarkerStart = "<!-- access-matrix:start -->"
+	accessMarkerEnd   = "<!-- access-matrix:end -->"
+)
+
+type actor string
+
+const (
+	anonymous     actor = "Anonymous"
+	outsider      actor = "User"
+	orgMember     actor = "Org member"
+	orgAdmin      actor = "Org admin"
+	orgOwner      actor = "Org owner"
+	platformAdmin actor = "Platform admin"
+	superuser     actor = "Superuser"
+)
+
+var actors = []actor{anonymous, outsider, orgMember, orgAdmin, orgOwner, platformAdmin, superuser}
+
+type action string
+
+const (
+	actionList   action = "List"
+	actionView   action = "View"
+	actionCreate action = "Create"
+	actionUpdate action = "Update"
+	actionDelete action = "Delete"
+)
+
+var actions = []action{actionList, actionView, actionCreate, actionUpdate, actionDelete}
+
+// Actor groups used by the matrix.
+var (
+	signedIn    = []actor{outsider, orgMember, orgAdmin, orgOwner, platformAdmin, superuser}
+	members     = []actor{orgMember, orgAdmin, orgOwner, superuser}
+	orgAdmins   = []actor{orgAdmin, orgOwner, superuser}
+	orgWriters  = []actor{orgAdmin, orgOwner, platformAdmin, superuser}
+	recordOwner = []actor{orgMember, platformAdmin, superuser}
+	superusers  = []actor{superuser}
+)
+
+// access is who may perform an action, and how the README describes it.
+type access struct {
+	rule    string
+	allowed []actor
+}
+
+// accessCollection is one collection of the matrix. The fixture's org
+// member owns the target records of users and settings.
+type accessCollection struct {
+	name string
+	// target is the fixture record that list, view, update and delete use.
+	target string
+	// create returns the body actor sends to create a record.
+	create func(f *accessFixture, a actor) map[string]any
+	// beforeCreate prepares the app for actor's create request.
+	beforeCreate func(t testing.TB, app core.App, f *accessFixture, a actor)
+	update       map[string]any
+	access       map[action]access
+	// note follows the table in the README.
+	note string
+}
+
+var accessMatrix = []accessCollection{
+	{
+		name:   "organizations",
+		target: "org",
+		create: func(f *accessFixture, a actor) map[string]any {
+			return map[string]any{"name": "New Org", "slug": "new-org"}
+		},
+		update: map[string]any{"name": "Renamed Org"},
+		access: map[action]access{
+			actionList:   {"User is a member of the org", members},
+			actionView:   {"User is a member of the org", members},
+			actionCreate: {"Any authenticated user", signedIn},
+			actionUpdate: {"Org owner or admin", orgAdmins},
+			actionDelete: {"Org owner only", []actor{orgOwner, superuser}},
+		},
+	},
+	{
+		name:   "org_members",
+		target: "membership",
+		create: func(f *accessFixture, a actor) map[string]any {
+			return map[string]any{"organization": f.ids["org"], "user": f.users[outsider], "role": roles.OrgMember}
+		},
+		update: map[string]any{"role": roles.OrgAdmin},
+		access: map[action]access{
+			actionList:   {"User is a member of the org", members},
+			actionView:   {"User is a member of the org", members},
+			actionCreate: {"Org owner or admin", orgAdmins},
+			actionUpdate: {"Org owner or admin", orgAdmins},
+			actionDelete: {"Org owner or admin", orgAdmins},
+		},
+		note: "Unique constraint: one membership per user per organization.",
+	},
+	{
+		name:   "org_settings",
+		target: "org_settings",
+		create: func(f *accessFixture, a actor) map[string]any {
+			return map[string]any{"organization": f.ids["spare_org"]}
+		},
+		update: map[string]any{"billing_email": "billing@acme.test"},
+		access: map[action]access{
+			actionList:   {"User is a member of the org", members},
+			actionView:   {"User is a member of the org", members},
+			actionCreate: {"Server only (created with the org)", superusers},
+			actionUpdate: {"Org owner or admin", orgAdmins},
+			actionDelete: {"Server only", superusers},
+		},
+		note: "Unique constraint: one settings record per organization.",
+	},
+	{
+		name:   "org_invites",
+		target: "invite",
+		create: func(f *accessFixture, a actor) map[string]any {
+			return map[string]any{"organization": f.ids["org"], "email": "invitee@acme.test", "role": roles.OrgMember}
+		},
+		update: map[string]any{"role": roles.OrgAdmin},
+		access: map[action]access{
+			actionList:   {"Org owner or admin", orgAdmins},
+			actionView:   {"Org owner or admin", orgAdmins},
+			actionCreate: {"Org owner or admin", orgAdmins},
+			actionUpdate: {"Org owner or admin", orgAdmins},
+			actionDelete: {"Org owner or admin", orgAdmins},
+		},
+	},
+	{
+		name:   "properties",
+		target: "property",
+		create: func(f *accessFixture, a actor) map[string]any {
+			return map[string]any{"organization": f.ids["org"], "property_name": "Annex", "address": "2 Main St", "city": "Springfield"}
+		},
+		update: map[string]any{"property_name": "Renamed"},
+		access: map[action]access{
+			actionList:   {"Anyone", actors},
+			actionView:   {"Anyone", actors},
+			actionCreate: {"Org owner or admin, or platform admin", orgWriters},
+			actionUpdate: {"Org owner or admin, or platform admin", orgWriters},
+			actionDelete: {"Org owner or admin, or platform admin", orgWriters},
+		},
+	},
+	{
+		name:   "settings",
+		target: "settings",
+		// Actors create their own settings; the others try the member's.
+		create: func(f *accessFixture, a actor) map[string]any {
+			return map[string]any{"user": f.settingsUser(a), "theme": "dark"}
+		},
+		beforeCreate: func(t testing.TB, app core.App, f *accessFixture, a actor) {
+			existing, err := app.FindFirstRecordByData("settings", "user", f.settingsUser(a))
+			require.NoError(t, err)
+			require.NoError(t, app.Delete(existing))
+		},
+		update: map[string]any{"theme": "light"},
+		access: map[action]access{
+			actionList:   {"Owner or platform admin", recordOwner},
+			actionView:   {"Owner or platform admin", recordOwner},
+			actionCreate: {"Own settings, or platform admin", signedIn},
+			actionUpdate: {"Owner or platform admin", recordOwner},
+			actionDelete: {"Owner or platform admin", recordOwner},
+		},
+		note: "Unique constraint: one settings record per user.",
+	},
+	{
+		name:   "users",
+		target: "user",
+		create: func(f *accessFixture, a actor) map[string]any {
+			return map[string]any{"email": "new@acme.test", "password": "password123", "passwordConfirm": "password123"}
+		},
+		update: map[string]any{"name": "Renamed"},
+		access: map[action]access{
+			actionList:   {"Self or platform admin", recordOwner},
+			actionView:   {"Self or platform admin", recordOwner},
+			actionCreate: {"Anyone (sign-up)", actors},
+			actionUpdate: {"Self or platform admin", recordOwner},
+			actionDelete: {"Self or platform admin", recordOwner},
+		},
+	},
+}
+
+// accessFixture is a data dir holding an organization with a member, an
+// admin and an owner, a signed-in outsider, a platform admin and a
+// superuser, plus a target record per collection and a property contact.
+// Each scenario runs on a copy of it.
+type accessFixture struct {
+	dir    string
+	users  map[actor]string
+	tokens map[actor]string
+	ids    map[string]string
+}
+
+// settingsUser returns whose settings actor creates: its own, or the
+// member's for actors without a users record.
+func (f *accessFixture) settingsUser(a actor) string {
+	if id, ok := f.users[a]; ok {
+		return id
+	}
+	return f.users[orgMember]
+}
+
+func newAccessFixture(t *testing.T) (*accessFixture, func()) {
+	app, cleanup := bootstrapApp(t)
+	f := &accessFixture{
+		dir:    app.DataDir(),
+		users:  map[actor]string{},
+		tokens: map[actor]string{},
+		ids:    map[string]string{},
+	}
+
+	save := func(collection string, data map[string]any) *core.Record {
+		t.Helper()
+		col, err := app.FindCollectionByNameOrId(collection)
+		require.NoError(t, err)
+		record := core.NewRecord(col)
+		for k, v := range data {
+			record.Set(k, v)
+		}
+		require.NoError(t, app.Save(record), collection)
+		return record
+	}
+	token := func(a actor, record *core.Record) {
+		t.Helper()
+		tok, err := record.NewAuthToken()
+		require.NoError(t, err)
+		f.tokens[a] = tok
+	}
+
+	for _, a := range []actor{outsider, orgMember, orgAdmin, orgOwner, platformAdmin} {
+		role := roles.User
+		if a == platformAdmin {
+			role = roles.Admin
+		}
+		email := strings.ReplaceAll(strings.ToLower(string(a)), " ", "-") + "@acme.test"
+		user := save("users", map[string]any{"email": email, "password": "password123", "role": role, "phone": "+15550100"})
+		f.users[a] = user.Id
+		token(a, user)
+	}
+	su := save(core.CollectionNameSuperusers, map[string]any{"email": "root@acme.test", "password": "password123"})
+	token(superuser, su)
+
+	org := save("organizations", map[string]any{"name": "Acme", "slug": "acme"})
+	f.ids["org"] = org.Id
+	f.ids["spare_org"] = save("organizations", map[string]any{"name": "Spare", "slug": "spare"}).Id
+	for a, role := range map[actor]string{orgMember: roles.OrgMember, orgAdmin: roles.OrgAdmin, orgOwner: roles.OrgOwner} {
+		membership := save("org_members", map[string]any{"organization": org.Id, "user": f.users[a], "role": role})
+		if a == orgMember {
+			f.ids["membership"] = membership.Id
+		}
+	}
+	f.ids["org_settings"] = save("org_settings", map[string]any{"organization": org.Id}).Id
+	f.ids["invite"] = save("org_invites", map[string]any{
+		"organization": org.Id, "email": "pending@acme.test", "role": roles.OrgMember,
+		"token": "fixture", "status": "pending", "expires_at": "2030-01-01 00:00:00.000Z",
+	}).Id
+	f.ids["property"] = save("properties", map[string]any{
+		"organization": org.Id, "property_name": "HQ", "address": "1 Main St", "city": "Springfield",
+	}).Id
+	f.ids["contact"] = save("property_contacts", map[string]any{
+		"property": f.ids["property"], "role": "listing_agent", "name": "Pat Agent",
+		"phone": "+15550199", "email": "pat@brokerage.test", "license_number": "LIC-12345",
+	}).Id
+	settings, err := app.FindFirstRecordByData("settings", "user", f.users[orgMember])
+	require.NoError(t, err)
+	f.ids["settings"] = settings.Id
+	f.ids["user"] = f.users[orgMember]
+
+	// Close the databases so copies of the data dir are complete.
+	require.NoError(t, app.ResetBootstrapState())
+	return f, cleanup
+}
+
+// newApp returns a copy of the fixture with the server's record hooks.
+func (f *accessFixture) newApp(t testing.TB) *pbtests.TestApp {
+	app, err := pbtests.NewTestApp(f.dir)
+	require.NoError(t, err)
+	users.RegisterHooks(app)
+	organizations.RegisterHooks(app)
+	organizations.RegisterInviteHooks(app)
+	entitlements.RegisterHooks(app)
+	billing.RegisterHooks(app)
+	notifications.RegisterHooks(app)
+	realestate.RegisterSavedPropertyHooks(app)
+	visibility.RegisterHooks(app)
+	return app
+}
+
+// scenario returns the request a does for c, expecting it to succeed when
+// allowed. Denied requests fail the way PocketBase reports rule failures:
+// 403 for superuser-only rules, otherwise an empty list, 400 on create and
+// 404 for the rest.
+func (f *accessFixture) scenario(t *testing.T, c accessCollection, act action, a actor, superuserOnly bool) pbtests.ApiScenario {
+	target := f.ids[c.target]
+	base := "/api/collections/" + c.name + "/records"
+	s := pbtests.ApiScenario{
+		Name:           fmt.Sprintf("%s %s %s", a, strings.ToLower(string(act)), c.name),
+		TestAppFactory: f.newApp,
+	}
+	if tok, ok := f.tokens[a]; ok {
+		s.Headers = map[string]string{"Authorization": tok}
+	}
+
+	switch act {
+	case actionList:
+		s.Method, s.URL = http.MethodGet, base+"?perPage=500"
+	case actionView:
+		s.Method, s.URL = http.MethodGet, base+"/"+target
+	case actionCreate:
+		body, err := json.Marshal(c.create(f, a))
+		require.NoError(t, err)
+		s.Method, s.URL, s.Body = http.MethodPost, base, strings.NewReader(string(body))
+		s.Headers = withJSON(s.Headers)
+		if c.beforeCreate != nil {
+			s.BeforeTestFunc = func(t testing.TB, app *pbtests.TestApp, e *core.ServeEvent) {
+				c.beforeCreate(t, app, f, a)
+			}
+		}
+	case actionUpdate:
+		body, err := json.Marshal(c.update)
+		require.NoError(t, err)
+		s.Method, s.URL, s.Body = http.MethodPatch, base+"/"+target, strings.NewReader(string(body))
+		s.Headers = withJSON(s.Headers)
+	case actionDelete:
+		s.Method, s.URL = http.MethodDelete, base+"/"+target
+	}
+
+	idContent := []string{fmt.Sprintf(`"id":%q`, target)}
+	switch allowed := slices.Contains(c.access[act].allowed, a); {
+	case allowed && act == actionDelete:
+		s.ExpectedStatus = http.StatusNoContent
+	case allowed && act == actionCreate:
+		s.ExpectedStatus = http.StatusOK
+		s.ExpectedContent = []string{fmt.Sprintf(`"collectionName":%q`, c.name)}
+	case allowed:
+		s.ExpectedStatus = http.StatusOK
+		s.ExpectedContent = idContent
+	case superuserOnly:
+		s.ExpectedStatus = http.StatusForbidden
+		s.ExpectedContent = []string{`"status":403`}
+	case act == actionList:
+		s.ExpectedStatus = http.StatusOK
+		s.NotExpectedContent = idContent
+	case act == actionCreate:
+		s.ExpectedStatus = http.StatusBadRequest
+		s.ExpectedContent = []string{`"status":400`}
+	default:
+		s.ExpectedStatus = http.StatusNotFound
+		s.ExpectedContent = []string{`"status":404`}
+	}
+	return s
+}
+
+func withJSON(headers map[string]string) map[string]string {
+	h := map[string]string{"Content-Type": "application/json"}
+	for k, v := range headers {
+		h[k] = v
+	}
+	return h
+}
+
+// ruleOf returns the rule of act on collection.
+func ruleOf(col *core.Collection, act action) *string {
+	return map[action]*string{
+		actionList:   col.ListRule,
+		actionView:   col.ViewRule,
+		actionCreate: col.CreateRule,
+		actionUpdate: col.UpdateRule,
+		actionDelete: col.DeleteRule,
+	}[act]
+}
+
+// TestAccessMatrix runs every actor × collection × action of accessMatrix
+// against the API. Run it with -update-access to regenerate the README
+// tables after changing the matrix.
+func TestAccessMatrix(t *testing.T) {
+	f, cleanup := newAccessFixture(t)
+	defer cleanup()
+
+	for _, c := range accessMatrix {
+		t.Run(c.name, func(t *testing.T) {
+			app := f.newApp(t)
+			col, err := app.FindCollectionByNameOrId(c.name)
+			require.NoError(t, err)
+			app.Cleanup()
+
+			for _, act := range actions {
+				superuserOnly := ruleOf(col, act) == nil
+				for _, a := range actors {
+					scenario := f.scenario(t, c, act, a, superuserOnly)
+					scenario.Test(t)
+				}
+			}
+		})
+	}
+
+	t.Run("readme", func(t *testing.T) {
+		raw, err := os.ReadFile(accessReadme)
+		require.NoError(t, err)
+		readme := string(raw)
+		start := strings.Index(readme, accessMarkerStart)
+		end := strings.Index(readme, accessMarkerEnd)
+		require.True(t, start >= 0 && end > start, "access markers in %s", accessReadme)
+
+		updated := readme[:start+len(accessMarkerStart)] + "\n" + accessTables() + readme[end:]
+		if *updateAccess {
+			require.NoError(t, os.WriteFile(accessReadme, []byte(updated), 0o644))
+			return
+		}
+		require.Equal(t, updated, readme, "stale access tables; run go test ./tests -run TestAccessMatrix -update-access")
+	})
+}
+
+// accessTables renders accessMatrix as markdown.
+func accessTables() string {
+	var b strings.Builder
+	b.WriteString("\n_Generated from the access matrix in `tests/access_test.go`. " +
+		"User is signed in but not in the org; the org roles are memberships of the record's org. " +
+		"The settings and users rows are about the org member's own record._\n")
+	for _, c := range accessMatrix {
+		fmt.Fprintf(&b, "\n### %s\n\n| Action | Rule |", c.name)
+		for _, a := range actors {
+			fmt.Fprintf(&b, " %s |", a)
+		}
+		b.WriteString("\n|--------|------|")
+		b.WriteString(strings.Repeat("---|", len(actors)))
+		b.WriteString("\n")
+		for _, act := range actions {
+			fmt.Fprintf(&b, "| %s | %s |", act, c.access[act].rule)
+			for _, a := range actors {
+				mark := ""
+				if slices.Contains(c.access[act].allowed, a) {
+					mark = "✓"
+				}
+				fmt.Fprintf(&b, " %s |", mark)
+			}
+			b.WriteString("\n")
+		}
+		if c.note != "" {
+			fmt.Fprintf(&b, "\n%s\n", c.note)
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,229 @@
+package tests_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/backup"
+)
+
+func newBackupManager(t *testing.T, app *pbtests.TestApp, dir string, retention backup.Retention) *backup.Manager {
+	t.Helper()
+	return backup.New(backup.Config{
+		App:       app,
+		Store:     func() (*filesystem.System, error) { return filesystem.NewLocal(dir) },
+		Retention: retention,
+	})
+}
+
+func uploadStorageFile(t *testing.T, app *pbtests.TestApp, key, content string) {
+	t.Helper()
+	fsys, err := app.NewFilesystem()
+	require.NoError(t, err)
+	defer fsys.Close()
+	require.NoError(t, fsys.Upload([]byte(content), key))
+}
+
+func TestBackupCreateVerifyRestore(t *testing.T) {
+	app, err := pbtests.NewTestApp()
+	require.NoError(t, err)
+	defer app.Cleanup()
+
+	ctx := context.Background()
+	uploadStorageFile(t, app, "col/rec/avatar.txt", "hello")
+
+	manager := newBackupManager(t, app, t.TempDir(), backup.Retention{KeepLast: 5})
+	info, err := manager.Create(ctx)
+	require.NoError(t, err)
+
+	backups, err := manager.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, info.Id, backups[0].Id)
+
+	manifest, err := manager.Verify(ctx, info.Id)
+	require.NoError(t, err)
+	var names []string
+	stored := 0
+	for _, f := range manifest.Files {
+		names = append(names, f.Name)
+		if strings.HasPrefix(f.Name, backup.StorageDir) {
+			stored++
+		}
+	}
+	assert.Contains(t, names, backup.DataDB)
+	assert.Contains(t, names, backup.AuxDB)
+	assert.Contains(t, names, backup.StorageDir+"col/rec/avatar.txt")
+
+	_, err = manager.Verify(ctx, "20000101T000000.000Z")
+	assert.ErrorIs(t, err, backup.ErrNotFound)
+
+	found, err := manager.Find(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, info.Id, found.Id)
+	_, err = manager.Find(ctx, info.Created.Add(-time.Hour))
+	assert.ErrorIs(t, err, backup.ErrNotFound)
+
+	// Restore into a separate data dir and the app's (emptied) storage.
+	dir := t.TempDir()
+	_, err = manager.Extract(ctx, info.Id, dir)
+	require.NoError(t, err)
+
+	dataDir := t.TempDir()
+	restored, err := backup.RestoreDatabases(dir, dataDir, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{backup.AuxDB}, restored)
+	assert.NoFileExists(t, filepath.Join(dataDir, backup.DataDB))
+
+	restored, err = backup.RestoreDatabases(dir, dataDir, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{backup.DataDB, backup.AuxDB}, restored)
+
+	fsys, err := app.NewFilesystem()
+	require.NoError(t, err)
+	require.NoError(t, fsys.Delete("col/rec/avatar.txt"))
+	fsys.Close()
+
+	n, err := backup.RestoreFiles(ctx, app, dir)
+	require.NoError(t, err)
+	assert.Equal(t, stored, n)
+
+	fsys, err = app.NewFilesystem()
+	require.NoError(t, err)
+	defer fsys.Close()
+	ok, err := fsys.Exists("col/rec/avatar.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestBackupTampered(t *testing.T) {
+	app, err := pbtests.NewTestApp()
+	require.NoError(t, err)
+	defer app.Cleanup()
+
+	ctx := context.Background()
+	uploadStorageFile(t, app, "col/rec/doc.txt", "original")
+
+	storeDir := t.TempDir()
+	manager := newBackupManager(t, app, storeDir, backup.Retention{KeepLast: 5})
+	info, err := manager.Create(ctx)
+	require.NoError(t, err)
+
+	// Rewrite the archive with one entry changed but the manifest intact.
+	path := filepath.Join(storeDir, info.Key)
+	rewriteArchive(t, path, backup.StorageDir+"col/rec/doc.txt", "tampered")
+
+	_, err = manager.Verify(ctx, info.Id)
+	assert.ErrorIs(t, err, backup.ErrCorrupt)
+
+	require.NoError(t, os.WriteFile(path, []byte("not a gzip stream"), 0644))
+	_, err = manager.Verify(ctx, info.Id)
+	assert.ErrorIs(t, err, backup.ErrCorrupt)
+}
+
+func rewriteArchive(t *testing.T, path, name, content string) {
+	t.Helper()
+
+	in, err := os.Open(path)
+	require.NoError(t, err)
+	gz, err := gzip.NewReader(in)
+	require.NoError(t, err)
+
+	type entry struct {
+		hdr  *tar.Header
+		data []byte
+	}
+	var entries []entry
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		if hdr.Name == name {
+			data = []byte(content)
+			hdr.Size = int64(len(data))
+		}
+		entries = append(entries, entry{hdr, data})
+	}
+	in.Close()
+
+	out, err := os.Create(path)
+	require.NoError(t, err)
+	defer out.Close()
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		require.NoError(t, tw.WriteHeader(e.hdr))
+		_, err := tw.Write(e.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+}
+
+func TestBackupRetention(t *testing.T) {
+	app, err := pbtests.NewTestApp()
+	require.NoError(t, err)
+	defer app.Cleanup()
+
+	ctx := context.Background()
+	manager := newBackupManager(t, app, t.TempDir(), backup.Retention{KeepLast: 2})
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		info, err := manager.Create(ctx)
+		require.NoError(t, err)
+		ids = append(ids, info.Id)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	backups, err := manager.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, ids[2], backups[0].Id)
+	assert.Equal(t, ids[1], backups[1].Id)
+
+	// MaxAge never deletes the newest backup.
+	manager = newBackupManager(t, app, t.TempDir(), backup.Retention{KeepLast: 5, MaxAge: time.Nanosecond})
+	_, err = manager.Create(ctx)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	info, err := manager.Create(ctx)
+	require.NoError(t, err)
+
+	backups, err = manager.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, info.Id, backups[0].Id)
+}
+
+func TestBackupInstanceLock(t *testing.T) {
+	dir := t.TempDir()
+
+	unlock, err := backup.LockInstance(dir)
+	require.NoError(t, err)
+
+	_, err = backup.LockInstance(dir)
+	assert.ErrorIs(t, err, backup.ErrInstanceRunning)
+
+	unlock()
+	unlock, err = backup.LockInstance(dir)
+	require.NoError(t, err)
+	unlock()
+}
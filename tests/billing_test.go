@@ -0,0 +1,132 @@
+package tests_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/webhooks"
+	"pocketbase-server/pb/collections/billing"
+	"pocketbase-server/pb/collections/entitlements"
+)
+
+func TestBilling(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	provider := billing.NewFakeProvider("test-secret")
+	webhooks.Register(provider)
+	webhook := webhooks.Handle(provider.Name(), billing.HandleEvent(app, provider))
+
+	usersCol, err := app.FindCollectionByNameOrId("users")
+	require.NoError(t, err)
+	owner := core.NewRecord(usersCol)
+	owner.SetEmail("billing-owner@example.com")
+	owner.SetPassword("password1234!")
+	owner.Set("role", "user")
+	require.NoError(t, app.Save(owner))
+
+	org, err := app.FindFirstRecordByFilter("organizations", "slug = {:slug}", dbx.Params{"slug": owner.Id})
+	require.NoError(t, err)
+
+	settings := func() *core.Record {
+		record, err := app.FindFirstRecordByFilter("org_settings", "organization = {:org}", dbx.Params{"org": org.Id})
+		require.NoError(t, err)
+		return record
+	}
+
+	send := func(body string, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/billing/webhook/fake", strings.NewReader(body))
+		req.Header.Set(billing.FakeSignatureHeader, signature)
+		rec := httptest.NewRecorder()
+		webhook(rec, req)
+		return rec.Code
+	}
+	event := func(typ, plan string) string {
+		return `{"type":"` + typ + `","org_id":"` + org.Id + `","plan":"` + plan + `","subscription_id":"sub_1","status":"active"}`
+	}
+
+	t.Run("rejects unknown and current plans", func(t *testing.T) {
+		_, err := billing.RequestPlanChange(context.Background(), app, provider, org.Id, "platinum", owner.Id)
+		assert.ErrorIs(t, err, billing.ErrUnknownPlan)
+
+		_, err = billing.RequestPlanChange(context.Background(), app, provider, org.Id, entitlements.DefaultPlan, owner.Id)
+		assert.ErrorIs(t, err, billing.ErrSamePlan)
+	})
+
+	t.Run("upgrade is pending until the webhook confirms it", func(t *testing.T) {
+		result, err := billing.RequestPlanChange(context.Background(), app, provider, org.Id, "pro", owner.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", result.Status)
+		require.Len(t, provider.Requests(), 1)
+		assert.Equal(t, "pro", provider.Requests()[0].Plan)
+
+		assert.Equal(t, "pro", settings().GetString("pending_plan"))
+		assert.Equal(t, entitlements.DefaultPlan, settings().GetString("billing_plan"))
+
+		body := event(billing.EventSubscriptionUpdated, "pro")
+		assert.Equal(t, http.StatusOK, send(body, provider.Sign([]byte(body))))
+
+		record := settings()
+		assert.Equal(t, "pro", record.GetString("billing_plan"))
+		assert.Empty(t, record.GetString("pending_plan"))
+		assert.Equal(t, "sub_1", record.GetString("subscription_id"))
+
+		ent, err := entitlements.For(app, org.Id)
+		require.NoError(t, err)
+		assert.Equal(t, 50, ent.Limits[entitlements.MaxMembers])
+	})
+
+	t.Run("rejects unsigned webhooks", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, send(event(billing.EventSubscriptionCanceled, ""), "bad"))
+		assert.Equal(t, "pro", settings().GetString("billing_plan"))
+	})
+
+	t.Run("refuses downgrades below current usage", func(t *testing.T) {
+		record := settings()
+		record.Set("features", map[string]any{entitlements.MaxMembers: 50})
+		require.NoError(t, app.Save(record))
+
+		membersCol, err := app.FindCollectionByNameOrId("org_members")
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			user := core.NewRecord(usersCol)
+			user.SetEmail("billing-member" + string(rune('a'+i)) + "@example.com")
+			user.SetPassword("password1234!")
+			user.Set("role", "user")
+			require.NoError(t, app.Save(user))
+
+			member := core.NewRecord(membersCol)
+			member.Set("user", user.Id)
+			member.Set("organization", org.Id)
+			member.Set("role", "member")
+			require.NoError(t, app.Save(member))
+		}
+
+		_, err = billing.RequestPlanChange(context.Background(), app, provider, org.Id, "free", owner.Id)
+		var downgrade *billing.DowngradeError
+		require.True(t, errors.As(err, &downgrade), "expected a downgrade error, got %v", err)
+		assert.Equal(t, entitlements.MaxMembers, downgrade.Feature)
+		assert.Equal(t, 6, downgrade.Used)
+
+		_, err = billing.RequestPlanChange(context.Background(), app, provider, org.Id, "starter", owner.Id)
+		assert.NoError(t, err)
+	})
+
+	t.Run("cancellation falls back to the free plan", func(t *testing.T) {
+		body := event(billing.EventSubscriptionCanceled, "")
+		assert.Equal(t, http.StatusOK, send(body, provider.Sign([]byte(body))))
+
+		record := settings()
+		assert.Equal(t, entitlements.DefaultPlan, record.GetString("billing_plan"))
+		assert.Empty(t, record.GetString("pending_plan"))
+	})
+}
@@ -14,10 +14,12 @@ import (
 
 	"pocketbase-server/pb/collections/organizations"
 	"pocketbase-server/pb/collections/users"
+	"pocketbase-server/server"
 )
 
 // bootstrapApp creates a real SQLite-backed PocketBase test app in a local
-// temp directory. It creates all custom collections and registers hooks.
+// temp directory. It bootstraps every registered collection like the
+// server and registers hooks.
 //
 // The SQLite file lives at <os.TempDir>/pb_test_<random>/pb_data/data.db
 // so you can inspect it after a test run if needed.
@@ -34,16 +36,10 @@ func bootstrapApp(t *testing.T) (*pbtests.TestApp, func()) {
 	app, err := pbtests.NewTestApp(dir)
 	require.NoError(t, err)
 
-	// Phase 1: create collections in dependency order
-	// (users is built-in — EnsureCollection just adds custom fields)
-	require.NoError(t, users.EnsureCollection(app), "users.EnsureCollection")
-	require.NoError(t, users.EnsureSettings(app), "users.EnsureSettings")
-	require.NoError(t, organizations.EnsureCollection(app), "organizations.EnsureCollection")
-	require.NoError(t, organizations.EnsureMembers(app), "organizations.EnsureMembers")
-	require.NoError(t, organizations.EnsureOrgSettings(app), "organizations.EnsureOrgSettings")
-	require.NoError(t, organizations.EnsureInvites(app), "organizations.EnsureInvites")
+	// The same collection setup as the server
+	require.NoError(t, server.BootstrapCollections(app, true))
 
-	// Phase 2: register hooks
+	// Register hooks
 	// NOTE: OnRecordCreateRequest / OnRecordUpdateRequest are HTTP-only and
 	// won't fire on direct app.Save() calls — only OnRecordCreate /
 	// OnRecordUpdate fire in these tests.
@@ -191,6 +187,39 @@ func TestUserSignupAutoCreates(t *testing.T) {
 	})
 }
 
+// The hooks must write through the event's app, or a user created inside a
+// transaction (e.g. by the seed command) blocks on the outer connection.
+func TestUserSignupInTransaction(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	usersCol, err := app.FindCollectionByNameOrId("users")
+	require.NoError(t, err)
+
+	user := core.NewRecord(usersCol)
+	user.SetEmail("bob@example.com")
+	user.SetPassword("password1234!")
+	user.Set("role", "user")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.RunInTransaction(func(txApp core.App) error {
+			return txApp.Save(user)
+		})
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("saving a user in a transaction blocked")
+	}
+
+	_, err = app.FindFirstRecordByFilter("settings", "user = {:userId}", dbx.Params{"userId": user.Id})
+	assert.NoError(t, err, "settings record should exist")
+	_, err = app.FindFirstRecordByFilter("organizations", "slug = {:slug}", dbx.Params{"slug": user.Id})
+	assert.NoError(t, err, "personal org should exist")
+}
+
 // --------------------------------------------------------------------------
 // Invite flow: create invite → accept → org_member created
 // --------------------------------------------------------------------------
@@ -0,0 +1,110 @@
+package tests_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/config"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestConfigLayering(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := config.Load(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "0.0.0.0:8080", cfg.Server.Addr)
+		assert.Equal(t, 30*time.Second, cfg.LibSQL.SyncInterval)
+	})
+
+	t.Run("file < env < flags", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", `
+server:
+  addr: 127.0.0.1:9000
+  data_dir: /tmp/from-file
+libsql:
+  sync_interval: 10s
+log:
+  level: debug
+`)
+		t.Setenv("PB_DATA_DIR", "/tmp/from-env")
+		t.Setenv("LOG_LEVEL", "warn")
+
+		cfg, err := config.Load([]string{"serve", "--config", path, "--log-level", "error", "--unknown"})
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:9000", cfg.Server.Addr, "file overrides default")
+		assert.Equal(t, 10*time.Second, cfg.LibSQL.SyncInterval, "file duration")
+		assert.Equal(t, "/tmp/from-env", cfg.Server.DataDir, "env overrides file")
+		assert.Equal(t, "error", cfg.Log.Level, "flag overrides env")
+	})
+
+	t.Run("toml file", func(t *testing.T) {
+		path := writeConfigFile(t, "config.toml", `
+[server]
+addr = "127.0.0.1:7000"
+`)
+		cfg, err := config.Load([]string{"--config", path})
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:7000", cfg.Server.Addr)
+	})
+}
+
+func TestConfigValidation(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.Addr = "no-port"
+	cfg.LibSQL.URL = "ftp://example.com"
+	cfg.S3.Bucket = "files"
+	cfg.OAuth2.GithubClientID = "id-only"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.True(t, config.IsValidationError(err))
+
+	msg := err.Error()
+	for _, want := range []string{"server.addr", "libsql.url", "s3.region", "s3.secret", "oauth2.github_client_id"} {
+		assert.Contains(t, msg, want)
+	}
+}
+
+func TestConfigRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.Admin.Pass = "supersecret"
+	cfg.LibSQL.Token = "token"
+
+	r := cfg.Redacted()
+	assert.NotEqual(t, "supersecret", r.Admin.Pass)
+	assert.NotEqual(t, "token", r.LibSQL.Token)
+	assert.Equal(t, "supersecret", cfg.Admin.Pass, "original is untouched")
+}
+
+func TestConfigEncryptionKey(t *testing.T) {
+	keyFile := writeConfigFile(t, "libsql.key", "  s3cret-key\n")
+
+	cfg := config.Default()
+	cfg.LibSQL.EncryptionKeyFile = keyFile
+	key, err := cfg.LibSQL.ResolveEncryptionKey()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret-key", key, "key file is trimmed")
+	require.NoError(t, cfg.Validate())
+
+	cfg.LibSQL.EncryptionKey = "inline"
+	require.Error(t, cfg.Validate(), "key and key file together")
+
+	cfg.LibSQL.EncryptionKeyFile = ""
+	cfg.LibSQL.Mode = "local"
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only applies to replica mode")
+
+	assert.NotEqual(t, "inline", cfg.Redacted().LibSQL.EncryptionKey)
+}
@@ -0,0 +1,120 @@
+package tests_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/config"
+	"pocketbase-server/internal/database"
+	"pocketbase-server/server"
+)
+
+func TestLibSQLModes(t *testing.T) {
+	t.Run("mode from config", func(t *testing.T) {
+		cases := []struct{ mode, url, want string }{
+			{"", "", "local"},
+			{"", "local", "local"},
+			{"", "libsql://db.turso.io", "replica"},
+			{"remote", "libsql://db.turso.io", "remote"},
+			{"memory", "", "memory"},
+		}
+		for _, c := range cases {
+			cfg := config.LibSQLConfig{Mode: c.mode, URL: c.url}
+			assert.Equal(t, c.want, cfg.EffectiveMode(), "mode %q url %q", c.mode, c.url)
+		}
+
+		cfg := config.Default()
+		cfg.LibSQL.Mode = "remote"
+		cfg.LibSQL.URL = "local"
+		require.Error(t, cfg.Validate())
+	})
+
+	t.Run("local file", func(t *testing.T) {
+		dir := t.TempDir()
+		conn, err := database.NewLibSQLConnection(&database.LibSQLConfig{Mode: database.ModeLocal, DataDir: dir})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.DB.Exec("CREATE TABLE t (a INTEGER)")
+		require.NoError(t, err)
+		require.NoError(t, conn.Ping(context.Background()))
+		require.NoError(t, conn.Sync(), "sync is a no-op")
+		assert.True(t, conn.SyncStatus().LastAttempt.IsZero())
+
+		_, err = os.Stat(filepath.Join(dir, "data.db"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("encryption needs a replica", func(t *testing.T) {
+		_, err := database.NewLibSQLConnection(&database.LibSQLConfig{
+			Mode:          database.ModeLocal,
+			DataDir:       t.TempDir(),
+			EncryptionKey: "key",
+		})
+		assert.Error(t, err)
+
+		err = database.RotateKey(database.LibSQLConfig{Mode: database.ModeLocal, DataDir: t.TempDir()}, "key")
+		assert.Error(t, err)
+	})
+
+	t.Run("in-memory databases are private", func(t *testing.T) {
+		a, err := database.NewLibSQLConnection(&database.LibSQLConfig{Mode: database.ModeMemory})
+		require.NoError(t, err)
+		defer a.Close()
+		b, err := database.NewLibSQLConnection(&database.LibSQLConfig{Mode: database.ModeMemory})
+		require.NoError(t, err)
+		defer b.Close()
+
+		_, err = a.DB.Exec("CREATE TABLE t (a INTEGER)")
+		require.NoError(t, err)
+
+		// visible from other pooled connections, not from another database
+		var conns []*sql.Conn
+		for i := 0; i < 3; i++ {
+			c, err := a.DB.Conn(context.Background())
+			require.NoError(t, err)
+			conns = append(conns, c)
+			var n int
+			require.NoError(t, c.QueryRowContext(context.Background(), "SELECT count(*) FROM t").Scan(&n))
+		}
+		for _, c := range conns {
+			c.Close()
+		}
+
+		_, err = b.DB.Exec("SELECT count(*) FROM t")
+		assert.Error(t, err)
+		assert.False(t, b.Syncs())
+	})
+	t.Run("in-memory server bootstraps", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.DataDir = t.TempDir()
+		cfg.LibSQL.Mode = "memory"
+		srv, err := server.New(cfg)
+		require.NoError(t, err)
+
+		done := make(chan error, 1)
+		go func() {
+			if err := srv.App().Bootstrap(); err != nil {
+				done <- err
+				return
+			}
+			_, err := srv.App().CountRecords("_superusers")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("bootstrap blocked on the in-memory database")
+		}
+		require.NoError(t, srv.App().ResetBootstrapState())
+		require.NoError(t, srv.Shutdown())
+	})
+}
@@ -0,0 +1,79 @@
+package tests_test
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/rules"
+)
+
+func lookupSpecs(t *testing.T, names ...string) []patch.Spec {
+	t.Helper()
+	specs := make([]patch.Spec, len(names))
+	for i, name := range names {
+		s, ok := patch.LookupSpec(name)
+		require.True(t, ok, name)
+		specs[i] = s
+	}
+	return specs
+}
+
+func TestSchemaDrift(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	specs := lookupSpecs(t, "organizations", "org_members")
+	drift, err := patch.DetectDrift(app, specs)
+	require.NoError(t, err)
+	for _, d := range drift {
+		assert.Equal(t, patch.DriftExtra, d.Status, "only collections outside specs: %s", d)
+	}
+
+	// Admin edits through the UI.
+	col, err := app.FindCollectionByNameOrId("organizations")
+	require.NoError(t, err)
+	col.ListRule = rules.Ptr(rules.AuthOnly)
+	col.Fields.GetByName("state").(*core.TextField).Max = 3
+	col.Fields.Add(&core.TextField{Name: "motto"})
+	col.RemoveIndex("idx_organizations_slug")
+	require.NoError(t, app.Save(col))
+
+	drift, err = patch.DetectDrift(app, specs)
+	require.NoError(t, err)
+	got := map[string]patch.Drift{}
+	for _, d := range drift {
+		if d.Collection == "organizations" {
+			got[d.String()] = d
+		}
+	}
+	require.Len(t, got, 4, "%v", drift)
+	assert.Contains(t, got, "organizations: changed rule listRule")
+	assert.Equal(t, rules.AuthOnly, got["organizations: changed rule listRule"].Actual)
+	assert.Contains(t, got, "organizations: changed field state")
+	assert.Contains(t, got, "organizations: extra field motto")
+	assert.Contains(t, got, "organizations: missing index idx_organizations_slug")
+
+	fixed, err := patch.FixDrift(app, specs)
+	require.NoError(t, err)
+	assert.Len(t, fixed, 3, "extra fields are kept")
+
+	drift, err = patch.DetectDrift(app, specs)
+	require.NoError(t, err)
+	var remaining []string
+	for _, d := range drift {
+		if d.Collection == "organizations" {
+			remaining = append(remaining, d.String())
+		}
+	}
+	assert.Equal(t, []string{"organizations: extra field motto"}, remaining)
+
+	col, err = app.FindCollectionByNameOrId("organizations")
+	require.NoError(t, err)
+	assert.Equal(t, rules.DirectOrgMember, *col.ListRule)
+	assert.Equal(t, 2, col.Fields.GetByName("state").(*core.TextField).Max)
+	assert.NotEmpty(t, col.GetIndex("idx_organizations_slug"))
+}
@@ -0,0 +1,123 @@
+package tests_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb"
+)
+
+func TestEmbeddedHandler(t *testing.T) {
+	app, err := pbtests.NewTestApp()
+	require.NoError(t, err)
+	defer app.Cleanup()
+
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		e.Router.GET("/api/custom/hello", func(re *core.RequestEvent) error {
+			return re.JSON(http.StatusOK, map[string]any{"path": re.Request.URL.Path})
+		})
+		e.Router.GET("/admin", func(re *core.RequestEvent) error {
+			return re.Redirect(http.StatusTemporaryRedirect, "/_/")
+		})
+		return e.Next()
+	})
+
+	mux := http.NewServeMux()
+	require.NoError(t, pb.Mount(mux, app, "/pb"))
+	mux.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	host := httptest.NewServer(mux)
+	defer host.Close()
+
+	client := host.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	get := func(path string) (*http.Response, string) {
+		res, err := client.Get(host.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	t.Run("built-in API under prefix", func(t *testing.T) {
+		res, body := get("/pb/api/health")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, "API is healthy")
+	})
+
+	t.Run("custom routes see the stripped path", func(t *testing.T) {
+		res, body := get("/pb/api/custom/hello")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, `"path":"/api/custom/hello"`)
+	})
+
+	t.Run("admin UI under prefix", func(t *testing.T) {
+		res, body := get("/pb/_/")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, "<title>PocketBase</title>")
+	})
+
+	t.Run("redirects keep the prefix", func(t *testing.T) {
+		res, _ := get("/pb/admin")
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+		assert.Equal(t, "/pb/_/", res.Header.Get("Location"))
+
+		res, _ = get("/pb")
+		assert.Equal(t, "/pb/", res.Header.Get("Location"))
+	})
+
+	t.Run("admin UI headers like serve", func(t *testing.T) {
+		res, _ := get("/pb/_/")
+		assert.Contains(t, res.Header.Get("Content-Security-Policy"), "default-src 'self'")
+		assert.Empty(t, res.Header.Get("Cache-Control"))
+
+		res, _ = get("/pb/_/images/logo.svg")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, res.Header.Get("Cache-Control"), "max-age=")
+	})
+
+	t.Run("CORS from any origin by default", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodOptions, host.URL+"/pb/api/health", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Equal(t, "*", res.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("CORS limited to the allowed origins", func(t *testing.T) {
+		h, err := pb.Handler(app, "/pb", "https://app.example.com")
+		require.NoError(t, err)
+
+		for origin, allowed := range map[string]string{
+			"https://app.example.com": "https://app.example.com",
+			"https://evil.example":    "",
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/pb/api/health", nil)
+			req.Header.Set("Origin", origin)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			assert.Equal(t, allowed, rec.Header().Get("Access-Control-Allow-Origin"), origin)
+		}
+	})
+
+	t.Run("host routes untouched", func(t *testing.T) {
+		res, _ := get("/other")
+		assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	})
+}
@@ -0,0 +1,127 @@
+package tests_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/entitlements"
+)
+
+func TestEntitlements(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	entitlements.RegisterHooks(app)
+
+	usersCol, err := app.FindCollectionByNameOrId("users")
+	require.NoError(t, err)
+	newUser := func(email string) *core.Record {
+		user := core.NewRecord(usersCol)
+		user.SetEmail(email)
+		user.SetPassword("password1234!")
+		user.Set("role", "user")
+		require.NoError(t, app.Save(user))
+		return user
+	}
+
+	owner := newUser("owner@example.com")
+	org, err := app.FindFirstRecordByFilter("organizations", "slug = {:slug}", dbx.Params{"slug": owner.Id})
+	require.NoError(t, err)
+
+	// starter plan, with features overriding the member limit only
+	settingsCol, err := app.FindCollectionByNameOrId("org_settings")
+	require.NoError(t, err)
+	settings := core.NewRecord(settingsCol)
+	settings.Set("organization", org.Id)
+	settings.Set("billing_plan", "starter")
+	settings.Set("features", map[string]any{entitlements.MaxMembers: 2})
+	require.NoError(t, app.Save(settings))
+
+	t.Run("resolves plan defaults and overrides", func(t *testing.T) {
+		ent, err := entitlements.For(app, org.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "starter", ent.Plan)
+		assert.Equal(t, 2, ent.Limits[entitlements.MaxMembers])
+		assert.Equal(t, 500, ent.Limits[entitlements.MaxProperties])
+	})
+
+	membersCol, err := app.FindCollectionByNameOrId("org_members")
+	require.NoError(t, err)
+	addMember := func(user *core.Record) error {
+		member := core.NewRecord(membersCol)
+		member.Set("user", user.Id)
+		member.Set("organization", org.Id)
+		member.Set("role", "member")
+		return app.Save(member)
+	}
+
+	assertLimit := func(t *testing.T, err error, feature string) {
+		var apiErr *router.ApiError
+		require.True(t, errors.As(err, &apiErr), "expected an API error, got %v", err)
+		assert.Equal(t, http.StatusPaymentRequired, apiErr.Status)
+		assert.Equal(t, feature, apiErr.Data["feature"])
+	}
+
+	t.Run("blocks members over the limit", func(t *testing.T) {
+		require.NoError(t, addMember(newUser("second@example.com")))
+		assertLimit(t, addMember(newUser("third@example.com")), entitlements.MaxMembers)
+	})
+
+	t.Run("blocks invite acceptance at the limit", func(t *testing.T) {
+		newUser("invited@example.com")
+
+		invitesCol, err := app.FindCollectionByNameOrId("org_invites")
+		require.NoError(t, err)
+		invite := core.NewRecord(invitesCol)
+		invite.Set("organization", org.Id)
+		invite.Set("email", "invited@example.com")
+		invite.Set("role", "member")
+		invite.Set("token", "entitlements-token")
+		invite.Set("status", "pending")
+		invite.Set("expires_at", time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+		require.NoError(t, app.Save(invite))
+
+		invite.Set("status", "accepted")
+		assertLimit(t, app.Save(invite), entitlements.MaxMembers)
+
+		loaded, err := app.FindRecordById("org_invites", invite.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", loaded.GetString("status"))
+	})
+
+	t.Run("blocks properties over the limit", func(t *testing.T) {
+		settings.Set("features", map[string]any{entitlements.MaxMembers: 2, entitlements.MaxProperties: 1})
+		require.NoError(t, app.Save(settings))
+
+		propertiesCol, err := app.FindCollectionByNameOrId("properties")
+		require.NoError(t, err)
+		newProperty := func() error {
+			property := core.NewRecord(propertiesCol)
+			property.Set("organization", org.Id)
+			property.Set("property_name", "Main St")
+			property.Set("address", "1 Main St")
+			property.Set("city", "Springfield")
+			return app.Save(property)
+		}
+
+		require.NoError(t, newProperty())
+		assertLimit(t, newProperty(), entitlements.MaxProperties)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		ent, err := entitlements.For(app, org.Id)
+		require.NoError(t, err)
+		usage, err := ent.Usage(app)
+		require.NoError(t, err)
+		assert.Equal(t, entitlements.Usage{Used: 2, Limit: 2}, usage[entitlements.MaxMembers])
+		assert.Equal(t, entitlements.Usage{Used: 1, Limit: 1}, usage[entitlements.MaxProperties])
+	})
+}
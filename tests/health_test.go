@@ -0,0 +1,98 @@
+package tests_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/service"
+)
+
+func staticCheck(name string, critical bool, err error) service.Check {
+	return service.Check{
+		Name:     name,
+		Critical: critical,
+		Run: func(context.Context, core.App) (map[string]any, error) {
+			return nil, err
+		},
+	}
+}
+
+func healthApp(checks ...service.Check) func(t testing.TB) *pbtests.TestApp {
+	return func(t testing.TB) *pbtests.TestApp {
+		app, err := pbtests.NewTestApp()
+		require.NoError(t, err)
+
+		registry := service.NewRegistry(app)
+		require.NoError(t, registry.Register(service.NewHealthService(checks...)))
+		registry.Bind()
+		return app
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	scenarios := []pbtests.ApiScenario{
+		{
+			Name:            "live ignores failing checks",
+			Method:          http.MethodGet,
+			URL:             "/api/custom/health/live",
+			TestAppFactory:  healthApp(staticCheck("db", true, errors.New("down"))),
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"status":"ok"`},
+		},
+		{
+			Name:            "ready with passing checks",
+			Method:          http.MethodGet,
+			URL:             "/api/custom/health/ready",
+			TestAppFactory:  healthApp(staticCheck("db", true, nil), staticCheck("cron", true, nil)),
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"status":"ok"`, `"db":{`, `"cron":{`},
+		},
+		{
+			Name:           "ready tolerates non-critical failures",
+			Method:         http.MethodGet,
+			URL:            "/api/custom/health/ready",
+			TestAppFactory: healthApp(staticCheck("db", true, nil), staticCheck("mail", false, errors.New("no smtp"))),
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"status":"ok"`,
+				`"mail":{"status":"fail","critical":false`,
+				`"error":"no smtp"`,
+			},
+		},
+		{
+			Name:           "ready fails on critical check",
+			Method:         http.MethodGet,
+			URL:            "/api/custom/health/ready",
+			TestAppFactory: healthApp(staticCheck("db", true, errors.New("connection refused"))),
+			ExpectedStatus: http.StatusServiceUnavailable,
+			ExpectedContent: []string{
+				`"status":"fail"`,
+				`"error":"connection refused"`,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestCronCheck(t *testing.T) {
+	app, err := pbtests.NewTestApp()
+	require.NoError(t, err)
+	defer app.Cleanup()
+
+	check := service.CronCheck(app)
+	require.NoError(t, app.OnServe().Trigger(&core.ServeEvent{App: app}))
+
+	app.Cron().Stop()
+	details, err := check.Run(context.Background(), app)
+	require.NoError(t, err, "within a minute of serving")
+	require.Equal(t, true, details["starting"])
+}
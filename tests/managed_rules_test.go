@@ -0,0 +1,114 @@
+package tests_test
+
+import (
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/rules"
+)
+
+func TestManagedRules(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	reconcile := func(name string) patch.RulesResult {
+		t.Helper()
+		result, err := patch.ReconcileRules(app, name)
+		require.NoError(t, err)
+		return result
+	}
+	listRule := func(name string) string {
+		t.Helper()
+		col, err := app.FindCollectionByNameOrId(name)
+		require.NoError(t, err)
+		require.NotNil(t, col.ListRule)
+		return *col.ListRule
+	}
+
+	t.Run("bootstrap records applied rules", func(t *testing.T) {
+		for _, name := range []string{"organizations", "org_members", "properties"} {
+			assert.Equal(t, patch.RulesCurrent, reconcile(name), name)
+		}
+		_, err := patch.ReconcileRules(app, "notifications")
+		assert.Error(t, err, "not managed")
+	})
+
+	t.Run("existing matching rules are adopted", func(t *testing.T) {
+		_, err := app.DB().Delete(patch.RulesTable, dbx.HashExp{"collection": "org_settings"}).Execute()
+		require.NoError(t, err)
+		assert.Equal(t, patch.RulesAdopted, reconcile("org_settings"))
+		assert.Equal(t, patch.RulesCurrent, reconcile("org_settings"))
+	})
+
+	t.Run("admin customizations are left alone", func(t *testing.T) {
+		col, err := app.FindCollectionByNameOrId("organizations")
+		require.NoError(t, err)
+		col.ListRule = rules.Ptr(rules.AuthOnly)
+		require.NoError(t, app.Save(col))
+
+		assert.Equal(t, patch.RulesCustomized, reconcile("organizations"))
+		assert.Equal(t, rules.AuthOnly, listRule("organizations"))
+
+		// Fixing the drift resets the rules and records them as managed.
+		_, err = patch.FixDrift(app, lookupSpecs(t, "organizations"))
+		require.NoError(t, err)
+		assert.Equal(t, patch.RulesCurrent, reconcile("organizations"))
+		assert.Equal(t, rules.DirectOrgMember, listRule("organizations"))
+	})
+
+	t.Run("newer versions are applied", func(t *testing.T) {
+		original := patch.ExpectedRules(lookupSpecs(t, "org_members")[0])
+		defer patch.DeclareRules("org_members", 2, original)
+
+		changed := original
+		changed.List = rules.Ptr(rules.OrgAdmin("organization"))
+
+		patch.DeclareRules("org_members", 2, changed)
+		assert.Equal(t, patch.RulesStale, reconcile("org_members"), "changed without a version bump")
+		assert.Equal(t, rules.OrgMember("organization"), listRule("org_members"))
+
+		patch.DeclareRules("org_members", 3, changed)
+		assert.Equal(t, patch.RulesApplied, reconcile("org_members"))
+		assert.Equal(t, rules.OrgAdmin("organization"), listRule("org_members"))
+		assert.Equal(t, patch.RulesCurrent, reconcile("org_members"))
+	})
+
+	t.Run("rules from before the ledger are upgraded", func(t *testing.T) {
+		// The rules as releases before the ledger wrote them.
+		member := func(field string) string {
+			return "@request.auth.id != '' && " + field + ".id ?= @collection.org_members.organization && @request.auth.id ?= @collection.org_members.user"
+		}
+		admin := func(field string) string {
+			return member(field) + " && (@collection.org_members.role = 'owner' || @collection.org_members.role = 'admin')"
+		}
+		direct := "@request.auth.id != '' && @request.auth.id ?= @collection.org_members.user && id ?= @collection.org_members.organization"
+		propertiesWrite := "(@request.auth.role = 'admin') || (" + admin("organization") + ")"
+		baseline := map[string][5]string{ // list, view, create, update, delete
+			"organizations": {direct, direct, rules.AuthOnly, direct + " && (@collection.org_members.role = 'owner' || @collection.org_members.role = 'admin')", direct + " && @collection.org_members.role = 'owner'"},
+			"org_members":   {member("organization"), member("organization"), admin("organization"), admin("organization"), admin("organization")},
+			"org_invites":   {admin("organization"), admin("organization"), admin("organization"), admin("organization"), admin("organization")},
+			"properties":    {"", "", propertiesWrite, propertiesWrite, propertiesWrite},
+		}
+
+		_, err := app.DB().NewQuery("DELETE FROM " + patch.RulesTable).Execute()
+		require.NoError(t, err)
+		for name, r := range baseline {
+			col, err := app.FindCollectionByNameOrId(name)
+			require.NoError(t, err)
+			patch.Rules{List: &r[0], View: &r[1], Create: &r[2], Update: &r[3], Delete: &r[4]}.Apply(col)
+			require.NoError(t, app.Save(col))
+		}
+
+		for name := range baseline {
+			assert.Equal(t, patch.RulesApplied, reconcile(name), name)
+			col, err := app.FindCollectionByNameOrId(name)
+			require.NoError(t, err)
+			assert.Equal(t, patch.ExpectedRules(lookupSpecs(t, name)[0]), patch.RulesOf(col), name)
+			assert.Equal(t, patch.RulesCurrent, reconcile(name), name)
+		}
+	})
+}
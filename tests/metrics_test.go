@@ -0,0 +1,108 @@
+package tests_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/metrics"
+	"pocketbase-server/server/middleware"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	// superuser from PocketBase's test data; the token stays valid in
+	// every TestApp since they share the same data copy
+	setup, err := pbtests.NewTestApp()
+	require.NoError(t, err)
+	superuser, err := setup.FindAuthRecordByEmail(core.CollectionNameSuperusers, "test@example.com")
+	require.NoError(t, err)
+	token, err := superuser.NewAuthToken()
+	require.NoError(t, err)
+	setup.Cleanup()
+
+	// The registry is process-wide, so expect the 401 on top of earlier runs.
+	unauthorized := map[string]string{"method": "GET", "route": "/metrics", "status": "401"}
+	requests := metricCount(t, "http_requests_total", unauthorized) + 1
+	observed := metricCount(t, "http_request_duration_seconds", unauthorized) + 1
+
+	metrics.InviteEmail(nil)
+	metrics.CronRun("expire_invites", errors.New("boom"))
+	metrics.NotificationSent("info", nil)
+
+	appFactory := func(t testing.TB) *pbtests.TestApp {
+		app, err := pbtests.NewTestApp()
+		require.NoError(t, err)
+
+		app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+			e.Router.Bind(middleware.Metrics())
+			e.Router.GET("/metrics", apis.WrapStdHandler(metrics.Handler())).
+				Bind(middleware.RequireSuperuser())
+			return e.Next()
+		})
+		return app
+	}
+
+	scenarios := []pbtests.ApiScenario{
+		{
+			Name:            "requires auth",
+			Method:          http.MethodGet,
+			URL:             "/metrics",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"error":"authentication required"`},
+		},
+		{
+			Name:           "exposes service metrics to superusers",
+			Method:         http.MethodGet,
+			URL:            "/metrics",
+			Headers:        map[string]string{"Authorization": token},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				fmt.Sprintf(`http_requests_total{method="GET",route="/metrics",status="401"} %d`, requests),
+				fmt.Sprintf(`http_request_duration_seconds_bucket{method="GET",route="/metrics",status="401",le="+Inf"} %d`, observed),
+				// counters below are process-wide and other tests add to them
+				`invite_emails_total{result="sent"}`,
+				`cron_job_runs_total{job="expire_invites"}`,
+				`cron_job_errors_total{job="expire_invites"}`,
+				`notifications_sent_total{result="sent",type="info"}`,
+				`libsql_sync_failures_total`,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.TestAppFactory = appFactory
+		scenario.Test(t)
+	}
+}
+
+// metricCount returns the value of counter name, or the sample count of
+// histogram name, for the series with labels.
+func metricCount(t *testing.T, name string, labels map[string]string) uint64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	series:
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if labels[l.GetName()] != l.GetValue() {
+					continue series
+				}
+			}
+			if h := m.GetHistogram(); h != nil {
+				return h.GetSampleCount()
+			}
+			return uint64(m.GetCounter().GetValue())
+		}
+	}
+	return 0
+}
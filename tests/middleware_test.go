@@ -0,0 +1,104 @@
+package tests_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/roles"
+	"pocketbase-server/server/middleware"
+)
+
+// runMiddleware invokes h for a request authenticated as auth (nil for
+// guests) and returns the recorded response and the event.
+func runMiddleware(app core.App, h *hook.Handler[*core.RequestEvent], auth *core.Record, orgId string) (*httptest.ResponseRecorder, *core.RequestEvent) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/orgs/"+orgId, nil)
+	req.SetPathValue("orgId", orgId)
+
+	re := &core.RequestEvent{App: app, Auth: auth}
+	re.Request = req
+	re.Response = rec
+
+	_ = h.Func(re)
+	return rec, re
+}
+
+func TestAuthMiddlewares(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	usersCol, err := app.FindCollectionByNameOrId("users")
+	require.NoError(t, err)
+
+	newUser := func(email, role string) *core.Record {
+		user := core.NewRecord(usersCol)
+		user.SetEmail(email)
+		user.SetPassword("password1234!")
+		user.Set("role", role)
+		require.NoError(t, app.Save(user))
+		return user
+	}
+
+	owner := newUser("owner@example.com", roles.User)
+	outsider := newUser("outsider@example.com", roles.User)
+	platformAdmin := newUser("admin@example.com", roles.Admin)
+
+	org, err := app.FindFirstRecordByFilter("organizations", "slug = {:slug}", dbx.Params{"slug": owner.Id})
+	require.NoError(t, err)
+
+	superusers, err := app.FindCollectionByNameOrId(core.CollectionNameSuperusers)
+	require.NoError(t, err)
+	superuser := core.NewRecord(superusers)
+
+	t.Run("RequireAuth rejects guests", func(t *testing.T) {
+		rec, _ := runMiddleware(app, middleware.RequireAuth(), nil, org.Id)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"error":"authentication required"`)
+
+		rec, _ = runMiddleware(app, middleware.RequireAuth(), outsider, org.Id)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("RequireSuperuser", func(t *testing.T) {
+		rec, _ := runMiddleware(app, middleware.RequireSuperuser(), platformAdmin, org.Id)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec, _ = runMiddleware(app, middleware.RequireSuperuser(), superuser, org.Id)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("RequirePlatformRole", func(t *testing.T) {
+		rec, _ := runMiddleware(app, middleware.RequirePlatformRole(roles.Admin), outsider, org.Id)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec, _ = runMiddleware(app, middleware.RequirePlatformRole(roles.Admin), platformAdmin, org.Id)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("RequireOrgRole resolves the membership", func(t *testing.T) {
+		h := middleware.RequireOrgRole("orgId", roles.OrgOwner, roles.OrgAdmin)
+
+		rec, re := runMiddleware(app, h, owner, org.Id)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, middleware.OrgMember(re))
+		assert.Equal(t, roles.OrgOwner, middleware.OrgMember(re).GetString("role"))
+
+		rec, _ = runMiddleware(app, h, outsider, org.Id)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "not a member")
+
+		rec, _ = runMiddleware(app, middleware.RequireOrgRole("orgId", roles.OrgMember), owner, org.Id)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec, re = runMiddleware(app, h, superuser, org.Id)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, middleware.OrgMember(re))
+	})
+}
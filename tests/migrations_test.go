@@ -0,0 +1,133 @@
+package tests_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/patch"
+)
+
+func addSettingsField(name string) func(app core.App) error {
+	return func(app core.App) error {
+		return patch.Collection(app, "settings", patch.Field(&core.TextField{Name: name}))
+	}
+}
+
+func removeSettingsField(name string) func(app core.App) error {
+	return func(app core.App) error {
+		col, err := app.FindCollectionByNameOrId("settings")
+		if err != nil {
+			return err
+		}
+		col.Fields.RemoveByName(name)
+		return app.Save(col)
+	}
+}
+
+func hasSettingsField(t *testing.T, app core.App, name string) bool {
+	t.Helper()
+	col, err := app.FindCollectionByNameOrId("settings")
+	require.NoError(t, err)
+	return col.Fields.GetByName(name) != nil
+}
+
+func TestMigrations(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	// Start from an empty ledger; bootstrap applied the registered migrations.
+	_, err := app.DB().Delete(patch.LedgerTable, nil).Execute()
+	require.NoError(t, err)
+
+	ledger := patch.NewLedger()
+	require.NoError(t, ledger.Add(patch.Migration{Id: "002_nickname", Up: addSettingsField("nickname")}))
+	require.NoError(t, ledger.Add(patch.Migration{
+		Id:   "001_motto",
+		Up:   addSettingsField("motto"),
+		Down: removeSettingsField("motto"),
+	}))
+	assert.Error(t, ledger.Add(patch.Migration{Id: "001_motto", Up: addSettingsField("other")}), "duplicate id")
+	assert.Error(t, ledger.Add(patch.Migration{Id: "003_empty"}), "missing up step")
+
+	migrations := ledger.Migrations()
+	require.Len(t, migrations, 2)
+	assert.Equal(t, "001_motto", migrations[0].Id, "sorted by id")
+
+	t.Run("dry run reports without applying", func(t *testing.T) {
+		results, err := ledger.Up(app, true)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, []string{"settings: add field motto (text)"}, results[0].Changes)
+		assert.False(t, hasSettingsField(t, app, "motto"))
+
+		statuses, err := ledger.Status(app)
+		require.NoError(t, err)
+		assert.False(t, statuses[0].Applied)
+	})
+
+	t.Run("up applies and records", func(t *testing.T) {
+		results, err := ledger.Up(app, false)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.True(t, hasSettingsField(t, app, "motto"))
+		assert.True(t, hasSettingsField(t, app, "nickname"))
+
+		statuses, err := ledger.Status(app)
+		require.NoError(t, err)
+		for _, s := range statuses {
+			assert.True(t, s.Applied, s.Id)
+			assert.False(t, s.AppliedAt.IsZero(), s.Id)
+		}
+
+		results, err = ledger.Up(app, false)
+		require.NoError(t, err)
+		assert.Empty(t, results, "nothing pending")
+	})
+
+	t.Run("failed up rolls back and stops", func(t *testing.T) {
+		require.NoError(t, ledger.Add(patch.Migration{Id: "003_broken", Up: func(app core.App) error {
+			if err := addSettingsField("broken")(app); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		}}))
+		require.NoError(t, ledger.Add(patch.Migration{Id: "004_after", Up: addSettingsField("after")}))
+
+		_, err := ledger.Up(app, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "003_broken")
+		assert.False(t, hasSettingsField(t, app, "broken"), "rolled back")
+		assert.False(t, hasSettingsField(t, app, "after"), "stopped")
+	})
+
+	t.Run("down reverts and refuses without a down step", func(t *testing.T) {
+		_, err := ledger.Down(app, 1, false)
+		require.Error(t, err, "002_nickname has no down step")
+		assert.Contains(t, err.Error(), "no down step")
+
+		ledger := patch.NewLedger()
+		require.NoError(t, ledger.Add(migrations[0]))
+		require.NoError(t, ledger.Add(migrations[1]))
+		require.NoError(t, ledger.Add(patch.Migration{
+			Id:   "003_tagline",
+			Up:   addSettingsField("tagline"),
+			Down: removeSettingsField("tagline"),
+		}))
+		_, err = ledger.Up(app, false)
+		require.NoError(t, err)
+
+		results, err := ledger.Down(app, 1, false)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, []string{"settings: remove field tagline"}, results[0].Changes)
+		assert.False(t, hasSettingsField(t, app, "tagline"))
+
+		statuses, err := ledger.Status(app)
+		require.NoError(t, err)
+		assert.False(t, statuses[2].Applied)
+	})
+}
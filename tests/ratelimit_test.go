@@ -0,0 +1,157 @@
+package tests_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/config"
+	"pocketbase-server/internal/ratelimit"
+	"pocketbase-server/server/middleware"
+	"pocketbase-server/server/router"
+)
+
+func TestRateLimitStores(t *testing.T) {
+	app, err := pbtests.NewTestApp()
+	require.NoError(t, err)
+	defer app.Cleanup()
+
+	dbStore := ratelimit.NewDBStore(app)
+	require.NoError(t, dbStore.Ensure())
+
+	stores := map[string]ratelimit.Store{
+		"memory": ratelimit.NewMemoryStore(),
+		"db":     dbStore,
+	}
+
+	// 2 requests per minute: one token every 30s
+	limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			take := func(key string, at time.Duration) (bool, time.Duration) {
+				allowed, retryAfter, err := store.Take(key, limit, start.Add(at))
+				require.NoError(t, err)
+				return allowed, retryAfter
+			}
+
+			allowed, _ := take("a", 0)
+			assert.True(t, allowed)
+			allowed, _ = take("a", 0)
+			assert.True(t, allowed, "burst defaults to requests")
+
+			allowed, retryAfter := take("a", 0)
+			assert.False(t, allowed)
+			assert.Equal(t, 30*time.Second, retryAfter)
+
+			allowed, _ = take("b", 0)
+			assert.True(t, allowed, "keys have separate buckets")
+
+			allowed, _ = take("a", 30*time.Second)
+			assert.True(t, allowed, "refilled one token")
+			allowed, _ = take("a", 30*time.Second)
+			assert.False(t, allowed)
+		})
+	}
+
+	t.Run("db cleanup", func(t *testing.T) {
+		deleted, err := dbStore.Cleanup(start.Add(time.Hour))
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, deleted)
+	})
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	// shared across scenarios so the second request finds an empty bucket
+	store := ratelimit.NewMemoryStore()
+
+	appFactory := func(t testing.TB) *pbtests.TestApp {
+		app, err := pbtests.NewTestApp()
+		require.NoError(t, err)
+
+		app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+			e.Router.GET("/api/limited", func(re *core.RequestEvent) error {
+				return re.JSON(http.StatusOK, map[string]any{"ok": true})
+			}).Bind(middleware.RateLimit(store, "limited", ratelimit.Limit{Requests: 1, Per: time.Minute}, middleware.KeyByIP))
+			return e.Next()
+		})
+		return app
+	}
+
+	scenarios := []pbtests.ApiScenario{
+		{
+			Name:            "first request passes",
+			Method:          http.MethodGet,
+			URL:             "/api/limited",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"ok":true`},
+		},
+		{
+			Name:            "second request is limited",
+			Method:          http.MethodGet,
+			URL:             "/api/limited",
+			ExpectedStatus:  http.StatusTooManyRequests,
+			ExpectedContent: []string{`"error":"too many requests"`},
+			AfterTestFunc: func(t testing.TB, app *pbtests.TestApp, res *http.Response) {
+				assert.Equal(t, "60", res.Header.Get("Retry-After"))
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.TestAppFactory = appFactory
+		scenario.Test(t)
+	}
+}
+
+func TestBulkInviteCap(t *testing.T) {
+	f, cleanup := newAccessFixture(t)
+	defer cleanup()
+
+	emails := func(n int) string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = fmt.Sprintf(`"invitee%d@example.com"`, i)
+		}
+		return `{"emails":[` + strings.Join(list, ",") + `]}`
+	}
+	newApp := func(t testing.TB) *pbtests.TestApp {
+		app := f.newApp(t)
+		router.NewRouter(app, config.Default().RateLimit, ratelimit.NewMemoryStore()).RegisterBind()
+		return app
+	}
+
+	scenarios := []pbtests.ApiScenario{
+		{
+			Name:            "too many emails",
+			Method:          http.MethodPost,
+			URL:             "/api/orgs/" + f.ids["org"] + "/invites/bulk",
+			Body:            strings.NewReader(emails(51)),
+			Headers:         map[string]string{"Authorization": f.tokens[orgOwner]},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{"at most 50 emails"},
+			TestAppFactory:  newApp,
+		},
+		{
+			Name:            "within the cap",
+			Method:          http.MethodPost,
+			URL:             "/api/orgs/" + f.ids["org"] + "/invites/bulk",
+			Body:            strings.NewReader(emails(2)),
+			Headers:         map[string]string{"Authorization": f.tokens[orgOwner]},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"sent":2`},
+			TestAppFactory:  newApp,
+		},
+	}
+	for _, s := range scenarios {
+		s.Test(t)
+	}
+}
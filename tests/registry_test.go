@@ -0,0 +1,68 @@
+package tests_test
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/registry"
+)
+
+func TestRegistrySort(t *testing.T) {
+	noop := func(core.App) error { return nil }
+	names := func(cols []registry.Collection) []string {
+		out := make([]string, len(cols))
+		for i, c := range cols {
+			out[i] = c.Name
+		}
+		return out
+	}
+	indexOf := func(names []string, name string) int {
+		for i, n := range names {
+			if n == name {
+				return i
+			}
+		}
+		t.Fatalf("%s not in %v", name, names)
+		return -1
+	}
+
+	t.Run("registered collections", func(t *testing.T) {
+		cols, err := registry.Collections()
+		require.NoError(t, err)
+		order := names(cols)
+		assert.Less(t, indexOf(order, "photos"), indexOf(order, "rental_comps"))
+		assert.Less(t, indexOf(order, "organizations"), indexOf(order, "properties"))
+		assert.Less(t, indexOf(order, "properties"), indexOf(order, "property_details"))
+		assert.Less(t, indexOf(order, "users"), indexOf(order, "settings"))
+	})
+
+	t.Run("dependencies first, then alphabetical", func(t *testing.T) {
+		cols, err := registry.Sort([]registry.Collection{
+			{Name: "c", DependsOn: []string{"b"}, Setup: noop},
+			{Name: "a", Setup: noop},
+			{Name: "b", DependsOn: []string{"d"}, Setup: noop},
+			{Name: "d", Setup: noop},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "d", "b", "c"}, names(cols))
+	})
+
+	t.Run("missing dependency", func(t *testing.T) {
+		_, err := registry.Sort([]registry.Collection{
+			{Name: "rental_comps", DependsOn: []string{"photos"}, Setup: noop},
+		})
+		assert.EqualError(t, err, `collection "rental_comps" depends on "photos", which is not registered`)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := registry.Sort([]registry.Collection{
+			{Name: "a", DependsOn: []string{"b"}, Setup: noop},
+			{Name: "b", DependsOn: []string{"c"}, Setup: noop},
+			{Name: "c", DependsOn: []string{"a"}, Setup: noop},
+		})
+		assert.EqualError(t, err, "collection dependency cycle: a -> b -> c -> a")
+	})
+}
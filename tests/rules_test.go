@@ -0,0 +1,78 @@
+package tests_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/rules"
+)
+
+func TestRuleExpressions(t *testing.T) {
+	isOwner := rules.Eq(rules.Field("owner"), rules.Auth("id"))
+	isAdmin := rules.Eq(rules.Auth("role"), rules.Value("admin"))
+
+	cases := []struct {
+		name string
+		expr rules.Expr
+		want string
+	}{
+		{"or nested in and", rules.And(rules.Neq(rules.Auth("id"), rules.Value("")), rules.Or(isOwner, isAdmin)),
+			"@request.auth.id != '' && (owner = @request.auth.id || @request.auth.role = 'admin')"},
+		{"and nested in or", rules.Or(isAdmin, rules.And(isOwner, rules.Eq(rules.Field("published"), rules.Value(true)))),
+			"(@request.auth.role = 'admin' || (owner = @request.auth.id && published = true))"},
+		{"nested groups flatten", rules.And(isOwner, rules.And(isAdmin, isOwner)),
+			"owner = @request.auth.id && @request.auth.role = 'admin' && owner = @request.auth.id"},
+		{"in", rules.In(rules.Collection("org_members", "role"), "owner", "admin"),
+			"(@collection.org_members.role = 'owner' || @collection.org_members.role = 'admin')"},
+		{"not applies de morgan", rules.Not(rules.And(isOwner, rules.In(rules.Field("status"), "draft", 1))),
+			"(owner != @request.auth.id || (status != 'draft' && status != 1))"},
+		{"not any", rules.Not(rules.AnyEq(rules.Auth("id"), rules.Collection("org_members", "user"))),
+			"@request.auth.id != @collection.org_members.user"},
+		{"raw is parenthesized", rules.And(isOwner, rules.Raw(rules.OwnRecord("user"))),
+			"owner = @request.auth.id && ((@request.auth.id = user || @request.auth.role = 'admin'))"},
+		{"quotes are escaped", rules.Eq(rules.Field("name"), rules.Value("o'brien")),
+			`name = 'o\'brien'`},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.expr.String(), c.name)
+	}
+	assert.Panics(t, func() { rules.Not(rules.Raw(rules.AuthOnly)) })
+}
+
+func TestRuleValidation(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	require.NoError(t, patch.ValidateRules(app, patch.Specs()), "the code's rules")
+
+	valid := []string{
+		rules.OrgAdmin("organization"),
+		rules.WithPlatformAdmin(rules.OrgMember("organization")),
+		"organization.name != '' && @request.body.price:isset = false",
+		"@collection.org_members:m.user ?= @request.auth.id && created > @now",
+		"organization.org_members_via_organization.user ?= @request.auth.id",
+	}
+	for _, rule := range valid {
+		assert.NoError(t, rules.Validate(app, "properties", rule), rule)
+	}
+
+	invalid := map[string]string{
+		"ownr = @request.auth.id":                            `properties has no field "ownr"`,
+		"organization.nope = 'x'":                            `organizations has no field "nope"`,
+		"city.name = 'x'":                                    "properties.city is not a relation",
+		"@request.auth.team = 'x'":                           `users has no field "team"`,
+		"@request.body.title != ''":                          `properties has no field "title"`,
+		"@collection.org_member.user ?= @request.auth.id":    `collection "org_member" not found`,
+		"org_members_via_user.role = 'owner'":                "org_members.user is not a relation to properties",
+		"@request.auth.id != '' && (price > 1 || prise < 2)": `properties has no field "prise"`,
+	}
+	for rule, want := range invalid {
+		err := rules.Validate(app, "properties", rule)
+		if assert.Error(t, err, rule) {
+			assert.Contains(t, err.Error(), want, rule)
+		}
+	}
+}
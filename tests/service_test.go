@@ -0,0 +1,73 @@
+package tests_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/service"
+)
+
+type echoService struct {
+	started bool
+}
+
+func (s *echoService) Name() string { return "echo" }
+
+func (s *echoService) RegisterRoutes(e *core.ServeEvent) {
+	e.Router.GET("/ping", func(re *core.RequestEvent) error {
+		return re.JSON(http.StatusOK, map[string]any{"pong": true, "started": s.started})
+	})
+}
+
+func (s *echoService) Start(app core.App) error {
+	s.started = true
+	return nil
+}
+
+func TestServiceRegistry(t *testing.T) {
+	t.Run("rejects duplicate names", func(t *testing.T) {
+		app, err := pbtests.NewTestApp()
+		require.NoError(t, err)
+		defer app.Cleanup()
+
+		registry := service.NewRegistry(app)
+		require.NoError(t, registry.Register(&echoService{}))
+		assert.Error(t, registry.Register(&echoService{}))
+		assert.Len(t, registry.Services(), 1)
+	})
+
+	scenarios := []pbtests.ApiScenario{
+		{
+			Name:            "service routes are mounted under their prefix",
+			Method:          http.MethodGet,
+			URL:             "/api/custom/echo/ping",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"pong":true`, `"started":true`},
+		},
+		{
+			Name:            "health service root route",
+			Method:          http.MethodGet,
+			URL:             "/api/custom/health",
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"status":"ok"`},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.TestAppFactory = func(t testing.TB) *pbtests.TestApp {
+			app, err := pbtests.NewTestApp()
+			require.NoError(t, err)
+
+			registry := service.NewRegistry(app)
+			require.NoError(t, registry.Register(&echoService{}, service.NewHealthService()))
+			registry.Bind()
+			return app
+		}
+		scenario.Test(t)
+	}
+}
@@ -0,0 +1,85 @@
+package tests_test
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/patch"
+	"pocketbase-server/pb/rules"
+)
+
+func TestSpecReconcile(t *testing.T) {
+	app, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	spec := patch.Spec{
+		Name: "listings",
+		Fields: []core.Field{
+			&core.RelationField{Name: "organization", CollectionId: "organizations", MaxSelect: 1},
+			&core.SelectField{Name: "status", MaxSelect: 1, Values: []string{"draft", "live"}},
+		},
+		Indexes: []patch.IndexSpec{{Name: "idx_listings_org", Columns: "organization"}},
+		Rules:   patch.Rules{List: rules.Ptr(rules.AuthOnly)},
+	}
+	assert.Equal(t, []string{"organizations"}, spec.Dependencies())
+
+	changes, err := patch.Reconcile(app, spec)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create collection listings"}, changes)
+
+	col, err := app.FindCollectionByNameOrId("listings")
+	require.NoError(t, err)
+	orgs, err := app.FindCollectionByNameOrId("organizations")
+	require.NoError(t, err)
+	assert.Equal(t, orgs.Id, col.Fields.GetByName("organization").(*core.RelationField).CollectionId, "relation resolved")
+	assert.Equal(t, "organizations", spec.Fields[0].(*core.RelationField).CollectionId, "spec untouched")
+	require.NotNil(t, col.ListRule)
+
+	changes, err = patch.Reconcile(app, spec)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "already up to date")
+
+	t.Run("applies a minimal diff", func(t *testing.T) {
+		// An admin customized the rule and added a select value.
+		col.ListRule = rules.Ptr(rules.Public)
+		col.Fields.GetByName("status").(*core.SelectField).Values = []string{"draft", "live", "custom"}
+		require.NoError(t, app.Save(col))
+
+		next := spec
+		next.Fields = []core.Field{
+			spec.Fields[0],
+			&core.SelectField{Name: "status", MaxSelect: 1, Values: []string{"draft", "live", "sold"}},
+			&core.NumberField{Name: "price"},
+		}
+		next.Indexes = append(next.Indexes, patch.IndexSpec{Name: "idx_listings_price", Columns: "price"})
+
+		changes, err := patch.Reconcile(app, next)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"listings: add values sold to field status",
+			"listings: add field price (number)",
+			"listings: add index idx_listings_price",
+		}, changes)
+
+		col, err := app.FindCollectionByNameOrId("listings")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"draft", "live", "custom", "sold"}, col.Fields.GetByName("status").(*core.SelectField).Values)
+		assert.Equal(t, rules.Public, *col.ListRule, "customized rule kept")
+	})
+
+	t.Run("rejects type changes and missing dependencies", func(t *testing.T) {
+		next := spec
+		next.Fields = []core.Field{&core.TextField{Name: "status"}}
+		_, err := patch.Reconcile(app, next)
+		assert.ErrorContains(t, err, "listings.status is a select field")
+
+		_, err = patch.Reconcile(app, patch.Spec{
+			Name:   "orphans",
+			Fields: []core.Field{&core.RelationField{Name: "parent", CollectionId: "missing"}},
+		})
+		assert.ErrorContains(t, err, `relation target "missing" does not exist`)
+	})
+}
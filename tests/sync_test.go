@@ -0,0 +1,180 @@
+package tests_test
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/database"
+	"pocketbase-server/server"
+	"pocketbase-server/server/admin"
+)
+
+// fakeSync fails while fail is set and keeps SyncStatus like a replica.
+type fakeSync struct {
+	mu     sync.Mutex
+	fail   bool
+	calls  int
+	status database.SyncStatus
+}
+
+func (f *fakeSync) setFail(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+func (f *fakeSync) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	rec := database.SyncRecord{Started: time.Now(), FramesSynced: 2, FrameNo: f.calls * 2}
+	f.status.LastAttempt = rec.Started
+	if f.fail {
+		f.status.LastError = errors.New("primary unreachable")
+		f.status.ConsecutiveFailures++
+		rec.Error = f.status.LastError.Error()
+	} else {
+		f.status.LastError = nil
+		f.status.LastSuccess = rec.Started
+		f.status.FramesSynced, f.status.FrameNo = rec.FramesSynced, rec.FrameNo
+		f.status.ConsecutiveFailures = 0
+	}
+	f.status.History = append([]database.SyncRecord{rec}, f.status.History...)
+	return f.status.LastError
+}
+
+func (f *fakeSync) SyncAsync() bool {
+	go f.Sync()
+	return true
+}
+
+func (f *fakeSync) SyncStatus() database.SyncStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+func TestSyncBackoff(t *testing.T) {
+	interval, maxBackoff := time.Second, 10*time.Second
+	for failures, want := range []time.Duration{1, 2, 4, 8, 10, 10} {
+		assert.Equal(t, want*time.Second, database.Backoff(interval, maxBackoff, failures), "%d failures", failures)
+	}
+}
+
+func TestSyncer(t *testing.T) {
+	conn := &fakeSync{fail: true}
+	alerts := make(chan database.SyncStatus, 10)
+	recovered := make(chan database.SyncStatus, 10)
+
+	syncer := database.NewSyncer(conn, database.SyncerConfig{
+		Interval:   time.Millisecond,
+		MaxBackoff: 4 * time.Millisecond,
+		AlertAfter: 3,
+		OnAlert:    func(s database.SyncStatus) { alerts <- s },
+		OnRecover:  func(s database.SyncStatus) { recovered <- s },
+	})
+	syncer.Start()
+	defer syncer.Stop()
+
+	select {
+	case status := <-alerts:
+		assert.Equal(t, 3, status.ConsecutiveFailures)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert after consecutive failures")
+	}
+
+	conn.setFail(false)
+	select {
+	case status := <-recovered:
+		assert.Zero(t, status.ConsecutiveFailures)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no recovery after a successful sync")
+	}
+
+	syncer.Stop()
+	assert.Len(t, alerts, 0, "one alert per failure streak")
+}
+
+func TestSyncStatusEndpoint(t *testing.T) {
+	setup, err := pbtests.NewTestApp()
+	require.NoError(t, err)
+	superuser, err := setup.FindAuthRecordByEmail(core.CollectionNameSuperusers, "test@example.com")
+	require.NoError(t, err)
+	token, err := superuser.NewAuthToken()
+	require.NoError(t, err)
+	setup.Cleanup()
+
+	conn := &fakeSync{}
+	require.NoError(t, conn.Sync())
+	conn.setFail(true)
+	require.Error(t, conn.Sync())
+
+	appFactory := func(t testing.TB) *pbtests.TestApp {
+		app, err := pbtests.NewTestApp()
+		require.NoError(t, err)
+		admin.BindSyncFunc(app, conn)
+		return app
+	}
+
+	scenarios := []pbtests.ApiScenario{
+		{
+			Name:            "requires a superuser",
+			Method:          http.MethodGet,
+			URL:             "/api/admin/sync/status",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"error":"authentication required"`},
+		},
+		{
+			Name:           "reports the last syncs",
+			Method:         http.MethodGet,
+			URL:            "/api/admin/sync/status",
+			Headers:        map[string]string{"Authorization": token},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"consecutive_failures":1`,
+				`"frame_no":2`,
+				`"last_error":"primary unreachable"`,
+				`"error":"primary unreachable"`,
+				`"frames_synced":2`,
+			},
+		},
+		{
+			Name:            "starts an async sync",
+			Method:          http.MethodPost,
+			URL:             "/api/admin/sync?async=true",
+			Headers:         map[string]string{"Authorization": token},
+			ExpectedStatus:  http.StatusAccepted,
+			ExpectedContent: []string{`"message":"sync started`},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.TestAppFactory = appFactory
+		scenario.Test(t)
+	}
+}
+
+func TestShutdownStopsSyncer(t *testing.T) {
+	app := pocketbase.NewWithConfig(pocketbase.Config{DefaultDataDir: t.TempDir()})
+	conn := &fakeSync{}
+	syncer := database.NewSyncer(conn, database.SyncerConfig{Interval: time.Millisecond})
+	syncer.Start()
+	defer syncer.Stop()
+
+	server.NewLifecycle(app, nil, syncer, time.Second).Bind()
+	require.NoError(t, app.OnTerminate().Trigger(&core.TerminateEvent{App: app}))
+
+	synced := len(conn.SyncStatus().History)
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, conn.SyncStatus().History, synced, "no syncs after shutdown")
+}
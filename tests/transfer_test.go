@@ -0,0 +1,145 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/internal/transfer"
+)
+
+func saveRecord(t *testing.T, app core.App, collection string, fields map[string]any) *core.Record {
+	t.Helper()
+	col, err := app.FindCollectionByNameOrId(collection)
+	require.NoError(t, err)
+	record := core.NewRecord(col)
+	for k, v := range fields {
+		record.Set(k, v)
+	}
+	require.NoError(t, app.Save(record))
+	return record
+}
+
+func countRecords(t *testing.T, app core.App, collection string, filter string, params dbx.Params) int {
+	t.Helper()
+	records, err := app.FindRecordsByFilter(collection, filter, "", 0, 0, params)
+	require.NoError(t, err)
+	return len(records)
+}
+
+func TestTransfer(t *testing.T) {
+	src, cleanup := bootstrapApp(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	alice := saveRecord(t, src, "users", map[string]any{"email": "alice@example.com", "password": "password1234!", "role": "user"})
+	bob := saveRecord(t, src, "users", map[string]any{"email": "bob@example.com", "password": "password1234!", "role": "user"})
+	acme := saveRecord(t, src, "organizations", map[string]any{"name": "Acme", "slug": "acme"})
+	saveRecord(t, src, "org_members", map[string]any{"user": alice.Id, "organization": acme.Id, "role": "owner"})
+	saveRecord(t, src, "org_members", map[string]any{"user": bob.Id, "organization": acme.Id, "role": "member"})
+	property := saveRecord(t, src, "properties", map[string]any{
+		"organization": acme.Id, "property_name": "HQ", "address": "1 Main St", "city": "Springfield",
+	})
+
+	t.Run("full export round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := transfer.Export(ctx, src, &buf, transfer.Options{})
+		require.NoError(t, err)
+
+		// Targets of relations come first.
+		out := buf.String()
+		assert.Less(t, strings.Index(out, `"name":"users"`), strings.Index(out, `"name":"org_members"`))
+		assert.Less(t, strings.Index(out, `"name":"organizations"`), strings.Index(out, `"name":"properties"`))
+
+		dst, cleanup := bootstrapApp(t)
+		defer cleanup()
+
+		summary, err := transfer.Import(ctx, dst, bytes.NewReader(buf.Bytes()), transfer.Options{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, summary.Collections)
+
+		got, err := dst.FindRecordById("properties", property.Id)
+		require.NoError(t, err)
+		assert.Equal(t, acme.Id, got.GetString("organization"))
+		assert.Equal(t, property.GetDateTime("created").String(), got.GetDateTime("created").String(), "timestamps kept")
+
+		user, err := dst.FindAuthRecordByEmail("users", "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, alice.Id, user.Id)
+		assert.True(t, user.ValidatePassword("password1234!"), "password hash kept")
+
+		// Importing again overwrites instead of duplicating.
+		_, err = transfer.Import(ctx, dst, bytes.NewReader(buf.Bytes()), transfer.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, 2, countRecords(t, dst, "users", "", nil))
+	})
+
+	t.Run("organization filter with remapped ids", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := transfer.Export(ctx, src, &buf, transfer.Options{Organization: acme.Id})
+		require.NoError(t, err)
+
+		dst, cleanup := bootstrapApp(t)
+		defer cleanup()
+
+		summary, err := transfer.Import(ctx, dst, bytes.NewReader(buf.Bytes()), transfer.Options{RemapIds: true, DryRun: true})
+		require.NoError(t, err)
+		assert.NotEmpty(t, summary.Collections)
+		assert.Equal(t, 0, countRecords(t, dst, "users", "", nil), "dry run writes nothing")
+
+		_, err = transfer.Import(ctx, dst, bytes.NewReader(buf.Bytes()), transfer.Options{RemapIds: true})
+		require.NoError(t, err)
+
+		// Only Acme, not the personal organizations.
+		orgs, err := dst.FindAllRecords("organizations")
+		require.NoError(t, err)
+		require.Len(t, orgs, 1)
+		assert.Equal(t, "acme", orgs[0].GetString("slug"))
+		assert.NotEqual(t, acme.Id, orgs[0].Id)
+
+		// Members point at the remapped users and organization.
+		user, err := dst.FindAuthRecordByEmail("users", "bob@example.com")
+		require.NoError(t, err)
+		assert.NotEqual(t, bob.Id, user.Id)
+		assert.Equal(t, 1, countRecords(t, dst, "org_members", "user = {:user} && organization = {:org} && role = 'member'",
+			dbx.Params{"user": user.Id, "org": orgs[0].Id}))
+		assert.Equal(t, 1, countRecords(t, dst, "settings", "user = {:user}", dbx.Params{"user": user.Id}), "owned settings follow the user")
+		assert.Equal(t, 1, countRecords(t, dst, "properties", "organization = {:org}", dbx.Params{"org": orgs[0].Id}))
+	})
+
+	t.Run("rejects dangling relations", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := transfer.Export(ctx, src, &buf, transfer.Options{Organization: acme.Id})
+		require.NoError(t, err)
+
+		// Drop the users so the members point at nothing.
+		var kept []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if !strings.HasPrefix(line, `{"type":"record","collection":"users"`) {
+				kept = append(kept, line)
+			}
+		}
+
+		dst, cleanup := bootstrapApp(t)
+		defer cleanup()
+
+		_, err = transfer.Import(ctx, dst, strings.NewReader(strings.Join(kept, "\n")), transfer.Options{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing records")
+		assert.Equal(t, 0, countRecords(t, dst, "organizations", "", nil), "rolled back")
+	})
+
+	t.Run("rejects invalid streams", func(t *testing.T) {
+		_, err := transfer.Import(ctx, src, strings.NewReader(`{"type":"record"}`), transfer.Options{})
+		assert.ErrorIs(t, err, transfer.ErrFormat)
+
+		_, err = transfer.Export(ctx, src, &bytes.Buffer{}, transfer.Options{Organization: "missing"})
+		assert.Error(t, err)
+	})
+}
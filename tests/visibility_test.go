@@ -0,0 +1,218 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	pbtests "github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/subscriptions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pocketbase-server/pb/collections/visibility"
+)
+
+func TestFieldVisibility(t *testing.T) {
+	f, cleanup := newAccessFixture(t)
+	defer cleanup()
+
+	contactDetails := []string{`"phone":"+15550199"`, `"email":"pat@brokerage.test"`, `"license_number":"LIC-12345"`}
+	expandContacts := "/api/collections/properties/records/" + f.ids["property"] + "?expand=property_contacts_via_property"
+
+	scenarios := []struct {
+		actor      actor
+		url        string
+		visible    []string
+		notVisible []string
+	}{
+		// property_contacts details are for the property's org.
+		{outsider, "/api/collections/property_contacts/records", []string{`"name":"Pat Agent"`}, contactDetails},
+		{orgMember, "/api/collections/property_contacts/records", contactDetails, nil},
+		{platformAdmin, "/api/collections/property_contacts/records", contactDetails, nil},
+		{superuser, "/api/collections/property_contacts/records", contactDetails, nil},
+
+		// Expanded records are redacted the same way.
+		{outsider, expandContacts, []string{`"name":"Pat Agent"`}, contactDetails},
+		{orgMember, expandContacts, contactDetails, nil},
+
+		// users.phone is for the user and platform admins.
+		{orgMember, "/api/collections/users/records/" + f.users[orgMember], []string{`"phone":"+15550100"`}, nil},
+		{platformAdmin, "/api/collections/users/records/" + f.users[orgMember], []string{`"phone":"+15550100"`}, nil},
+	}
+
+	for _, s := range scenarios {
+		scenario := pbtests.ApiScenario{
+			Name:               string(s.actor) + " " + s.url,
+			Method:             http.MethodGet,
+			URL:                s.url,
+			Headers:            map[string]string{"Authorization": f.tokens[s.actor]},
+			ExpectedStatus:     http.StatusOK,
+			ExpectedContent:    s.visible,
+			NotExpectedContent: s.notVisible,
+			TestAppFactory:     f.newApp,
+		}
+		scenario.Test(t)
+	}
+
+	// The users rules only show users their own record, so the cases where
+	// users.phone is hidden are checked on the policy directly.
+	app := f.newApp(t)
+	defer app.Cleanup()
+	policy, ok := visibility.Lookup("users")
+	require.True(t, ok)
+	member, err := app.FindRecordById("users", f.users[orgMember])
+	require.NoError(t, err)
+	for _, a := range []actor{anonymous, outsider, orgAdmin} {
+		var auth *core.Record
+		if a != anonymous {
+			auth, err = app.FindRecordById("users", f.users[a])
+			require.NoError(t, err)
+		}
+		hidden, err := policy.Hidden(app, member, auth)
+		require.NoError(t, err)
+		assert.Contains(t, hidden, "phone", "%s sees another user's phone", a)
+	}
+}
+
+// A page of records shares the membership lookups: one query for the
+// viewer's organizations and one per related record.
+func TestFieldVisibilityQueries(t *testing.T) {
+	f, cleanup := newAccessFixture(t)
+	defer cleanup()
+
+	var lookups atomic.Int32
+	scenario := pbtests.ApiScenario{
+		Method:          http.MethodGet,
+		URL:             "/api/collections/property_contacts/records",
+		Headers:         map[string]string{"Authorization": f.tokens[orgMember]},
+		ExpectedStatus:  http.StatusOK,
+		ExpectedContent: []string{`"totalItems":10`, `"license_number":"LIC-12345"`},
+		TestAppFactory:  f.newApp,
+		BeforeTestFunc: func(t testing.TB, app *pbtests.TestApp, e *core.ServeEvent) {
+			col, err := app.FindCollectionByNameOrId("property_contacts")
+			require.NoError(t, err)
+			for i := range 9 {
+				contact := core.NewRecord(col)
+				contact.Load(map[string]any{"property": f.ids["property"], "role": "buyer_agent", "name": fmt.Sprintf("Agent %d", i)})
+				require.NoError(t, app.Save(contact))
+			}
+
+			app.ConcurrentDB().(*dbx.DB).QueryLogFunc = func(ctx context.Context, d time.Duration, sql string, rows *sql.Rows, err error) {
+				if strings.Contains(sql, "FROM `org_members`") || strings.Contains(sql, "FROM `properties`") {
+					lookups.Add(1)
+				}
+			}
+		},
+		AfterTestFunc: func(t testing.TB, app *pbtests.TestApp, res *http.Response) {
+			assert.EqualValues(t, 2, lookups.Load())
+		},
+	}
+	scenario.Test(t)
+}
+
+// Matching a hidden value reveals it, so filters and sorts on restricted
+// fields are rejected unless the viewer sees them on every record.
+func TestFieldVisibilityFilters(t *testing.T) {
+	f, cleanup := newAccessFixture(t)
+	defer cleanup()
+
+	contacts := "/api/collections/property_contacts/records?"
+	probe := url.Values{"filter": {"license_number='LIC-12345'"}}.Encode()
+	rejected := []string{`"status":400`, "property_contacts.license_number"}
+
+	scenarios := []struct {
+		name    string
+		actor   actor
+		url     string
+		status  int
+		content []string
+	}{
+		{"filter probe", outsider, contacts + probe, http.StatusBadRequest, rejected},
+		{"wrong guess", outsider, contacts + url.Values{"filter": {"license_number='nope'"}}.Encode(), http.StatusBadRequest, rejected},
+		{"org member", orgMember, contacts + probe, http.StatusBadRequest, rejected},
+		{"sort", outsider, contacts + "sort=-phone", http.StatusBadRequest, []string{"property_contacts.phone"}},
+		{"through a back-relation", outsider, "/api/collections/properties/records?" +
+			url.Values{"filter": {"property_contacts_via_property.email~'brokerage'"}}.Encode(), http.StatusBadRequest, []string{"property_contacts.email"}},
+		{"unrestricted field", outsider, contacts + url.Values{"filter": {"name='Pat Agent'"}}.Encode(), http.StatusOK, []string{`"totalItems":1`}},
+		{"platform admin", platformAdmin, contacts + probe, http.StatusOK, []string{`"totalItems":1`}},
+		{"superuser", superuser, contacts + probe, http.StatusOK, []string{`"totalItems":1`}},
+		{"owner of an owner-only field", orgMember, "/api/collections/users/records?filter=phone!=''", http.StatusBadRequest, []string{"users.phone"}},
+	}
+
+	for _, s := range scenarios {
+		scenario := pbtests.ApiScenario{
+			Name:            string(s.actor) + " " + s.name,
+			Method:          http.MethodGet,
+			URL:             s.url,
+			Headers:         map[string]string{"Authorization": f.tokens[s.actor]},
+			ExpectedStatus:  s.status,
+			ExpectedContent: s.content,
+			TestAppFactory:  f.newApp,
+		}
+		scenario.Test(t)
+	}
+
+	subscribe := func(name string, a actor, sub string, status int, content []string) pbtests.ApiScenario {
+		client := subscriptions.NewDefaultClient()
+		body, err := json.Marshal(map[string]any{"clientId": client.Id(), "subscriptions": []string{sub}})
+		require.NoError(t, err)
+		return pbtests.ApiScenario{
+			Name:            "realtime " + name,
+			Method:          http.MethodPost,
+			URL:             "/api/realtime",
+			Body:            bytes.NewReader(body),
+			Headers:         withJSON(map[string]string{"Authorization": f.tokens[a]}),
+			ExpectedStatus:  status,
+			ExpectedContent: content,
+			TestAppFactory:  f.newApp,
+			BeforeTestFunc: func(t testing.TB, app *pbtests.TestApp, e *core.ServeEvent) {
+				app.SubscriptionsBroker().Register(client)
+			},
+		}
+	}
+	options := url.Values{"options": {`{"query":{"filter":"license_number='LIC-12345'"}}`}}.Encode()
+	for _, scenario := range []pbtests.ApiScenario{
+		subscribe("filter probe", outsider, "property_contacts/*?"+options, http.StatusBadRequest, rejected),
+		subscribe("platform admin", platformAdmin, "property_contacts/*?"+options, http.StatusNoContent, nil),
+		subscribe("unfiltered", outsider, "property_contacts/*", http.StatusNoContent, nil),
+	} {
+		scenario.Test(t)
+	}
+}
+
+// Realtime events go through the same enrich hook, once per subscriber.
+func TestFieldVisibilityRealtime(t *testing.T) {
+	f, cleanup := newAccessFixture(t)
+	defer cleanup()
+	app := f.newApp(t)
+	defer app.Cleanup()
+
+	for a, visible := range map[actor]bool{outsider: false, orgMember: true, platformAdmin: true} {
+		contact, err := app.FindRecordById("property_contacts", f.ids["contact"])
+		require.NoError(t, err)
+		auth, err := app.FindRecordById("users", f.users[a])
+		require.NoError(t, err)
+
+		event := &core.RecordEnrichEvent{App: app, RequestInfo: &core.RequestInfo{Auth: auth, Context: core.RequestInfoContextRealtime}}
+		event.Record = contact
+		require.NoError(t, app.OnRecordEnrich().Trigger(event))
+
+		exported := contact.PublicExport()
+		assert.Equal(t, "Pat Agent", exported["name"], a)
+		for _, field := range []string{"phone", "email", "license_number"} {
+			_, ok := exported[field]
+			assert.Equal(t, visible, ok, "%s sees %s", a, field)
+		}
+	}
+}